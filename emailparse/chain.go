@@ -0,0 +1,49 @@
+package emailparse
+
+import (
+	"io"
+
+	"github.com/jarrod-lowe/jmap-service-libs/headers"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chain"
+)
+
+// NewChain creates a textproc chain over the decoded text of part, reading the
+// raw body from blob (the message the part was parsed from). The part's
+// charset and Content-Transfer-Encoding are passed through to utf8clean, and
+// its Content-Type selects how the text is interpreted (HTML, plain,
+// format=flowed or enriched).
+func NewChain(blob io.ReaderAt, part *BodyPart, maxBytes, overlap, charLimit int) (*chain.Chain, error) {
+	return chain.NewBuilder(part.Reader(blob)).
+		WithMaxBytes(maxBytes).
+		WithOverlap(overlap).
+		WithCharLimit(charLimit).
+		WithCharset(part.Charset).
+		WithTransferEncoding(part.ChainTransferEncoding()).
+		WithContentType(part.ChainContentType()).
+		Build()
+}
+
+// ChainTransferEncoding returns the transfer encoding to give utf8clean for
+// this part. Identity encodings (7bit, 8bit, binary) need no decoding and map
+// to the empty string.
+func (p *BodyPart) ChainTransferEncoding() string {
+	switch p.TransferEncoding {
+	case "base64", "quoted-printable":
+		return p.TransferEncoding
+	default:
+		return ""
+	}
+}
+
+// ChainContentType returns the content type to give the chain for this part.
+// This is the raw Content-Type header when it is present and agrees with
+// Type, so that parameters such as format=flowed are kept; otherwise it is
+// Type alone.
+func (p *BodyPart) ChainContentType() string {
+	if value, ok := p.Header("Content-Type"); ok {
+		if mediaType, _, err := headers.ParseMediaType(value); err == nil && mediaType == p.Type {
+			return value
+		}
+	}
+	return p.Type
+}
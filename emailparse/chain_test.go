@@ -0,0 +1,101 @@
+package emailparse
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chain"
+)
+
+func TestChainTransferEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                 "",
+		"7bit":             "",
+		"8bit":             "",
+		"binary":           "",
+		"base64":           "base64",
+		"quoted-printable": "quoted-printable",
+	}
+	for in, want := range tests {
+		p := &BodyPart{TransferEncoding: in}
+		if got := p.ChainTransferEncoding(); got != want {
+			t.Errorf("ChainTransferEncoding(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewChainDecodesTextPart(t *testing.T) {
+	raw := `Content-Type: multipart/mixed; boundary=b
+
+--b
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: base64
+
+SGVsbG8gZnJvbSBhIE1JTUUgcGFydC4=
+--b
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: 7bit
+
+Second part.
+--b--
+`
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(msg.TextBody) != 2 {
+		t.Fatalf("expected 2 text parts, got %d", len(msg.TextBody))
+	}
+
+	want := []string{"Hello from a MIME part.", "Second part."}
+	for i, part := range msg.TextBody {
+		c, err := NewChain(strings.NewReader(raw), part, chain.DefaultMaxBytes, chain.DefaultOverlap, chain.DefaultCharLimit)
+		if err != nil {
+			t.Fatalf("NewChain failed: %v", err)
+		}
+		slice, err := c.Next()
+		if err != nil && err != io.EOF {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if len(slice) != 1 || string(slice[0]) != want[i] {
+			t.Errorf("part %d: expected [%s], got %v", i, want[i], slice)
+		}
+	}
+}
+
+func TestChainContentType(t *testing.T) {
+	raw := "Content-Type: text/plain; format=flowed\r\n\r\nbody"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := msg.BodyStructure.ChainContentType(); got != " text/plain; format=flowed" {
+		t.Errorf("expected raw header value, got %q", got)
+	}
+
+	noHeader := &BodyPart{Type: "text/plain"}
+	if got := noHeader.ChainContentType(); got != "text/plain" {
+		t.Errorf("expected 'text/plain', got %q", got)
+	}
+}
+
+func TestNewChainUnwrapsFlowedText(t *testing.T) {
+	raw := "Content-Type: text/plain; charset=utf-8; format=flowed\r\n\r\n" +
+		"A line that was \r\nwrapped <here.\r\n"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	c, err := NewChain(strings.NewReader(raw), msg.BodyStructure, chain.DefaultMaxBytes, chain.DefaultOverlap, chain.DefaultCharLimit)
+	if err != nil {
+		t.Fatalf("NewChain failed: %v", err)
+	}
+	slice, err := c.Next()
+	if err != nil && err != io.EOF {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if len(slice) != 1 || string(slice[0]) != "A line that was wrapped <here." {
+		t.Errorf("unexpected chunks %q", slice)
+	}
+}
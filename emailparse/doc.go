@@ -0,0 +1,44 @@
+// Package emailparse parses RFC 5322 / MIME messages into the JMAP Email body
+// structure defined by RFC 8621.
+//
+// The parser streams the message line by line and never holds a part body in
+// memory. For every part it records the raw byte range of the body within the
+// message (so callers can mint blob IDs or re-read the part later), the decoded
+// size, and the charset and Content-Transfer-Encoding needed to decode it.
+//
+// # Body Structure
+//
+// [Parse] returns a [Message] containing:
+//
+//   - BodyStructure: the full MIME tree as [BodyPart] values
+//   - TextBody, HTMLBody, Attachments: the lists computed by the algorithm in
+//     RFC 8621 section 4.1.4
+//
+// Leaf parts are given sequential partIds ("1", "2", ...) in depth-first order.
+// Multipart containers have an empty PartID, matching the JMAP null value.
+//
+// # Feeding the Text Pipeline
+//
+// Each text part can be passed straight into the textproc chain:
+//
+//	msg, err := emailparse.Parse(bytes.NewReader(raw))
+//	if err != nil {
+//	    return err
+//	}
+//	for _, part := range msg.TextBody {
+//	    c, err := emailparse.NewChain(bytes.NewReader(raw), part,
+//	        chain.DefaultMaxBytes, chain.DefaultOverlap, chain.DefaultCharLimit)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // c.Next() ...
+//	}
+//
+// # Robustness
+//
+// Malformed input is handled leniently: missing final boundaries, bodies
+// without headers, and boundaries of enclosing multiparts that cut a child part
+// short are all accepted. Nesting beyond [WithMaxDepth] is treated as an opaque
+// leaf, and messages with more parts than [WithMaxParts] are rejected with
+// [ErrTooManyParts].
+package emailparse
@@ -0,0 +1,429 @@
+package emailparse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jarrod-lowe/jmap-service-libs/headers"
+)
+
+// Default limits applied by Parse.
+const (
+	DefaultMaxDepth = 20
+	DefaultMaxParts = 1000
+)
+
+// parser holds the state for a single Parse call.
+type parser struct {
+	lr       *lineReader
+	maxDepth int
+	maxParts int
+	parts    int // leaf parts numbered so far
+	nodes    int // all parts seen so far, for the maxParts limit
+}
+
+// Option configures a parser.
+type Option func(*parser)
+
+// WithMaxDepth sets the maximum multipart nesting depth. Multiparts nested
+// deeper than this are treated as opaque leaf parts. Default is 20.
+func WithMaxDepth(n int) Option {
+	return func(p *parser) {
+		p.maxDepth = n
+	}
+}
+
+// WithMaxParts sets the maximum number of MIME parts accepted before Parse
+// fails with ErrTooManyParts. Default is 1000.
+func WithMaxParts(n int) Option {
+	return func(p *parser) {
+		p.maxParts = n
+	}
+}
+
+// terminator describes what ended a part body: a boundary of one of the
+// enclosing multiparts, or the end of input (level -1). start is the offset
+// of the boundary line, or of the end of input.
+type terminator struct {
+	level int
+	final bool
+	start int64
+}
+
+// Parse reads a complete message from r and returns its body structure.
+func Parse(r io.Reader, opts ...Option) (*Message, error) {
+	p := &parser{
+		lr:       newLineReader(r),
+		maxDepth: DefaultMaxDepth,
+		maxParts: DefaultMaxParts,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	root, _, err := p.parsePart(nil, 0, "text/plain")
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{BodyStructure: root, Size: p.lr.off}
+	classify(msg)
+	return msg, nil
+}
+
+// parsePart parses the headers and body of one part. boundaries holds the
+// boundary strings of all enclosing multiparts, outermost first.
+func (p *parser) parsePart(boundaries []string, depth int, defaultType string) (*BodyPart, terminator, error) {
+	p.nodes++
+	if p.nodes > p.maxParts {
+		return nil, terminator{}, ErrTooManyParts{Limit: p.maxParts}
+	}
+
+	part := &BodyPart{}
+	if err := p.readHeaders(part, boundaries); err != nil {
+		return nil, terminator{}, err
+	}
+	p.describe(part, defaultType)
+	part.Offset = p.lr.pos()
+
+	if part.IsMultipart() && depth < p.maxDepth {
+		if boundary := p.boundary(part); boundary != "" {
+			term, err := p.parseMultipart(part, boundaries, boundary, depth)
+			return part, term, err
+		}
+	}
+
+	term, err := p.readLeafBody(part, boundaries)
+	if err != nil {
+		return nil, terminator{}, err
+	}
+	p.finishLeaf(part)
+	return part, term, nil
+}
+
+// finishLeaf assigns the next partId to a leaf part.
+func (p *parser) finishLeaf(part *BodyPart) {
+	p.parts++
+	part.PartID = strconv.Itoa(p.parts)
+}
+
+// readHeaders reads header fields into part. It stops after the blank line
+// that ends the header section, or before the first line that cannot be part
+// of it (a boundary, or text without a colon).
+func (p *parser) readHeaders(part *BodyPart, boundaries []string) error {
+	var current *Header
+	for {
+		l, err := p.lr.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !l.first {
+			// Continuation of an over-long line
+			if current != nil {
+				current.Value += string(l.text)
+			}
+			continue
+		}
+
+		if len(l.text) == 0 {
+			return nil
+		}
+
+		if _, _, ok := matchBoundary(l.text, boundaries); ok {
+			// Part has no body; let the caller see the boundary
+			p.lr.unread(l)
+			return nil
+		}
+
+		if (l.text[0] == ' ' || l.text[0] == '\t') && current != nil {
+			current.Value += l.eolString() + string(l.text)
+			continue
+		}
+
+		colon := bytes.IndexByte(l.text, ':')
+		if colon <= 0 {
+			// Not a header field: the body starts here without a blank line
+			p.lr.unread(l)
+			return nil
+		}
+
+		part.Headers = append(part.Headers, Header{
+			Name:  strings.TrimRight(string(l.text[:colon]), " \t"),
+			Value: string(l.text[colon+1:]),
+		})
+		current = &part.Headers[len(part.Headers)-1]
+	}
+}
+
+// readLeafBody skips over a leaf body, recording its extent and decoded size.
+func (p *parser) readLeafBody(part *BodyPart, boundaries []string) (terminator, error) {
+	counter := newSizeCounter(part.TransferEncoding)
+	prevEOL := 0
+	for {
+		l, err := p.lr.next()
+		if err == io.EOF {
+			part.Length = p.lr.off - part.Offset
+			part.Size = counter.total(prevEOL)
+			return p.endOfInput(), nil
+		}
+		if err != nil {
+			return terminator{}, err
+		}
+
+		if l.first {
+			if level, final, ok := matchBoundary(l.text, boundaries); ok {
+				part.Length = max(l.start-int64(prevEOL), part.Offset) - part.Offset
+				part.Size = counter.total(0)
+				return terminator{level: level, final: final, start: l.start}, nil
+			}
+		}
+
+		counter.add(l.text, prevEOL)
+		prevEOL = l.eol
+	}
+}
+
+// parseMultipart reads the preamble, children and epilogue of a multipart.
+func (p *parser) parseMultipart(part *BodyPart, boundaries []string, boundary string, depth int) (terminator, error) {
+	level := len(boundaries)
+	inner := append(boundaries[:level:level], boundary)
+
+	childType := "text/plain"
+	if part.Type == "multipart/digest" {
+		childType = "message/rfc822"
+	}
+
+	// Preamble
+	term, err := p.skipUntilBoundary(inner)
+	if err != nil {
+		return terminator{}, err
+	}
+
+	for term.level == level && !term.final {
+		child, t, err := p.parsePart(inner, depth+1, childType)
+		if err != nil {
+			return terminator{}, err
+		}
+		part.SubParts = append(part.SubParts, child)
+		term = t
+	}
+
+	if term.level == level {
+		// Epilogue, which only an enclosing boundary can end
+		term, err = p.skipUntilBoundary(boundaries)
+		if err != nil {
+			return terminator{}, err
+		}
+	}
+
+	part.Length = term.start - part.Offset
+	part.Size = part.Length
+	return term, nil
+}
+
+// skipUntilBoundary discards lines until a boundary in boundaries is found.
+func (p *parser) skipUntilBoundary(boundaries []string) (terminator, error) {
+	for {
+		l, err := p.lr.next()
+		if err == io.EOF {
+			return p.endOfInput(), nil
+		}
+		if err != nil {
+			return terminator{}, err
+		}
+		if !l.first {
+			continue
+		}
+		if level, final, ok := matchBoundary(l.text, boundaries); ok {
+			return terminator{level: level, final: final, start: l.start}, nil
+		}
+	}
+}
+
+// endOfInput returns the terminator for a part ended by the end of input.
+func (p *parser) endOfInput() terminator {
+	return terminator{level: -1, start: p.lr.off}
+}
+
+// boundary returns the boundary parameter of a multipart part, or "" if it is
+// missing or unusable.
+func (p *parser) boundary(part *BodyPart) string {
+	value, ok := part.Header("Content-Type")
+	if !ok {
+		return ""
+	}
+	_, params, _ := headers.ParseMediaType(value)
+	boundary := params["boundary"]
+	if boundary == "" || len(boundary) > 200 {
+		return ""
+	}
+	return boundary
+}
+
+// describe fills in the RFC 8621 properties derived from part headers.
+func (p *parser) describe(part *BodyPart, defaultType string) {
+	part.Type = defaultType
+	var typeParams map[string]string
+	if value, ok := part.Header("Content-Type"); ok {
+		mediaType, params, _ := headers.ParseMediaType(value)
+		if strings.Contains(mediaType, "/") {
+			part.Type = mediaType
+			typeParams = params
+		}
+	}
+
+	if strings.HasPrefix(part.Type, "text/") {
+		part.Charset = typeParams["charset"]
+		if part.Charset == "" {
+			part.Charset = "us-ascii"
+		}
+	}
+
+	var dispParams map[string]string
+	if value, ok := part.Header("Content-Disposition"); ok {
+		disposition, params, _ := headers.ParseMediaType(value)
+		part.Disposition = disposition
+		dispParams = params
+	}
+
+	part.Name = dispParams["filename"]
+	if part.Name == "" {
+		part.Name = typeParams["name"]
+	}
+
+	if value, ok := part.Header("Content-Transfer-Encoding"); ok {
+		part.TransferEncoding = strings.ToLower(strings.TrimSpace(unfold(value)))
+	}
+
+	if value, ok := part.Header("Content-ID"); ok {
+		part.CID = strings.Trim(strings.TrimSpace(unfold(value)), "<>")
+	}
+
+	if value, ok := part.Header("Content-Language"); ok {
+		for _, lang := range strings.Split(unfold(value), ",") {
+			if lang = strings.TrimSpace(lang); lang != "" {
+				part.Language = append(part.Language, lang)
+			}
+		}
+	}
+
+	if value, ok := part.Header("Content-Location"); ok {
+		part.Location = strings.TrimSpace(unfold(value))
+	}
+}
+
+// unfold removes folding line breaks from a raw header value.
+func unfold(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ReplaceAll(value, "\n", "")
+}
+
+// matchBoundary reports whether line is a delimiter for one of boundaries,
+// searching from the innermost. final is true for a close-delimiter.
+func matchBoundary(line []byte, boundaries []string) (level int, final bool, ok bool) {
+	if len(line) < 3 || line[0] != '-' || line[1] != '-' {
+		return 0, false, false
+	}
+	rest := line[2:]
+	for i := len(boundaries) - 1; i >= 0; i-- {
+		b := boundaries[i]
+		if !bytes.HasPrefix(rest, []byte(b)) {
+			continue
+		}
+		tail := rest[len(b):]
+		final := bytes.HasPrefix(tail, []byte("--"))
+		if final {
+			tail = tail[2:]
+		}
+		if len(bytes.TrimRight(tail, " \t")) == 0 {
+			return i, final, true
+		}
+	}
+	return 0, false, false
+}
+
+// line is a single line, or a fragment of an over-long line, from the input.
+type line struct {
+	text  []byte // content without the line terminator
+	start int64  // offset of text in the input
+	eol   int    // length of the line terminator (0 if none)
+	first bool   // true if text starts at the beginning of a line
+}
+
+// eolString returns the line terminator that ended the line.
+func (l line) eolString() string {
+	if l.eol == 2 {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// lineReader splits input into lines while tracking byte offsets.
+type lineReader struct {
+	r       *bufio.Reader
+	off     int64
+	midLine bool
+	peeked  bool
+	pending line
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, 8192)}
+}
+
+// next returns the next line. The returned text is only valid until the
+// following call to next.
+func (lr *lineReader) next() (line, error) {
+	if lr.peeked {
+		lr.peeked = false
+		return lr.pending, nil
+	}
+
+	data, err := lr.r.ReadSlice('\n')
+	l := line{start: lr.off, first: !lr.midLine}
+	lr.off += int64(len(data))
+
+	switch {
+	case err == bufio.ErrBufferFull:
+		lr.midLine = true
+		l.text = data
+		return l, nil
+	case err == io.EOF && len(data) == 0:
+		return line{}, io.EOF
+	case err != nil && err != io.EOF:
+		return line{}, err
+	}
+
+	lr.midLine = false
+	l.text = data
+	if n := len(data); n > 0 && data[n-1] == '\n' {
+		l.eol = 1
+		if n > 1 && data[n-2] == '\r' {
+			l.eol = 2
+		}
+		l.text = data[:n-l.eol]
+	}
+	return l, nil
+}
+
+// pos returns the offset of the next line to be returned by next.
+func (lr *lineReader) pos() int64 {
+	if lr.peeked {
+		return lr.pending.start
+	}
+	return lr.off
+}
+
+// unread pushes l back so the next call to next returns it again.
+func (lr *lineReader) unread(l line) {
+	l.text = bytes.Clone(l.text)
+	lr.pending = l
+	lr.peeked = true
+}
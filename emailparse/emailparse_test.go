@@ -0,0 +1,363 @@
+package emailparse
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func crlf(s string) string {
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// partBody returns the raw body of part from the message it was parsed from.
+func partBody(t *testing.T, raw string, part *BodyPart) string {
+	t.Helper()
+	body, err := io.ReadAll(part.Reader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("failed to read part body: %v", err)
+	}
+	return string(body)
+}
+
+func TestParseSinglePart(t *testing.T) {
+	raw := crlf("From: a@example.com\nSubject: Hello\nContent-Type: text/plain; charset=UTF-8\n\nHello world\n")
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	root := msg.BodyStructure
+	if root.PartID != "1" {
+		t.Errorf("expected partId '1', got '%s'", root.PartID)
+	}
+	if root.Type != "text/plain" {
+		t.Errorf("expected type text/plain, got '%s'", root.Type)
+	}
+	if root.Charset != "UTF-8" {
+		t.Errorf("expected charset UTF-8, got '%s'", root.Charset)
+	}
+	if len(root.Headers) != 3 {
+		t.Fatalf("expected 3 headers, got %d", len(root.Headers))
+	}
+	if root.Headers[1].Name != "Subject" || root.Headers[1].Value != " Hello" {
+		t.Errorf("unexpected header: %+v", root.Headers[1])
+	}
+	if body := partBody(t, raw, root); body != "Hello world\r\n" {
+		t.Errorf("expected body 'Hello world\\r\\n', got %q", body)
+	}
+	if root.Size != int64(len("Hello world\r\n")) {
+		t.Errorf("expected size %d, got %d", len("Hello world\r\n"), root.Size)
+	}
+	if msg.Size != int64(len(raw)) {
+		t.Errorf("expected message size %d, got %d", len(raw), msg.Size)
+	}
+}
+
+func TestParseDefaultsToTextPlainUSASCII(t *testing.T) {
+	raw := "Subject: test\n\nbody"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if msg.BodyStructure.Type != "text/plain" {
+		t.Errorf("expected text/plain, got '%s'", msg.BodyStructure.Type)
+	}
+	if msg.BodyStructure.Charset != "us-ascii" {
+		t.Errorf("expected us-ascii, got '%s'", msg.BodyStructure.Charset)
+	}
+}
+
+func TestParseFoldedHeaderKeepsRawValue(t *testing.T) {
+	raw := crlf("Subject: a long\n subject line\n\nbody")
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	value, ok := msg.BodyStructure.Header("subject")
+	if !ok {
+		t.Fatal("expected Subject header")
+	}
+	if value != " a long\r\n subject line" {
+		t.Errorf("expected folded raw value, got %q", value)
+	}
+}
+
+func TestParseMultipartOffsets(t *testing.T) {
+	raw := crlf(`Content-Type: multipart/mixed; boundary="XYZ"
+
+preamble
+--XYZ
+Content-Type: text/plain
+
+first part
+--XYZ
+Content-Type: text/html
+
+<p>second</p>
+--XYZ--
+epilogue
+`)
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	root := msg.BodyStructure
+	if root.PartID != "" {
+		t.Errorf("expected empty partId for multipart, got '%s'", root.PartID)
+	}
+	if len(root.SubParts) != 2 {
+		t.Fatalf("expected 2 subparts, got %d", len(root.SubParts))
+	}
+
+	first, second := root.SubParts[0], root.SubParts[1]
+	if first.PartID != "1" || second.PartID != "2" {
+		t.Errorf("expected partIds 1 and 2, got '%s' and '%s'", first.PartID, second.PartID)
+	}
+	if body := partBody(t, raw, first); body != "first part" {
+		t.Errorf("expected 'first part', got %q", body)
+	}
+	if body := partBody(t, raw, second); body != "<p>second</p>" {
+		t.Errorf("expected '<p>second</p>', got %q", body)
+	}
+	if second.Type != "text/html" {
+		t.Errorf("expected text/html, got '%s'", second.Type)
+	}
+}
+
+func TestParseNestedMultipart(t *testing.T) {
+	raw := `Content-Type: multipart/mixed; boundary=outer
+
+--outer
+Content-Type: multipart/alternative; boundary=inner
+
+--inner
+Content-Type: text/plain
+
+plain
+--inner
+Content-Type: text/html
+
+<b>html</b>
+--inner--
+--outer
+Content-Type: image/png
+Content-Disposition: attachment; filename="pic.png"
+Content-Transfer-Encoding: base64
+
+iVBORw0K
+GgoAAAA=
+--outer--
+`
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	root := msg.BodyStructure
+	if len(root.SubParts) != 2 {
+		t.Fatalf("expected 2 subparts, got %d", len(root.SubParts))
+	}
+	alt := root.SubParts[0]
+	if alt.Type != "multipart/alternative" || len(alt.SubParts) != 2 {
+		t.Fatalf("expected alternative with 2 parts, got %s with %d", alt.Type, len(alt.SubParts))
+	}
+	if body := partBody(t, raw, alt.SubParts[0]); body != "plain" {
+		t.Errorf("expected 'plain', got %q", body)
+	}
+
+	img := root.SubParts[1]
+	if img.PartID != "3" {
+		t.Errorf("expected partId 3, got '%s'", img.PartID)
+	}
+	if img.Name != "pic.png" {
+		t.Errorf("expected name pic.png, got '%s'", img.Name)
+	}
+	if img.Disposition != "attachment" {
+		t.Errorf("expected attachment disposition, got '%s'", img.Disposition)
+	}
+	if img.TransferEncoding != "base64" {
+		t.Errorf("expected base64, got '%s'", img.TransferEncoding)
+	}
+	if img.Charset != "" {
+		t.Errorf("expected no charset for image, got '%s'", img.Charset)
+	}
+	// 15 base64 characters decode to 11 bytes
+	if img.Size != 11 {
+		t.Errorf("expected decoded size 11, got %d", img.Size)
+	}
+}
+
+func TestParseQuotedPrintableSize(t *testing.T) {
+	raw := "Content-Transfer-Encoding: quoted-printable\n\ncaf=C3=A9 soft=\nbreak\n"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	// "café softbreak\n"
+	if msg.BodyStructure.Size != int64(len("café softbreak\n")) {
+		t.Errorf("expected size %d, got %d", len("café softbreak\n"), msg.BodyStructure.Size)
+	}
+}
+
+func TestParseMissingFinalBoundary(t *testing.T) {
+	raw := "Content-Type: multipart/mixed; boundary=b\n\n--b\n\nonly part\n"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	parts := msg.BodyStructure.SubParts
+	if len(parts) != 1 {
+		t.Fatalf("expected 1 subpart, got %d", len(parts))
+	}
+	if body := partBody(t, raw, parts[0]); body != "only part\n" {
+		t.Errorf("expected 'only part\\n', got %q", body)
+	}
+}
+
+func TestParseOuterBoundaryClosesInnerPart(t *testing.T) {
+	raw := `Content-Type: multipart/mixed; boundary=outer
+
+--outer
+Content-Type: multipart/alternative; boundary=inner
+
+--inner
+
+truncated
+--outer
+
+second
+--outer--
+`
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	root := msg.BodyStructure
+	if len(root.SubParts) != 2 {
+		t.Fatalf("expected 2 subparts, got %d", len(root.SubParts))
+	}
+	if body := partBody(t, raw, root.SubParts[1]); body != "second" {
+		t.Errorf("expected 'second', got %q", body)
+	}
+}
+
+func TestParseBodyWithoutBlankLine(t *testing.T) {
+	raw := "Content-Type: multipart/mixed; boundary=b\n\n--b\nno headers here\n--b--\n"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	part := msg.BodyStructure.SubParts[0]
+	if len(part.Headers) != 0 {
+		t.Errorf("expected no headers, got %d", len(part.Headers))
+	}
+	if body := partBody(t, raw, part); body != "no headers here" {
+		t.Errorf("expected 'no headers here', got %q", body)
+	}
+}
+
+func TestParseDigestDefaultsToMessage(t *testing.T) {
+	raw := "Content-Type: multipart/digest; boundary=d\n\n--d\n\nSubject: inner\n\nhi\n--d--\n"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := msg.BodyStructure.SubParts[0].Type; got != "message/rfc822" {
+		t.Errorf("expected message/rfc822, got '%s'", got)
+	}
+}
+
+func TestParseEncodedFilename(t *testing.T) {
+	raw := "Content-Type: application/pdf; name=\"=?UTF-8?B?w6l0w6kucGRm?=\"\n\nx"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if msg.BodyStructure.Name != "été.pdf" {
+		t.Errorf("expected name 'été.pdf', got '%s'", msg.BodyStructure.Name)
+	}
+}
+
+func TestParseContentHeaders(t *testing.T) {
+	raw := "Content-Type: image/gif\nContent-ID: <img1@example.com>\nContent-Language: en, fr\nContent-Location: http://example.com/a.gif\n\nGIF"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	root := msg.BodyStructure
+	if root.CID != "img1@example.com" {
+		t.Errorf("expected cid without brackets, got '%s'", root.CID)
+	}
+	if len(root.Language) != 2 || root.Language[0] != "en" || root.Language[1] != "fr" {
+		t.Errorf("expected languages [en fr], got %v", root.Language)
+	}
+	if root.Location != "http://example.com/a.gif" {
+		t.Errorf("unexpected location '%s'", root.Location)
+	}
+}
+
+func TestParseMaxDepthTreatsMultipartAsLeaf(t *testing.T) {
+	raw := "Content-Type: multipart/mixed; boundary=b\n\n--b\n\npart\n--b--\n"
+	msg, err := Parse(strings.NewReader(raw), WithMaxDepth(0))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(msg.BodyStructure.SubParts) != 0 {
+		t.Errorf("expected no subparts, got %d", len(msg.BodyStructure.SubParts))
+	}
+	if msg.BodyStructure.PartID != "1" {
+		t.Errorf("expected opaque multipart to have partId 1, got '%s'", msg.BodyStructure.PartID)
+	}
+}
+
+func TestParseMaxParts(t *testing.T) {
+	raw := "Content-Type: multipart/mixed; boundary=b\n\n--b\n\none\n--b\n\ntwo\n--b--\n"
+	_, err := Parse(strings.NewReader(raw), WithMaxParts(2))
+	var tooMany ErrTooManyParts
+	if !errors.As(err, &tooMany) {
+		t.Fatalf("expected ErrTooManyParts, got %v", err)
+	}
+	if tooMany.Limit != 2 {
+		t.Errorf("expected limit 2, got %d", tooMany.Limit)
+	}
+}
+
+func TestParseLongLines(t *testing.T) {
+	long := strings.Repeat("x", 20000)
+	raw := "Content-Type: multipart/mixed; boundary=b\n\n--b\n\n" + long + "\n--b--\n"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	part := msg.BodyStructure.SubParts[0]
+	if part.Length != int64(len(long)) {
+		t.Errorf("expected length %d, got %d", len(long), part.Length)
+	}
+}
+
+func TestParseEmptyInput(t *testing.T) {
+	msg, err := Parse(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if msg.BodyStructure == nil {
+		t.Fatal("expected a body structure")
+	}
+	if msg.BodyStructure.Length != 0 {
+		t.Errorf("expected empty body, got length %d", msg.BodyStructure.Length)
+	}
+}
+
+func TestParseRFC2231Filename(t *testing.T) {
+	raw := "Content-Type: application/pdf\nContent-Disposition: attachment;\n filename*=iso-8859-1''r%E9sum%E9.pdf\n\nx"
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if msg.BodyStructure.Name != "résumé.pdf" {
+		t.Errorf("expected name 'résumé.pdf', got '%s'", msg.BodyStructure.Name)
+	}
+}
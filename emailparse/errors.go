@@ -0,0 +1,17 @@
+package emailparse
+
+// Error types for the email parser.
+
+import (
+	"fmt"
+)
+
+// ErrTooManyParts indicates the message contains more MIME parts than the
+// configured limit.
+type ErrTooManyParts struct {
+	Limit int
+}
+
+func (e ErrTooManyParts) Error() string {
+	return fmt.Sprintf("message exceeds maximum of %d parts", e.Limit)
+}
@@ -0,0 +1,36 @@
+package emailparse_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jarrod-lowe/jmap-service-libs/emailparse"
+)
+
+func ExampleParse() {
+	raw := `Content-Type: multipart/alternative; boundary=alt
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+Hello
+--alt
+Content-Type: text/html; charset=utf-8
+
+<p>Hello</p>
+--alt--
+`
+	msg, err := emailparse.Parse(strings.NewReader(raw))
+	if err != nil {
+		panic(err)
+	}
+	for _, part := range msg.TextBody {
+		fmt.Println("text:", part.PartID, part.Type)
+	}
+	for _, part := range msg.HTMLBody {
+		fmt.Println("html:", part.PartID, part.Type)
+	}
+	// Output:
+	// text: 1 text/plain
+	// html: 2 text/html
+}
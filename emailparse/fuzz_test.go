@@ -0,0 +1,32 @@
+package emailparse
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzParse verifies that Parse never panics and that every part's body range
+// lies within the message.
+func FuzzParse(f *testing.F) {
+	f.Add("Subject: x\n\nbody")
+	f.Add("Content-Type: multipart/mixed; boundary=b\n\n--b\n\none\n--b--\n")
+	f.Add("Content-Type: multipart/mixed; boundary=b\r\n\r\n--b\r\nContent-Transfer-Encoding: base64\r\n\r\nQUJD\r\n--b")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		msg, err := Parse(strings.NewReader(raw))
+		if err != nil {
+			return
+		}
+		var check func(p *BodyPart)
+		check = func(p *BodyPart) {
+			if p.Offset < 0 || p.Length < 0 || p.Offset+p.Length > int64(len(raw)) {
+				t.Fatalf("part range [%d, +%d) outside message of %d bytes", p.Offset, p.Length, len(raw))
+			}
+			for _, sub := range p.SubParts {
+				check(sub)
+			}
+		}
+		check(msg.BodyStructure)
+	})
+}
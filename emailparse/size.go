@@ -0,0 +1,89 @@
+package emailparse
+
+import (
+	"bytes"
+)
+
+// sizeCounter computes the decoded size of a part body from its raw lines
+// without decoding it.
+type sizeCounter struct {
+	encoding string
+	n        int64 // decoded bytes so far (quoted-printable and identity)
+	chars    int64 // base64 alphabet characters so far
+	soft     bool  // previous quoted-printable line ended in a soft line break
+}
+
+func newSizeCounter(encoding string) *sizeCounter {
+	return &sizeCounter{encoding: encoding}
+}
+
+// add counts one line of body text. prevEOL is the length of the line
+// terminator that preceded it, which is only known to be part of the body
+// once a following line has been seen.
+func (c *sizeCounter) add(text []byte, prevEOL int) {
+	switch c.encoding {
+	case "base64":
+		for _, b := range text {
+			if isBase64Char(b) {
+				c.chars++
+			}
+		}
+	case "quoted-printable":
+		if !c.soft {
+			c.n += int64(prevEOL)
+		}
+		// Trailing whitespace is transport padding
+		text = bytes.TrimRight(text, " \t")
+		c.soft = len(text) > 0 && text[len(text)-1] == '='
+		if c.soft {
+			text = text[:len(text)-1]
+		}
+		c.n += quotedPrintableLen(text)
+	default:
+		c.n += int64(prevEOL) + int64(len(text))
+	}
+}
+
+// total returns the decoded size, including a final line terminator of
+// length trailingEOL.
+func (c *sizeCounter) total(trailingEOL int) int64 {
+	switch c.encoding {
+	case "base64":
+		size := c.chars / 4 * 3
+		switch c.chars % 4 {
+		case 2:
+			size++
+		case 3:
+			size += 2
+		}
+		return size
+	case "quoted-printable":
+		if c.soft {
+			return c.n
+		}
+		return c.n + int64(trailingEOL)
+	default:
+		return c.n + int64(trailingEOL)
+	}
+}
+
+// quotedPrintableLen returns the decoded length of a quoted-printable line
+// with its soft line break already removed.
+func quotedPrintableLen(text []byte) int64 {
+	var n int64
+	for i := 0; i < len(text); i++ {
+		if text[i] == '=' && i+2 < len(text) && isHexChar(text[i+1]) && isHexChar(text[i+2]) {
+			i += 2
+		}
+		n++
+	}
+	return n
+}
+
+func isBase64Char(b byte) bool {
+	return (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '+' || b == '/'
+}
+
+func isHexChar(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
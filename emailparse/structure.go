@@ -0,0 +1,105 @@
+package emailparse
+
+import (
+	"strings"
+)
+
+// classify fills in the TextBody, HTMLBody and Attachments lists of msg using
+// the algorithm in RFC 8621 section 4.1.4.
+func classify(msg *Message) {
+	text := &bodyList{}
+	html := &bodyList{}
+	var attachments []*BodyPart
+
+	parseStructure([]*BodyPart{msg.BodyStructure}, "mixed", false, html, text, &attachments)
+
+	msg.TextBody = text.parts
+	msg.HTMLBody = html.parts
+	msg.Attachments = attachments
+}
+
+// bodyList is a list of body parts that can be switched off for the rest of
+// an alternative, standing in for the JavaScript null in the RFC algorithm.
+type bodyList struct {
+	parts []*BodyPart
+}
+
+func (l *bodyList) add(p *BodyPart) {
+	if l != nil {
+		l.parts = append(l.parts, p)
+	}
+}
+
+func (l *bodyList) length() int {
+	if l == nil {
+		return -1
+	}
+	return len(l.parts)
+}
+
+// parseStructure is a transcription of the parseStructure function in
+// RFC 8621 section 4.1.4.
+func parseStructure(parts []*BodyPart, multipartType string, inAlternative bool, htmlBody, textBody *bodyList, attachments *[]*BodyPart) {
+	textLength := textBody.length()
+	htmlLength := htmlBody.length()
+
+	for i, part := range parts {
+		isMultipart := part.IsMultipart()
+		isInline := part.Disposition != "attachment" &&
+			(part.Type == "text/plain" || part.Type == "text/html" || isInlineMediaType(part.Type)) &&
+			(i == 0 || (multipartType != "related" && (isInlineMediaType(part.Type) || part.Name == "")))
+
+		switch {
+		case isMultipart:
+			subMultiType := strings.TrimPrefix(part.Type, "multipart/")
+			parseStructure(part.SubParts, subMultiType, inAlternative || subMultiType == "alternative",
+				htmlBody, textBody, attachments)
+
+		case isInline:
+			if multipartType == "alternative" {
+				switch part.Type {
+				case "text/plain":
+					textBody.add(part)
+				case "text/html":
+					htmlBody.add(part)
+				default:
+					*attachments = append(*attachments, part)
+				}
+				continue
+			} else if inAlternative {
+				if part.Type == "text/plain" {
+					htmlBody = nil
+				}
+				if part.Type == "text/html" {
+					textBody = nil
+				}
+			}
+			textBody.add(part)
+			htmlBody.add(part)
+			if (textBody == nil || htmlBody == nil) && isInlineMediaType(part.Type) {
+				*attachments = append(*attachments, part)
+			}
+
+		default:
+			*attachments = append(*attachments, part)
+		}
+	}
+
+	if multipartType == "alternative" && textBody != nil && htmlBody != nil {
+		// Found HTML part only
+		if textLength == len(textBody.parts) && htmlLength != len(htmlBody.parts) {
+			textBody.parts = append(textBody.parts, htmlBody.parts[htmlLength:]...)
+		}
+		// Found plaintext part only
+		if htmlLength == len(htmlBody.parts) && textLength != len(textBody.parts) {
+			htmlBody.parts = append(htmlBody.parts, textBody.parts[textLength:]...)
+		}
+	}
+}
+
+// isInlineMediaType returns true for media types that can be displayed inline.
+func isInlineMediaType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "image/") ||
+		strings.HasPrefix(mediaType, "audio/") ||
+		strings.HasPrefix(mediaType, "video/")
+}
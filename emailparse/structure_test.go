@@ -0,0 +1,179 @@
+package emailparse
+
+import (
+	"strings"
+	"testing"
+)
+
+// partIDs returns the partIds of parts, for compact comparison.
+func partIDs(parts []*BodyPart) string {
+	ids := make([]string, len(parts))
+	for i, p := range parts {
+		ids[i] = p.PartID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestClassifySinglePlainText(t *testing.T) {
+	msg, err := Parse(strings.NewReader("Subject: x\n\nhello"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := partIDs(msg.TextBody); got != "1" {
+		t.Errorf("expected textBody [1], got [%s]", got)
+	}
+	if got := partIDs(msg.HTMLBody); got != "1" {
+		t.Errorf("expected htmlBody [1], got [%s]", got)
+	}
+	if len(msg.Attachments) != 0 {
+		t.Errorf("expected no attachments, got %d", len(msg.Attachments))
+	}
+}
+
+func TestClassifyAlternative(t *testing.T) {
+	raw := `Content-Type: multipart/alternative; boundary=a
+
+--a
+Content-Type: text/plain
+
+plain
+--a
+Content-Type: text/html
+
+<p>html</p>
+--a--
+`
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := partIDs(msg.TextBody); got != "1" {
+		t.Errorf("expected textBody [1], got [%s]", got)
+	}
+	if got := partIDs(msg.HTMLBody); got != "2" {
+		t.Errorf("expected htmlBody [2], got [%s]", got)
+	}
+}
+
+func TestClassifyAlternativeHTMLOnlyFallsBackForText(t *testing.T) {
+	raw := `Content-Type: multipart/alternative; boundary=a
+
+--a
+Content-Type: text/html
+
+<p>html</p>
+--a--
+`
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := partIDs(msg.TextBody); got != "1" {
+		t.Errorf("expected textBody to fall back to html part [1], got [%s]", got)
+	}
+}
+
+// TestClassifyRFC8621Example uses the worked example from RFC 8621 section 4.1.4.
+func TestClassifyRFC8621Example(t *testing.T) {
+	raw := `Content-Type: multipart/mixed; boundary=m
+
+--m
+Content-Type: text/plain
+
+A
+--m
+Content-Type: multipart/mixed; boundary=m2
+
+--m2
+Content-Type: multipart/alternative; boundary=alt
+
+--alt
+Content-Type: multipart/mixed; boundary=m3
+
+--m3
+Content-Type: text/plain
+
+B
+--m3
+Content-Type: image/jpeg
+
+C
+--m3
+Content-Type: text/plain
+
+D
+--m3--
+--alt
+Content-Type: multipart/related; boundary=rel
+
+--rel
+Content-Type: text/html
+
+E
+--rel
+Content-Type: image/jpeg
+
+F
+--rel--
+--alt--
+--m2
+Content-Type: image/jpeg
+Content-Disposition: attachment
+
+G
+--m2
+Content-Type: application/x-excel
+
+H
+--m2
+Content-Type: message/rfc822
+
+J
+--m2--
+--m
+Content-Type: text/plain
+
+K
+--m--
+`
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Leaf partIds in order: A=1 B=2 C=3 D=4 E=5 F=6 G=7 H=8 J=9 K=10
+	if got := partIDs(msg.TextBody); got != "1,2,3,4,10" {
+		t.Errorf("expected textBody [A B C D K] = [1,2,3,4,10], got [%s]", got)
+	}
+	if got := partIDs(msg.HTMLBody); got != "1,5,10" {
+		t.Errorf("expected htmlBody [A E K] = [1,5,10], got [%s]", got)
+	}
+	if got := partIDs(msg.Attachments); got != "3,6,7,8,9" {
+		t.Errorf("expected attachments [C F G H J] = [3,6,7,8,9], got [%s]", got)
+	}
+}
+
+func TestClassifyNamedPartIsAttachment(t *testing.T) {
+	raw := `Content-Type: multipart/mixed; boundary=m
+
+--m
+Content-Type: text/plain
+
+body
+--m
+Content-Type: text/plain; name="notes.txt"
+
+notes
+--m--
+`
+	msg, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := partIDs(msg.TextBody); got != "1" {
+		t.Errorf("expected textBody [1], got [%s]", got)
+	}
+	if got := partIDs(msg.Attachments); got != "2" {
+		t.Errorf("expected attachments [2], got [%s]", got)
+	}
+}
@@ -0,0 +1,72 @@
+package emailparse
+
+import (
+	"io"
+	"strings"
+)
+
+// Header is a single header field as it appears in the raw message.
+// Value is the raw value following the colon, including any leading
+// whitespace and folding line breaks.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BodyPart is a node in the MIME tree, using the RFC 8621 EmailBodyPart
+// property names. Empty strings represent JMAP null values.
+type BodyPart struct {
+	PartID      string      `json:"partId,omitempty"`
+	Size        int64       `json:"size"`
+	Headers     []Header    `json:"headers"`
+	Name        string      `json:"name,omitempty"`
+	Type        string      `json:"type"`
+	Charset     string      `json:"charset,omitempty"`
+	Disposition string      `json:"disposition,omitempty"`
+	CID         string      `json:"cid,omitempty"`
+	Language    []string    `json:"language,omitempty"`
+	Location    string      `json:"location,omitempty"`
+	SubParts    []*BodyPart `json:"subParts,omitempty"`
+
+	// TransferEncoding is the lowercased Content-Transfer-Encoding, or empty
+	// when the header is absent.
+	TransferEncoding string `json:"-"`
+
+	// Offset and Length locate the raw (still transfer-encoded) body within
+	// the message, excluding the line break that precedes a boundary.
+	Offset int64 `json:"-"`
+	Length int64 `json:"-"`
+}
+
+// Message is the parsed structure of an email.
+type Message struct {
+	BodyStructure *BodyPart
+	TextBody      []*BodyPart
+	HTMLBody      []*BodyPart
+	Attachments   []*BodyPart
+
+	// Size is the total size of the raw message in bytes.
+	Size int64
+}
+
+// Header returns the raw value of the first header field with the given name,
+// compared case-insensitively. Returns false if the field is not present.
+func (p *BodyPart) Header(name string) (string, bool) {
+	for _, h := range p.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value, true
+		}
+	}
+	return "", false
+}
+
+// IsMultipart returns true if the part is a multipart/* container.
+func (p *BodyPart) IsMultipart() bool {
+	return strings.HasPrefix(p.Type, "multipart/")
+}
+
+// Reader returns a reader over the raw (transfer-encoded) body of the part
+// within blob, which must be the message the part was parsed from.
+func (p *BodyPart) Reader(blob io.ReaderAt) *io.SectionReader {
+	return io.NewSectionReader(blob, p.Offset, p.Length)
+}
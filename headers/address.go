@@ -0,0 +1,208 @@
+package headers
+
+import (
+	"strings"
+)
+
+// EmailAddress is the RFC 8621 EmailAddress object. An empty Name represents
+// the JMAP null value.
+type EmailAddress struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+}
+
+// EmailAddressGroup is the RFC 8621 EmailAddressGroup object. An empty Name
+// represents addresses that were not part of a named group.
+type EmailAddressGroup struct {
+	Name      string         `json:"name,omitempty"`
+	Addresses []EmailAddress `json:"addresses"`
+}
+
+// tokenKind identifies an address-list token.
+type tokenKind int
+
+const (
+	tokAtom tokenKind = iota
+	tokQuoted
+	tokComment
+	tokAngle
+	tokSpecial
+)
+
+// addrToken is a lexical token of an RFC 5322 address list.
+type addrToken struct {
+	kind tokenKind
+	text string // atom text, unquoted string, comment text, angle content or special character
+}
+
+// tokenizeAddresses splits an unfolded address list into tokens.
+func tokenizeAddresses(s string) []addrToken {
+	var tokens []addrToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			text, n := scanDelimited(s[i:], '"', '"')
+			tokens = append(tokens, addrToken{kind: tokQuoted, text: text})
+			i += n
+		case c == '(':
+			text, n := scanDelimited(s[i:], '(', ')')
+			tokens = append(tokens, addrToken{kind: tokComment, text: text})
+			i += n
+		case c == '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				end = len(s) - i
+			}
+			tokens = append(tokens, addrToken{kind: tokAngle, text: s[i+1 : i+end]})
+			i += min(end+1, len(s)-i)
+		case c == ',' || c == ':' || c == ';':
+			tokens = append(tokens, addrToken{kind: tokSpecial, text: string(c)})
+			i++
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\"(<,:;", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, addrToken{kind: tokAtom, text: s[i:j]})
+			i = j
+		}
+	}
+	return tokens
+}
+
+// scanDelimited reads a quoted string or (nestable) comment starting at s[0],
+// returning its content with quoted-pairs decoded and the bytes consumed.
+func scanDelimited(s string, open, close byte) (string, int) {
+	var out strings.Builder
+	depth := 1
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			out.WriteByte(s[i])
+			continue
+		case c == close:
+			depth--
+			if depth == 0 {
+				return out.String(), i + 1
+			}
+		case c == open:
+			depth++
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), len(s)
+}
+
+// addressParser accumulates mailboxes and groups from tokens.
+type addressParser struct {
+	groups    []EmailAddressGroup
+	inGroup   bool
+	ungrouped bool // last group collects mailboxes outside any group
+
+	phrase  []string // display-name words, decoded later
+	raw     strings.Builder
+	comment string
+	pending bool // phrase/raw hold a possible bare addr-spec
+}
+
+// parseAddressList parses an address list into groups, with ungrouped
+// mailboxes collected into groups with an empty name.
+func parseAddressList(value string) []EmailAddressGroup {
+	p := &addressParser{}
+	for _, tok := range tokenizeAddresses(unfold(value)) {
+		switch tok.kind {
+		case tokAtom, tokQuoted:
+			p.phrase = append(p.phrase, tok.text)
+			if tok.kind == tokQuoted {
+				p.raw.WriteString(`"` + tok.text + `"`)
+			} else {
+				p.raw.WriteString(tok.text)
+			}
+			p.pending = true
+		case tokComment:
+			p.comment = tok.text
+		case tokAngle:
+			p.add(EmailAddress{Name: p.displayName(), Email: stripRoute(tok.text)})
+			p.reset()
+		case tokSpecial:
+			switch tok.text {
+			case ",":
+				p.flushBare()
+			case ":":
+				if p.inGroup {
+					// Stray colon: treat as part of the phrase
+					p.phrase = append(p.phrase, ":")
+					continue
+				}
+				p.groups = append(p.groups, EmailAddressGroup{Name: p.displayName(), Addresses: []EmailAddress{}})
+				p.inGroup = true
+				p.ungrouped = false
+				p.reset()
+			case ";":
+				p.flushBare()
+				p.inGroup = false
+				p.ungrouped = false
+			}
+		}
+	}
+	p.flushBare()
+	return p.groups
+}
+
+// add appends a mailbox to the current group, opening an unnamed group if
+// the mailbox is outside any group and no unnamed group is open.
+func (p *addressParser) add(addr EmailAddress) {
+	if !p.inGroup && !p.ungrouped {
+		p.groups = append(p.groups, EmailAddressGroup{Addresses: []EmailAddress{}})
+		p.ungrouped = true
+	}
+	g := &p.groups[len(p.groups)-1]
+	g.Addresses = append(g.Addresses, addr)
+}
+
+// flushBare emits any pending tokens as a bare addr-spec mailbox, using a
+// trailing comment as the name.
+func (p *addressParser) flushBare() {
+	if p.pending {
+		p.add(EmailAddress{Name: collapseSpace(DecodeWords(p.comment)), Email: p.raw.String()})
+	}
+	p.reset()
+}
+
+// displayName returns the decoded display name from the phrase words, or the
+// comment if there is no phrase.
+func (p *addressParser) displayName() string {
+	if len(p.phrase) == 0 {
+		return collapseSpace(DecodeWords(p.comment))
+	}
+	return collapseSpace(DecodeWords(strings.Join(p.phrase, " ")))
+}
+
+func (p *addressParser) reset() {
+	p.phrase = p.phrase[:0]
+	p.raw.Reset()
+	p.comment = ""
+	p.pending = false
+}
+
+// stripRoute removes an obsolete source route ("@a,@b:") and whitespace
+// from angle-addr content.
+func stripRoute(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if strings.HasPrefix(addr, "@") {
+		if i := strings.IndexByte(addr, ':'); i >= 0 {
+			addr = addr[i+1:]
+		}
+	}
+	return strings.Join(strings.Fields(addr), "")
+}
+
+// collapseSpace replaces runs of whitespace with single spaces and trims.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
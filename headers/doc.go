@@ -0,0 +1,25 @@
+// Package headers decodes email header field values.
+//
+// It provides:
+//
+//   - [DecodeWords]: RFC 2047 encoded-word decoding in any charset known to
+//     golang.org/x/text/encoding/htmlindex, joining adjacent words before
+//     charset conversion so multi-byte characters split across words survive
+//   - [ParseMediaType]: Content-Type / Content-Disposition parsing with
+//     RFC 2231 parameter continuations and charsets
+//   - The JMAP parsed forms from RFC 8621 section 4.1.2: [AsText],
+//     [AsAddresses], [AsGroupedAddresses], [AsMessageIDs], [AsDate] and
+//     [AsURLs]
+//
+// All functions take the raw header value as it appears after the colon,
+// including folding line breaks, and are lenient: malformed input yields a
+// best-effort result rather than an error wherever RFC 8621 allows it.
+//
+// Example:
+//
+//	subject := headers.AsText(" =?UTF-8?Q?Caf=C3=A9?= menu")
+//	// subject is "Café menu"
+//
+//	from := headers.AsAddresses(` "Jane Doe" <jane@example.com>`)
+//	// from[0].Name is "Jane Doe", from[0].Email is "jane@example.com"
+package headers
@@ -0,0 +1,96 @@
+package headers
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// AsText returns the JMAP Text form of a raw header value (RFC 8621 section
+// 4.1.2.2): unfolded, leading spaces removed, encoded words decoded, and
+// normalised to NFC.
+func AsText(value string) string {
+	value = strings.TrimLeft(unfold(value), " ")
+	return norm.NFC.String(DecodeWords(value))
+}
+
+// AsAddresses returns the JMAP Addresses form of a raw header value
+// (RFC 8621 section 4.1.2.3). Group syntax is flattened. Returns nil if the
+// value contains no addresses.
+func AsAddresses(value string) []EmailAddress {
+	var addrs []EmailAddress
+	for _, g := range parseAddressList(value) {
+		addrs = append(addrs, g.Addresses...)
+	}
+	return addrs
+}
+
+// AsGroupedAddresses returns the JMAP GroupedAddresses form of a raw header
+// value (RFC 8621 section 4.1.2.4). Consecutive mailboxes outside a group are
+// collected into a group with an empty name.
+func AsGroupedAddresses(value string) []EmailAddressGroup {
+	return parseAddressList(value)
+}
+
+// AsMessageIDs returns the JMAP MessageIds form of a raw header value
+// (RFC 8621 section 4.1.2.5): the msg-id values without angle brackets.
+// Returns nil if the value is not a valid list of msg-ids.
+func AsMessageIDs(value string) []string {
+	return parseBracketed(value, func(id string) bool {
+		return id != "" && strings.Contains(id, "@") && !strings.ContainsAny(id, " \t<")
+	})
+}
+
+// AsDate returns the JMAP Date form of a raw header value (RFC 8621 section
+// 4.1.2.6). Returns nil if the value is not a valid RFC 5322 date-time.
+func AsDate(value string) *time.Time {
+	value = strings.TrimSpace(stripComments(unfold(value)))
+	t, err := mail.ParseDate(value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// AsURLs returns the JMAP URLs form of a raw header value (RFC 8621 section
+// 4.1.2.7), as used by the RFC 2369 List-* fields. Returns nil if the value
+// is not a valid list of bracketed URLs.
+func AsURLs(value string) []string {
+	return parseBracketed(value, func(url string) bool {
+		return url != "" && !strings.ContainsAny(url, "<")
+	})
+}
+
+// parseBracketed parses a comma- or whitespace-separated list of <...>
+// items with optional comments. valid checks each item; nil is returned if
+// any item fails or anything other than CFWS appears outside the brackets.
+func parseBracketed(value string, valid func(string) bool) []string {
+	value = unfold(value)
+	var items []string
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case c == ' ' || c == '\t' || c == ',':
+			i++
+		case c == '(':
+			_, n := scanDelimited(value[i:], '(', ')')
+			i += n
+		case c == '<':
+			end := strings.IndexByte(value[i:], '>')
+			if end < 0 {
+				return nil
+			}
+			item := strings.Join(strings.Fields(value[i+1:i+end]), "")
+			if !valid(item) {
+				return nil
+			}
+			items = append(items, item)
+			i += end + 1
+		default:
+			return nil
+		}
+	}
+	return items
+}
@@ -0,0 +1,126 @@
+package headers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAsText(t *testing.T) {
+	got := AsText(" =?UTF-8?Q?Caf=C3=A9?=\r\n menu")
+	if got != "Café menu" {
+		t.Errorf("expected 'Café menu', got '%s'", got)
+	}
+}
+
+func TestAsTextNormalisesToNFC(t *testing.T) {
+	// "e" followed by combining acute accent
+	got := AsText(" =?UTF-8?Q?e=CC=81?=")
+	if got != "é" {
+		t.Errorf("expected precomposed 'é', got %q", got)
+	}
+}
+
+func TestAsAddresses(t *testing.T) {
+	addrs := AsAddresses(` "James Smythe" <james@example.com>, jane@example.com (Jane),
+ =?UTF-8?Q?John_Sm=C3=AEth?= <john@example.com>`)
+	want := []EmailAddress{
+		{Name: "James Smythe", Email: "james@example.com"},
+		{Name: "Jane", Email: "jane@example.com"},
+		{Name: "John Smîth", Email: "john@example.com"},
+	}
+	if len(addrs) != len(want) {
+		t.Fatalf("expected %d addresses, got %d: %v", len(want), len(addrs), addrs)
+	}
+	for i := range want {
+		if addrs[i] != want[i] {
+			t.Errorf("address %d: expected %+v, got %+v", i, want[i], addrs[i])
+		}
+	}
+}
+
+func TestAsAddressesFlattensGroups(t *testing.T) {
+	addrs := AsAddresses(` Friends: jane@example.com, bob@example.com;, carol@example.com`)
+	if len(addrs) != 3 {
+		t.Fatalf("expected 3 addresses, got %d: %v", len(addrs), addrs)
+	}
+	if addrs[2].Email != "carol@example.com" {
+		t.Errorf("expected carol last, got %+v", addrs[2])
+	}
+}
+
+func TestAsAddressesEmpty(t *testing.T) {
+	if addrs := AsAddresses(" undisclosed-recipients:;"); addrs != nil {
+		t.Errorf("expected nil, got %v", addrs)
+	}
+}
+
+// TestAsGroupedAddressesRFC8621Example uses the example from RFC 8621 section 4.1.2.4.
+func TestAsGroupedAddressesRFC8621Example(t *testing.T) {
+	groups := AsGroupedAddresses(` "James Smythe" <james@example.com>, Friends:
+ jane@example.com, =?UTF-8?Q?John_Sm=C3=AEth?= <john@example.com>;`)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d: %+v", len(groups), groups)
+	}
+	if groups[0].Name != "" || len(groups[0].Addresses) != 1 || groups[0].Addresses[0].Email != "james@example.com" {
+		t.Errorf("unexpected first group: %+v", groups[0])
+	}
+	if groups[1].Name != "Friends" || len(groups[1].Addresses) != 2 {
+		t.Fatalf("unexpected second group: %+v", groups[1])
+	}
+	if groups[1].Addresses[1].Name != "John Smîth" {
+		t.Errorf("expected 'John Smîth', got '%s'", groups[1].Addresses[1].Name)
+	}
+}
+
+func TestAsAddressesQuotedLocalPart(t *testing.T) {
+	addrs := AsAddresses(` "john smith"@example.com`)
+	if len(addrs) != 1 || addrs[0].Email != `"john smith"@example.com` {
+		t.Errorf("unexpected result: %+v", addrs)
+	}
+}
+
+func TestAsMessageIDs(t *testing.T) {
+	ids := AsMessageIDs(" <a@example.com>\r\n <b@example.com> (comment)")
+	if len(ids) != 2 || ids[0] != "a@example.com" || ids[1] != "b@example.com" {
+		t.Errorf("unexpected ids: %v", ids)
+	}
+}
+
+func TestAsMessageIDsInvalid(t *testing.T) {
+	if ids := AsMessageIDs(" not-an-id"); ids != nil {
+		t.Errorf("expected nil, got %v", ids)
+	}
+}
+
+func TestAsDate(t *testing.T) {
+	d := AsDate(" Tue, 1 Jul 2003 10:52:37 +0200 (CEST)")
+	if d == nil {
+		t.Fatal("expected a date")
+	}
+	want := time.Date(2003, 7, 1, 8, 52, 37, 0, time.UTC)
+	if !d.Equal(want) {
+		t.Errorf("expected %v, got %v", want, d)
+	}
+}
+
+func TestAsDateInvalid(t *testing.T) {
+	if d := AsDate(" yesterday"); d != nil {
+		t.Errorf("expected nil, got %v", d)
+	}
+}
+
+func TestAsURLs(t *testing.T) {
+	urls := AsURLs(" <mailto:list-request@example.com?subject=unsubscribe>,\r\n <https://example.com/unsubscribe> (web)")
+	if len(urls) != 2 {
+		t.Fatalf("expected 2 urls, got %v", urls)
+	}
+	if urls[1] != "https://example.com/unsubscribe" {
+		t.Errorf("unexpected url: %s", urls[1])
+	}
+}
+
+func TestAsURLsInvalid(t *testing.T) {
+	if urls := AsURLs(" https://example.com/no-brackets"); urls != nil {
+		t.Errorf("expected nil, got %v", urls)
+	}
+}
@@ -0,0 +1,48 @@
+package headers
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzDecodeWords verifies that DecodeWords never panics and never turns
+// valid UTF-8 into invalid UTF-8.
+func FuzzDecodeWords(f *testing.F) {
+	f.Add("=?UTF-8?Q?Caf=C3=A9?=")
+	f.Add("=?ISO-8859-1?B?QW5kcuk=?= =?UTF-8?Q?x?=")
+	f.Add("=?")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		out := DecodeWords(s)
+		if utf8.ValidString(s) && !utf8.ValidString(out) {
+			t.Fatalf("DecodeWords(%q) produced invalid UTF-8: %q", s, out)
+		}
+	})
+}
+
+// FuzzParseMediaType verifies that ParseMediaType never panics.
+func FuzzParseMediaType(f *testing.F) {
+	f.Add(`text/plain; charset="utf-8"`)
+	f.Add(`attachment; filename*0*=utf-8''a%C3; filename*1*=%A9`)
+	f.Add(`;;;="`)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		_, _, _ = ParseMediaType(s)
+	})
+}
+
+// FuzzAsGroupedAddresses verifies that address parsing never panics.
+func FuzzAsGroupedAddresses(f *testing.F) {
+	f.Add(`"A" <a@example.com>, G: b@example.com;`)
+	f.Add(`<`)
+	f.Add(`(((`)
+	f.Add(`"\`)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		_ = AsGroupedAddresses(s)
+		_ = AsMessageIDs(s)
+		_ = AsURLs(s)
+		_ = AsDate(s)
+	})
+}
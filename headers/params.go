@@ -0,0 +1,249 @@
+package headers
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMediaType indicates a Content-Type or Content-Disposition value
+// whose type could not be parsed. ParseMediaType still returns any parameters
+// it managed to read.
+var ErrInvalidMediaType = errors.New("invalid media type")
+
+// paramSegment is one piece of an RFC 2231 continued parameter.
+type paramSegment struct {
+	index    int
+	value    string
+	extended bool // value is charset'language'percent-encoded (first) or percent-encoded
+}
+
+// paramForms collects the forms a parameter was given in. Senders often
+// give a plain value alongside an RFC 2231 one for older readers; the
+// forms are never mixed, and a repeated parameter keeps its first value.
+type paramForms struct {
+	plain    *paramSegment  // name=value
+	extended *paramSegment  // name*=charset'language'value
+	segments []paramSegment // name*0, name*1*, ...
+}
+
+// add records seg under its form, ignoring a repeat of a form or index
+// already seen.
+func (f *paramForms) add(seg paramSegment, continued bool) {
+	switch {
+	case continued:
+		for _, s := range f.segments {
+			if s.index == seg.index {
+				return
+			}
+		}
+		f.segments = append(f.segments, seg)
+	case seg.extended:
+		if f.extended == nil {
+			f.extended = &seg
+		}
+	default:
+		if f.plain == nil {
+			f.plain = &seg
+		}
+	}
+}
+
+// value returns the parameter's value, preferring the extended form, then
+// continuations, then the plain value.
+func (f *paramForms) value() string {
+	switch {
+	case f.extended != nil:
+		return joinSegments([]paramSegment{*f.extended})
+	case len(f.segments) > 0:
+		return joinSegments(f.segments)
+	default:
+		return joinSegments([]paramSegment{*f.plain})
+	}
+}
+
+// ParseMediaType parses a raw Content-Type or Content-Disposition value.
+//
+// The media type (or disposition type) is returned lowercased, and parameter
+// names are lowercased. RFC 2231 continuations (name*0, name*1, ...) are
+// joined and extended values (name*=charset'lang'value) are decoded from any
+// charset known to htmlindex. RFC 2047 encoded words inside quoted values,
+// which many mail clients produce for filenames, are decoded too. Where a
+// parameter is given both plainly and in RFC 2231 form, the RFC 2231 value
+// is used; a parameter repeated in the same form keeps its first value.
+func ParseMediaType(value string) (mediaType string, params map[string]string, err error) {
+	value = unfold(value)
+	head, rest, _ := strings.Cut(value, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(stripComments(head)))
+	if mediaType == "" || strings.ContainsAny(mediaType, " \t\"") {
+		err = ErrInvalidMediaType
+	}
+
+	forms := make(map[string]*paramForms)
+	for _, field := range splitParams(rest) {
+		name, val, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		val = strings.TrimSpace(val)
+		if name == "" {
+			continue
+		}
+
+		seg := paramSegment{value: unquote(val)}
+		if strings.HasSuffix(name, "*") {
+			seg.extended = true
+			name = strings.TrimSuffix(name, "*")
+		}
+		base, idx, continued := strings.Cut(name, "*")
+		if continued {
+			n, convErr := strconv.Atoi(idx)
+			if convErr != nil || n < 0 {
+				continue
+			}
+			name, seg.index = base, n
+		}
+		if forms[name] == nil {
+			forms[name] = &paramForms{}
+		}
+		forms[name].add(seg, continued)
+	}
+
+	params = make(map[string]string, len(forms))
+	for name, f := range forms {
+		params[name] = f.value()
+	}
+	return mediaType, params, err
+}
+
+// joinSegments assembles an RFC 2231 parameter from its segments.
+func joinSegments(segs []paramSegment) string {
+	sort.SliceStable(segs, func(i, j int) bool { return segs[i].index < segs[j].index })
+
+	charset := ""
+	var raw []byte // percent-decoded octets awaiting charset conversion
+	var out strings.Builder
+	flush := func() {
+		if len(raw) == 0 {
+			return
+		}
+		if decoded, ok := convertCharset(charsetOrDefault(charset), raw); ok {
+			out.WriteString(decoded)
+		} else {
+			out.Write(raw)
+		}
+		raw = raw[:0]
+	}
+
+	for _, seg := range segs {
+		if !seg.extended {
+			flush()
+			out.WriteString(DecodeWords(seg.value))
+			continue
+		}
+		v := seg.value
+		if seg.index == 0 {
+			// charset'language'value
+			if parts := strings.SplitN(v, "'", 3); len(parts) == 3 {
+				charset, v = parts[0], parts[2]
+			}
+		}
+		raw = append(raw, percentDecode(v)...)
+	}
+	flush()
+	return out.String()
+}
+
+func charsetOrDefault(charset string) string {
+	if charset == "" {
+		return "us-ascii"
+	}
+	return charset
+}
+
+// percentDecode decodes %XX escapes, leaving invalid escapes as-is.
+func percentDecode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if b, ok := unhex(s[i+1], s[i+2]); ok {
+				out = append(out, b)
+				i += 2
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return out
+}
+
+// splitParams splits a parameter list on semicolons outside quoted strings.
+func splitParams(s string) []string {
+	var fields []string
+	var cur strings.Builder
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && inQuote && i+1 < len(s):
+			cur.WriteByte(c)
+			cur.WriteByte(s[i+1])
+			i++
+			continue
+		case c == '"':
+			inQuote = !inQuote
+		case c == ';' && !inQuote:
+			fields = append(fields, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteByte(c)
+	}
+	return append(fields, cur.String())
+}
+
+// unquote removes surrounding double quotes and decodes quoted-pairs.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' {
+		return strings.TrimSpace(stripComments(s))
+	}
+	s = strings.TrimSuffix(s[1:], "\"")
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+// stripComments removes parenthesised comments from an unquoted token.
+func stripComments(s string) string {
+	if !strings.Contains(s, "(") {
+		return s
+	}
+	var out strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && depth > 0:
+			i++
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// unfold removes folding line breaks from a raw header value.
+func unfold(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ReplaceAll(value, "\n", "")
+}
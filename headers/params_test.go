@@ -0,0 +1,123 @@
+package headers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMediaTypeSimple(t *testing.T) {
+	mt, params, err := ParseMediaType(` Text/Plain; charset="UTF-8"; format=flowed`)
+	if err != nil {
+		t.Fatalf("ParseMediaType failed: %v", err)
+	}
+	if mt != "text/plain" {
+		t.Errorf("expected text/plain, got '%s'", mt)
+	}
+	if params["charset"] != "UTF-8" || params["format"] != "flowed" {
+		t.Errorf("unexpected params: %v", params)
+	}
+}
+
+func TestParseMediaTypeFolded(t *testing.T) {
+	mt, params, err := ParseMediaType(" multipart/mixed;\r\n\tboundary=\"abc;def\"")
+	if err != nil {
+		t.Fatalf("ParseMediaType failed: %v", err)
+	}
+	if mt != "multipart/mixed" || params["boundary"] != "abc;def" {
+		t.Errorf("unexpected result: %s %v", mt, params)
+	}
+}
+
+func TestParseMediaTypeRFC2231Continuations(t *testing.T) {
+	_, params, err := ParseMediaType(`attachment; filename*0="a very long "; filename*1="file name.txt"`)
+	if err != nil {
+		t.Fatalf("ParseMediaType failed: %v", err)
+	}
+	if params["filename"] != "a very long file name.txt" {
+		t.Errorf("expected joined filename, got '%s'", params["filename"])
+	}
+}
+
+func TestParseMediaTypeRFC2231Charset(t *testing.T) {
+	_, params, err := ParseMediaType(`attachment; filename*=iso-8859-1'fr'r%E9sum%E9.pdf`)
+	if err != nil {
+		t.Fatalf("ParseMediaType failed: %v", err)
+	}
+	if params["filename"] != "résumé.pdf" {
+		t.Errorf("expected 'résumé.pdf', got '%s'", params["filename"])
+	}
+}
+
+func TestParseMediaTypeRFC2231ExtendedContinuations(t *testing.T) {
+	// A multi-byte character split across extended segments
+	_, params, err := ParseMediaType(`attachment; filename*0*=utf-8''caf%C3; filename*1*=%A9.txt`)
+	if err != nil {
+		t.Fatalf("ParseMediaType failed: %v", err)
+	}
+	if params["filename"] != "café.txt" {
+		t.Errorf("expected 'café.txt', got '%s'", params["filename"])
+	}
+}
+
+func TestParseMediaTypePrefersExtendedForm(t *testing.T) {
+	tests := []string{
+		`attachment; filename="foo.txt"; filename*=UTF-8''f%C3%B6o.txt`,
+		`attachment; filename*=UTF-8''f%C3%B6o.txt; filename="foo.txt"`,
+		`attachment; filename="foo.txt"; filename*0*=UTF-8''f%C3%B6; filename*1=o.txt`,
+	}
+	for _, v := range tests {
+		_, params, err := ParseMediaType(v)
+		if err != nil {
+			t.Fatalf("ParseMediaType(%q) failed: %v", v, err)
+		}
+		if params["filename"] != "föo.txt" {
+			t.Errorf("ParseMediaType(%q): expected 'föo.txt', got '%s'", v, params["filename"])
+		}
+	}
+}
+
+func TestParseMediaTypeDuplicateParameters(t *testing.T) {
+	tests := map[string]string{
+		`text/plain; charset=utf-8; charset=iso-8859-1`:                   "utf-8",
+		`attachment; filename*=UTF-8''a.txt; filename*=UTF-8''b.txt`:      "a.txt",
+		`attachment; filename*0="a"; filename*1="b"; filename*1="c"`:      "ab",
+		`attachment; filename*1="b"; filename*0*=UTF-8''%C3%A9; name="x"`: "éb",
+	}
+	for v, want := range tests {
+		_, params, err := ParseMediaType(v)
+		if err != nil {
+			t.Fatalf("ParseMediaType(%q) failed: %v", v, err)
+		}
+		got := params["charset"] + params["filename"]
+		if got != want {
+			t.Errorf("ParseMediaType(%q): expected '%s', got '%s'", v, want, got)
+		}
+	}
+}
+
+func TestParseMediaTypeEncodedWordInQuotedValue(t *testing.T) {
+	_, params, err := ParseMediaType(`application/pdf; name="=?UTF-8?B?w6l0w6kucGRm?="`)
+	if err != nil {
+		t.Fatalf("ParseMediaType failed: %v", err)
+	}
+	if params["name"] != "été.pdf" {
+		t.Errorf("expected 'été.pdf', got '%s'", params["name"])
+	}
+}
+
+func TestParseMediaTypeComments(t *testing.T) {
+	mt, params, err := ParseMediaType(`text/plain (plain text); charset=us-ascii (ascii)`)
+	if err != nil {
+		t.Fatalf("ParseMediaType failed: %v", err)
+	}
+	if mt != "text/plain" || params["charset"] != "us-ascii" {
+		t.Errorf("unexpected result: %s %v", mt, params)
+	}
+}
+
+func TestParseMediaTypeInvalid(t *testing.T) {
+	_, _, err := ParseMediaType(`  ; charset=utf-8`)
+	if !errors.Is(err, ErrInvalidMediaType) {
+		t.Errorf("expected ErrInvalidMediaType, got %v", err)
+	}
+}
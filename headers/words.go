@@ -0,0 +1,194 @@
+package headers
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// encodedWordPattern matches an RFC 2047 encoded word. The charset group may
+// carry an RFC 2231 language suffix ("utf-8*en").
+var encodedWordPattern = regexp.MustCompile(`=\?([^?\s]+)\?([bBqQ])\?([^?\s]*)\?=`)
+
+// encodedWord is a syntactically valid encoded word found in a value.
+type encodedWord struct {
+	start, end int
+	charset    string
+	data       []byte // decoded octets, before charset conversion
+}
+
+// DecodeWords decodes the RFC 2047 encoded words in s and returns the result.
+//
+// Encoded words are only decoded where RFC 2047 allows them: delimited by
+// whitespace, parentheses or the ends of the value. Whitespace between
+// adjacent encoded words is removed, and adjacent words in the same charset
+// are converted together so a character split across words is preserved.
+// Words with an unknown charset or invalid encoding are left as-is. Control
+// characters produced by decoding are dropped.
+func DecodeWords(s string) string {
+	words := findEncodedWords(s)
+	if len(words) == 0 {
+		return s
+	}
+
+	var out strings.Builder
+	pos := 0
+	for i := 0; i < len(words); {
+		w := words[i]
+		out.WriteString(s[pos:w.start])
+
+		// Merge the run of adjacent words sharing a charset
+		data := w.data
+		end := w.end
+		j := i + 1
+		for ; j < len(words); j++ {
+			next := words[j]
+			if strings.TrimSpace(s[end:next.start]) != "" || !strings.EqualFold(next.charset, w.charset) {
+				break
+			}
+			data = append(data, next.data...)
+			end = next.end
+		}
+
+		decoded, ok := convertCharset(w.charset, data)
+		if !ok {
+			out.WriteString(s[w.start:end])
+		} else {
+			out.WriteString(dropControls(decoded))
+		}
+		pos = end
+		i = j
+
+		// Whitespace between this run and a following word in another charset
+		if ok && i < len(words) && strings.TrimSpace(s[pos:words[i].start]) == "" {
+			pos = words[i].start
+		}
+	}
+	out.WriteString(s[pos:])
+	return out.String()
+}
+
+// findEncodedWords returns the correctly placed, decodable encoded words in s.
+func findEncodedWords(s string) []encodedWord {
+	var words []encodedWord
+	for _, m := range encodedWordPattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		if start > 0 && !isWordDelimiter(s[start-1]) {
+			continue
+		}
+		if end < len(s) && !isWordDelimiter(s[end]) {
+			continue
+		}
+
+		charset := s[m[2]:m[3]]
+		if i := strings.IndexByte(charset, '*'); i >= 0 {
+			charset = charset[:i]
+		}
+		data, ok := decodeWordText(s[m[4]:m[5]], s[m[6]:m[7]])
+		if !ok {
+			continue
+		}
+		words = append(words, encodedWord{start: start, end: end, charset: charset, data: data})
+	}
+	return words
+}
+
+// isWordDelimiter reports whether b may appear next to an encoded word.
+func isWordDelimiter(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '(' || b == ')' || b == '"'
+}
+
+// decodeWordText decodes the encoded-text of a word in the given encoding.
+func decodeWordText(encoding, text string) ([]byte, bool) {
+	switch encoding {
+	case "B", "b":
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			// Tolerate missing padding
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(text, "="))
+			if err != nil {
+				return nil, false
+			}
+		}
+		return data, true
+	default:
+		data := make([]byte, 0, len(text))
+		for i := 0; i < len(text); i++ {
+			switch c := text[i]; {
+			case c == '_':
+				data = append(data, ' ')
+			case c == '=':
+				if i+2 >= len(text) {
+					return nil, false
+				}
+				b, ok := unhex(text[i+1], text[i+2])
+				if !ok {
+					return nil, false
+				}
+				data = append(data, b)
+				i += 2
+			default:
+				data = append(data, c)
+			}
+		}
+		return data, true
+	}
+}
+
+// convertCharset converts data from charset to UTF-8.
+func convertCharset(charset string, data []byte) (string, bool) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return strings.ToValidUTF8(string(data), "�"), true
+	case "us-ascii", "ascii":
+		if utf8.Valid(data) {
+			return string(data), true
+		}
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return "", false
+	}
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", false
+	}
+	return string(decoded), true
+}
+
+// dropControls removes control characters from decoded text, turning tabs
+// into spaces.
+func dropControls(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// unhex decodes two hex digits, accepting either case.
+func unhex(hi, lo byte) (byte, bool) {
+	h, ok1 := fromHex(hi)
+	l, ok2 := fromHex(lo)
+	return h<<4 | l, ok1 && ok2
+}
+
+func fromHex(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
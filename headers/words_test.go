@@ -0,0 +1,101 @@
+package headers
+
+import (
+	"testing"
+)
+
+func TestDecodeWordsQEncoding(t *testing.T) {
+	got := DecodeWords("=?UTF-8?Q?Caf=C3=A9_au_lait?=")
+	if got != "Café au lait" {
+		t.Errorf("expected 'Café au lait', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsLowercaseHex(t *testing.T) {
+	got := DecodeWords("=?utf-8?q?caf=c3=a9?=")
+	if got != "café" {
+		t.Errorf("expected 'café', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsBEncoding(t *testing.T) {
+	got := DecodeWords("=?UTF-8?B?w6l0w6k=?=")
+	if got != "été" {
+		t.Errorf("expected 'été', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsMissingPadding(t *testing.T) {
+	got := DecodeWords("=?UTF-8?B?w6l0w6k?=")
+	if got != "été" {
+		t.Errorf("expected 'été', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsLegacyCharsets(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"=?ISO-8859-1?Q?Andr=E9?=", "André"},
+		{"=?windows-1251?B?z/Do4uXy?=", "Привет"},
+		{"=?Shift_JIS?B?k/qWe4zq?=", "日本語"},
+		{"=?ISO-2022-JP?B?GyRCRnxLXDhsGyhC?=", "日本語"},
+		{"=?koi8-r?Q?=F0=D2=C9=D7=C5=D4?=", "Привет"},
+	}
+	for _, tc := range tests {
+		if got := DecodeWords(tc.in); got != tc.want {
+			t.Errorf("DecodeWords(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeWordsAdjacentWhitespaceRemoved(t *testing.T) {
+	got := DecodeWords("=?UTF-8?Q?Hello?= =?UTF-8?Q?_World?=")
+	if got != "Hello World" {
+		t.Errorf("expected 'Hello World', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsSplitMultibyteCharacter(t *testing.T) {
+	// "é" is C3 A9, split across two words
+	got := DecodeWords("=?UTF-8?Q?caf=C3?= =?UTF-8?Q?=A9?=")
+	if got != "café" {
+		t.Errorf("expected 'café', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsKeepsSurroundingText(t *testing.T) {
+	got := DecodeWords("Re: =?UTF-8?Q?r=C3=A9sum=C3=A9?= attached")
+	if got != "Re: résumé attached" {
+		t.Errorf("expected 'Re: résumé attached', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsPlacementRules(t *testing.T) {
+	// Encoded words must be delimited by whitespace
+	in := "abc=?UTF-8?Q?x?=def"
+	if got := DecodeWords(in); got != in {
+		t.Errorf("expected misplaced word to be left alone, got '%s'", got)
+	}
+}
+
+func TestDecodeWordsUnknownCharset(t *testing.T) {
+	in := "=?x-unknown?Q?abc?="
+	if got := DecodeWords(in); got != in {
+		t.Errorf("expected unknown charset to be left alone, got '%s'", got)
+	}
+}
+
+func TestDecodeWordsLanguageSuffix(t *testing.T) {
+	got := DecodeWords("=?UTF-8*en?Q?Hello?=")
+	if got != "Hello" {
+		t.Errorf("expected 'Hello', got '%s'", got)
+	}
+}
+
+func TestDecodeWordsDropsControls(t *testing.T) {
+	got := DecodeWords("=?UTF-8?Q?a=00b=07c?=")
+	if got != "abc" {
+		t.Errorf("expected 'abc', got %q", got)
+	}
+}
@@ -0,0 +1,122 @@
+package bpe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// Tokenizer encodes text with byte pair encoding. It is safe for
+// concurrent use.
+type Tokenizer struct {
+	ranks map[string]int // Token bytes to rank, which is also the token ID
+}
+
+// Load reads a vocabulary in the tiktoken format: one token per line, as
+// its base64-encoded bytes and its rank separated by a space. Every single
+// byte must be a token, so that any text can be encoded.
+func Load(r io.Reader) (*Tokenizer, error) {
+	t := &Tokenizer{ranks: make(map[string]int)}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		token, rank, ok := bytes.Cut(text, []byte(" "))
+		if !ok {
+			return nil, ErrInvalidVocabulary{Line: line, Reason: "expected token and rank"}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil || len(decoded) == 0 {
+			return nil, ErrInvalidVocabulary{Line: line, Reason: "invalid base64 token"}
+		}
+		n, err := strconv.Atoi(string(rank))
+		if err != nil || n < 0 {
+			return nil, ErrInvalidVocabulary{Line: line, Reason: fmt.Sprintf("invalid rank %q", rank)}
+		}
+		t.ranks[string(decoded)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for b := 0; b < 256; b++ {
+		if _, ok := t.ranks[string([]byte{byte(b)})]; !ok {
+			return nil, ErrInvalidVocabulary{Reason: fmt.Sprintf("missing single-byte token 0x%02x", b)}
+		}
+	}
+	return t, nil
+}
+
+// LoadFile reads a vocabulary file, as Load does.
+func LoadFile(path string) (*Tokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Load(f)
+}
+
+// Encode returns the token IDs of text.
+func (t *Tokenizer) Encode(text string) []int {
+	var ids []int
+	for _, piece := range pieces(text) {
+		if rank, ok := t.ranks[piece]; ok {
+			ids = append(ids, rank)
+			continue
+		}
+		bounds := t.merge(piece)
+		for i := 0; i+1 < len(bounds); i++ {
+			ids = append(ids, t.ranks[piece[bounds[i]:bounds[i+1]]])
+		}
+	}
+	return ids
+}
+
+// Count returns the number of tokens in text, without building the IDs.
+func (t *Tokenizer) Count(text string) int {
+	n := 0
+	for _, piece := range pieces(text) {
+		if _, ok := t.ranks[piece]; ok {
+			n++
+			continue
+		}
+		n += len(t.merge(piece)) - 1
+	}
+	return n
+}
+
+// merge applies byte pair merges to piece, lowest rank first, and returns
+// the byte offsets bounding each resulting token.
+func (t *Tokenizer) merge(piece string) []int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := t.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return bounds
+}
+
+// Ensure Tokenizer implements Counter
+var _ textproc.Counter = (*Tokenizer)(nil)
@@ -0,0 +1,125 @@
+package bpe
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// vocabulary returns a tiktoken-format vocabulary of every single byte,
+// ranked by value, followed by merges ranked in order.
+func vocabulary(merges ...string) string {
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, m := range merges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(m)), 256+i)
+	}
+	return sb.String()
+}
+
+func TestLoad(t *testing.T) {
+	tok, err := Load(strings.NewReader(vocabulary("he", "ll")))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(tok.ranks) != 258 {
+		t.Errorf("expected 258 tokens, got %d", len(tok.ranks))
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"missing rank", vocabulary() + "aGk=\n", 257},
+		{"bad base64", vocabulary() + "!!! 256\n", 257},
+		{"bad rank", vocabulary() + "aGk= x\n", 257},
+		{"missing byte", strings.SplitN(vocabulary(), "\n", 2)[1], 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.input))
+			var invalid ErrInvalidVocabulary
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected ErrInvalidVocabulary, got %v", err)
+			}
+			if invalid.Line != tt.line {
+				t.Errorf("expected line %d, got %d (%v)", tt.line, invalid.Line, err)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab.tiktoken")
+	if err := os.WriteFile(path, []byte(vocabulary("ab")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if got := tok.Count("ab"); got != 1 {
+		t.Errorf("expected 1 token, got %d", got)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tok, err := Load(strings.NewReader(vocabulary("he", "ll", "hell", " w", "or", " wor", "ld")))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  []int
+	}{
+		// he, ll, then hell merge, leaving o
+		{"hello", []int{258, 'o'}},
+		// Whole pieces in the vocabulary are one token
+		{"hell", []int{258}},
+		{"hello world", []int{258, 'o', 261, 262}},
+		{"", nil},
+		// Bytes with no merges are one token each
+		{"é", []int{0xc3, 0xa9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := tok.Encode(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			if n := tok.Count(tt.input); n != len(tt.want) {
+				t.Errorf("Count(%q) = %d, want %d", tt.input, n, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMergeOrder(t *testing.T) {
+	// "bc" ranks below "ab", so "abc" merges b and c first
+	tok, err := Load(strings.NewReader(vocabulary("bc", "ab")))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	got := tok.Encode("abc")
+	want := []int{'a', 256}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode(\"abc\") = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,28 @@
+// Package bpe counts model tokens with an offline byte pair encoding
+// tokenizer, so chunk windows can be sized to an embedding or language
+// model's token budget.
+//
+// A Tokenizer is loaded from a vocabulary file in the tiktoken format, as
+// published for OpenAI's cl100k_base encoding: one line per token, holding
+// the token's base64-encoded bytes and its rank. Nothing is fetched over
+// the network.
+//
+// Text is first split into pieces the way cl100k_base does (contractions,
+// words with an optional leading space or symbol, up to three digits,
+// symbol runs and whitespace runs), and each piece is then merged from
+// single bytes, lowest-ranked pair first. With a cl100k_base vocabulary the
+// token counts match the model's. Vocabularies trained with a different
+// splitting pattern give close, but not exact, counts.
+//
+// Tokenizer implements textproc.Counter:
+//
+//	tok, err := bpe.LoadFile("cl100k_base.tiktoken")
+//	if err != nil {
+//	    return err
+//	}
+//	s := splitter.NewProcessor(src, 512, splitter.WithCounter(tok))
+//	c := combiner.NewProcessor(s, combiner.WithCharLimit(8000), combiner.WithCounter(tok))
+//
+// Errors:
+//   - ErrInvalidVocabulary: returned by Load when the vocabulary is malformed
+package bpe
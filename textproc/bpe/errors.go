@@ -0,0 +1,22 @@
+package bpe
+
+// Error types for bpe.
+
+import (
+	"fmt"
+)
+
+// ErrInvalidVocabulary indicates a vocabulary file that could not be loaded.
+// Line is the 1-based line number of the problem, or 0 if it concerns the
+// vocabulary as a whole.
+type ErrInvalidVocabulary struct {
+	Line   int
+	Reason string
+}
+
+func (e ErrInvalidVocabulary) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("invalid vocabulary: %s", e.Reason)
+	}
+	return fmt.Sprintf("invalid vocabulary at line %d: %s", e.Line, e.Reason)
+}
@@ -0,0 +1,151 @@
+package bpe
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// pieces splits text into the pieces that are encoded separately, as the
+// cl100k_base pattern does:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// The pattern needs a lookahead, which regexp does not support, so it is
+// matched by hand. The pieces concatenate to text.
+func pieces(text string) []string {
+	var out []string
+	for text != "" {
+		n := nextPiece(text)
+		out = append(out, text[:n])
+		text = text[n:]
+	}
+	return out
+}
+
+// nextPiece returns the length in bytes of the piece at the start of s,
+// which must not be empty. The alternatives are tried in pattern order.
+func nextPiece(s string) int {
+	if n := contraction(s); n > 0 {
+		return n
+	}
+
+	r0, n0 := utf8.DecodeRuneInString(s)
+
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	if unicode.IsLetter(r0) {
+		return n0 + letters(s[n0:])
+	}
+	if r0 != '\r' && r0 != '\n' && !unicode.IsNumber(r0) {
+		if n := letters(s[n0:]); n > 0 {
+			return n0 + n
+		}
+	}
+
+	// \p{N}{1,3}
+	if unicode.IsNumber(r0) {
+		n := n0
+		for i := 1; i < 3 && n < len(s); i++ {
+			r, size := utf8.DecodeRuneInString(s[n:])
+			if !unicode.IsNumber(r) {
+				break
+			}
+			n += size
+		}
+		return n
+	}
+
+	// ' ?[^\s\p{L}\p{N}]+[\r\n]*'
+	start := 0
+	if r0 == ' ' {
+		start = n0
+	}
+	if n := symbols(s[start:]); n > 0 {
+		n += start
+		for n < len(s) && (s[n] == '\r' || s[n] == '\n') {
+			n++
+		}
+		return n
+	}
+
+	// The rest start with whitespace: \s*[\r\n]+ takes the run up to its
+	// last line break, \s+(?!\S) leaves the run's last space to prefix the
+	// next word, and \s+ takes a lone space before a word.
+	run, lastBreak, lastSize := 0, -1, 0
+	for run < len(s) {
+		r, size := utf8.DecodeRuneInString(s[run:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		if r == '\r' || r == '\n' {
+			lastBreak = run + size
+		}
+		lastSize = size
+		run += size
+	}
+	switch {
+	case run == 0:
+		// Unreachable for valid patterns; take a rune to make progress
+		return n0
+	case lastBreak > 0:
+		return lastBreak
+	case run == len(s) || run == lastSize:
+		return run
+	default:
+		return run - lastSize
+	}
+}
+
+// contraction returns the length of an English contraction suffix at the
+// start of s, or 0.
+func contraction(s string) int {
+	if len(s) < 2 || s[0] != '\'' {
+		return 0
+	}
+	if len(s) >= 3 {
+		switch lower(s[1])<<8 | lower(s[2]) {
+		case 'r'<<8 | 'e', 'v'<<8 | 'e', 'l'<<8 | 'l':
+			return 3
+		}
+	}
+	switch lower(s[1]) {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	return 0
+}
+
+// lower folds an ASCII letter to lower case.
+func lower(b byte) uint16 {
+	if b >= 'A' && b <= 'Z' {
+		b += 'a' - 'A'
+	}
+	return uint16(b)
+}
+
+// letters returns the length of the run of letters at the start of s.
+func letters(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// symbols returns the length of the run at the start of s of runes that
+// are not whitespace, letters or numbers.
+func symbols(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsSpace(r) || unicode.IsLetter(r) || unicode.IsNumber(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
@@ -0,0 +1,55 @@
+package bpe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPieces(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"words", "Hello world", []string{"Hello", " world"}},
+		{"contractions", "I'm sure they'LL", []string{"I", "'m", " sure", " they", "'LL"}},
+		{"digits in threes", "12345", []string{"123", "45"}},
+		{"symbols", "a, b!", []string{"a", ",", " b", "!"}},
+		{"symbol with space", "x ...", []string{"x", " ..."}},
+		{"symbol prefixes word", "#tag", []string{"#tag"}},
+		{"line breaks", "one\n\ntwo", []string{"one", "\n\n", "two"}},
+		{"trailing spaces", "end   ", []string{"end", "   "}},
+		{"spaces before word", "a   b", []string{"a", "  ", " b"}},
+		{"space before digit", "a 1", []string{"a", " ", "1"}},
+		{"spaces before break", "a  \n b", []string{"a", "  \n", " b"}},
+		{"unicode letters", "naïve 世界", []string{"naïve", " 世界"}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pieces(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pieces(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzPieces(f *testing.F) {
+	f.Add("Hello world")
+	f.Add("it's 2024!\r\n  ok")
+	f.Add("\xff\xfe 世界")
+	f.Fuzz(func(t *testing.T, input string) {
+		got := pieces(input)
+		for _, piece := range got {
+			if piece == "" {
+				t.Fatalf("pieces(%q) returned an empty piece", input)
+			}
+		}
+		if joined := strings.Join(got, ""); joined != input {
+			t.Fatalf("pieces(%q) joined to %q", input, joined)
+		}
+	})
+}
@@ -0,0 +1,577 @@
+package chain
+
+import (
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jarrod-lowe/jmap-service-libs/headers"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chunker"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/combiner"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/dedupe"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/elider"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/enriched"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/flowed"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/footer"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/htmlstrip"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/redact"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/splitter"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/utf8clean"
+)
+
+// Names of the built-in stages.
+const (
+	StageHTMLStrip = "htmlstrip"
+	StageElider    = "elider"
+	StageChunker   = "chunker"
+	StageSplitter  = "splitter"
+	StageFlowed    = "flowed"
+	StageEnriched  = "enriched"
+	StageDedupe    = "dedupe"
+	StageFooter    = "footer"
+	StageRedact    = "redact"
+)
+
+// DataKind is the kind of data passed between stages.
+type DataKind int
+
+const (
+	// KindString is text from a textproc.StringProcessor.
+	KindString DataKind = iota
+	// KindChunk is chunks from a textproc.ChunkProcessor.
+	KindChunk
+)
+
+func (k DataKind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindChunk:
+		return "chunk"
+	default:
+		return "unknown"
+	}
+}
+
+// Stage is a named step between utf8clean and the combiner.
+// Create one with StringStage, ChunkingStage or ChunkStage.
+type Stage struct {
+	Name string
+	In   DataKind
+	Out  DataKind
+
+	str      func(textproc.StringProcessor) textproc.StringProcessor
+	chunking func(textproc.StringProcessor) textproc.ChunkProcessor
+	chunk    func(textproc.ChunkProcessor) textproc.ChunkProcessor
+}
+
+// StringStage creates a stage that transforms text, like htmlstrip or elider.
+func StringStage(name string, fn func(textproc.StringProcessor) textproc.StringProcessor) Stage {
+	return Stage{Name: name, In: KindString, Out: KindString, str: fn}
+}
+
+// ChunkingStage creates a stage that turns text into chunks, like chunker.
+func ChunkingStage(name string, fn func(textproc.StringProcessor) textproc.ChunkProcessor) Stage {
+	return Stage{Name: name, In: KindString, Out: KindChunk, chunking: fn}
+}
+
+// ChunkStage creates a stage that transforms chunks, like splitter.
+func ChunkStage(name string, fn func(textproc.ChunkProcessor) textproc.ChunkProcessor) Stage {
+	return Stage{Name: name, In: KindChunk, Out: KindChunk, chunk: fn}
+}
+
+// Builder configures and builds a Chain.
+//
+// The reader and utf8clean stages always come first and the combiner always
+// comes last. The stages between them default to htmlstrip → elider →
+// chunker → splitter and can be removed, reordered or added to. Stage types
+// are checked by Build.
+type Builder struct {
+	r            io.Reader
+	maxBytes     int
+	readerOpts   []reader.Option
+	utf8Opts     []utf8clean.Option
+	htmlOpts     []htmlstrip.Option
+	eliderOpts   []elider.Option
+	elider       *elider.Processor // Built elider stage, for its report
+	splitterOpts []splitter.Option
+	combinerOpts []combiner.Option
+	chunkerOpts  []chunker.Option
+	dedupeOpts   []dedupe.Option
+	footerOpts   []footer.Option
+	redactOpts   []redact.Option
+	redact       *redact.Processor // Built redact stage, for its report
+	limits       Limits
+	stages       []Stage
+	delSp        bool  // DelSp=yes for the flowed stage
+	err          error // First error from a stage edit, reported by Build
+}
+
+// NewBuilder creates a Builder reading from r with the default configuration.
+func NewBuilder(r io.Reader) *Builder {
+	b := &Builder{
+		r:        r,
+		maxBytes: DefaultMaxBytes,
+		combinerOpts: []combiner.Option{
+			combiner.WithCharLimit(DefaultCharLimit),
+			combiner.WithOverlap(DefaultOverlap),
+		},
+	}
+	b.stages = []Stage{
+		b.builtinStage(StageHTMLStrip),
+		b.builtinStage(StageElider),
+		b.builtinStage(StageChunker),
+		b.builtinStage(StageSplitter),
+	}
+	return b
+}
+
+// builtinStage returns the named built-in stage. Stage options are read
+// when the chain is built, so they may be set in any order.
+func (b *Builder) builtinStage(name string) Stage {
+	switch name {
+	case StageHTMLStrip:
+		return StringStage(name, func(src textproc.StringProcessor) textproc.StringProcessor {
+			opts := append([]htmlstrip.Option{htmlstrip.WithMaxDepth(b.limits.MaxHTMLDepth)}, b.htmlOpts...)
+			return htmlstrip.NewProcessor(src, opts...)
+		})
+	case StageElider:
+		return StringStage(name, func(src textproc.StringProcessor) textproc.StringProcessor {
+			b.elider = elider.NewProcessor(src, b.eliderOpts...)
+			return b.elider
+		})
+	case StageFlowed:
+		return StringStage(name, func(src textproc.StringProcessor) textproc.StringProcessor {
+			return flowed.NewProcessor(src, flowed.WithDelSp(b.delSp))
+		})
+	case StageEnriched:
+		return StringStage(name, func(src textproc.StringProcessor) textproc.StringProcessor {
+			return enriched.NewProcessor(src)
+		})
+	case StageChunker:
+		return ChunkingStage(name, func(src textproc.StringProcessor) textproc.ChunkProcessor {
+			return chunker.NewProcessor(src, b.chunkerOpts...)
+		})
+	case StageFooter:
+		return StringStage(name, func(src textproc.StringProcessor) textproc.StringProcessor {
+			return footer.NewProcessor(src, b.footerOpts...)
+		})
+	case StageRedact:
+		return StringStage(name, func(src textproc.StringProcessor) textproc.StringProcessor {
+			b.redact = redact.NewProcessor(src, b.redactOpts...)
+			return b.redact
+		})
+	case StageDedupe:
+		return ChunkStage(name, func(src textproc.ChunkProcessor) textproc.ChunkProcessor {
+			return dedupe.NewProcessor(src, b.dedupeOpts...)
+		})
+	case StageSplitter:
+		return ChunkStage(name, func(src textproc.ChunkProcessor) textproc.ChunkProcessor {
+			opts := append([]splitter.Option{splitter.WithByteLimit(b.maxBytes)}, b.splitterOpts...)
+			return splitter.NewProcessor(src, b.maxBytes, opts...)
+		})
+	}
+	return Stage{}
+}
+
+// WithMaxBytes sets the size in UTF-8 bytes above which the splitter
+// splits chunks. No chunk is larger, unless WithCounter changes the unit.
+func (b *Builder) WithMaxBytes(n int) *Builder {
+	b.maxBytes = n
+	return b
+}
+
+// WithCharLimit sets the maximum characters per ChunkSlice.
+func (b *Builder) WithCharLimit(n int) *Builder {
+	b.combinerOpts = append(b.combinerOpts, combiner.WithCharLimit(n))
+	return b
+}
+
+// WithByteLimit sets a strict limit of n UTF-8 bytes per ChunkSlice, in
+// place of WithCharLimit. Overlap is reduced and oversized chunks are split
+// so that no ChunkSlice's chunks total more than n bytes.
+func (b *Builder) WithByteLimit(n int) *Builder {
+	b.combinerOpts = append(b.combinerOpts, combiner.WithByteLimit(n))
+	return b
+}
+
+// WithOverlap sets the number of chunks overlapping between ChunkSlices.
+func (b *Builder) WithOverlap(n int) *Builder {
+	b.combinerOpts = append(b.combinerOpts, combiner.WithOverlap(n))
+	return b
+}
+
+// WithCounter sets how the splitter and combiner measure chunks, so the
+// sizes from WithMaxBytes and WithCharLimit are counted in its units, such
+// as model tokens from a bpe.Tokenizer.
+func (b *Builder) WithCounter(c textproc.Counter) *Builder {
+	b.splitterOpts = append(b.splitterOpts, splitter.WithCounter(c))
+	b.combinerOpts = append(b.combinerOpts, combiner.WithCounter(c))
+	return b
+}
+
+// WithLimits bounds the resources the chain spends on its input; see
+// Limits. Once a limit trips, the chain's Next methods return a
+// LimitExceededError.
+func (b *Builder) WithLimits(l Limits) *Builder {
+	b.limits = l
+	return b
+}
+
+// WithCharset sets the charset of the input. Empty means UTF-8.
+func (b *Builder) WithCharset(charset string) *Builder {
+	b.utf8Opts = append(b.utf8Opts, utf8clean.WithCharset(charset))
+	return b
+}
+
+// WithTransferEncoding sets the Content-Transfer-Encoding of the input.
+func (b *Builder) WithTransferEncoding(encoding string) *Builder {
+	b.utf8Opts = append(b.utf8Opts, utf8clean.WithTransferEncoding(encoding))
+	return b
+}
+
+// WithCharsetDetection sniffs the charset of the input, treating any
+// charset from WithCharset as a hint.
+func (b *Builder) WithCharsetDetection() *Builder {
+	b.utf8Opts = append(b.utf8Opts, utf8clean.WithCharsetDetection())
+	return b
+}
+
+// WithContentType chooses the front-end stage for the input's
+// Content-Type header value:
+//
+//   - text/html and application/xhtml+xml: htmlstrip
+//   - text/plain; format=flowed: flowed, honouring DelSp
+//   - text/enriched and text/richtext: enriched
+//   - anything else: none, the text is used as it is
+//
+// The front-end replaces the htmlstrip stage, or goes first if htmlstrip
+// has been removed. Without WithContentType the input is treated as HTML.
+func (b *Builder) WithContentType(contentType string) *Builder {
+	mediaType, params, err := headers.ParseMediaType(contentType)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+
+	var front string
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		front = StageHTMLStrip
+	case "text/enriched", "text/richtext":
+		front = StageEnriched
+	case "text/plain":
+		if strings.EqualFold(params["format"], "flowed") {
+			front = StageFlowed
+			b.delSp = strings.EqualFold(params["delsp"], "yes")
+		}
+	}
+
+	// Remove any existing front-end before adding the new one
+	i := 0
+	for _, name := range []string{StageHTMLStrip, StageFlowed, StageEnriched} {
+		if j := b.index(name); j >= 0 {
+			b.stages = slices.Delete(b.stages, j, j+1)
+			i = j
+		}
+	}
+	if front != "" {
+		b.insert(i, b.builtinStage(front))
+	}
+	return b
+}
+
+// WithReaderOptions adds options for the reader stage.
+func (b *Builder) WithReaderOptions(opts ...reader.Option) *Builder {
+	b.readerOpts = append(b.readerOpts, opts...)
+	return b
+}
+
+// WithUTF8CleanOptions adds options for the utf8clean stage.
+func (b *Builder) WithUTF8CleanOptions(opts ...utf8clean.Option) *Builder {
+	b.utf8Opts = append(b.utf8Opts, opts...)
+	return b
+}
+
+// WithHTMLStripOptions adds options for the htmlstrip stage.
+func (b *Builder) WithHTMLStripOptions(opts ...htmlstrip.Option) *Builder {
+	b.htmlOpts = append(b.htmlOpts, opts...)
+	return b
+}
+
+// WithEliderOptions adds options for the elider stage.
+func (b *Builder) WithEliderOptions(opts ...elider.Option) *Builder {
+	b.eliderOpts = append(b.eliderOpts, opts...)
+	return b
+}
+
+// WithChunkerOptions adds options for the chunker stage.
+func (b *Builder) WithChunkerOptions(opts ...chunker.Option) *Builder {
+	b.chunkerOpts = append(b.chunkerOpts, opts...)
+	return b
+}
+
+// WithFooter adds the footer stage before the elider, or before the stage
+// that makes chunks if there is no elider, to remove legal disclaimers and
+// mailing list footers. Options from repeated calls accumulate; see
+// footer.NewProcessor.
+func (b *Builder) WithFooter(opts ...footer.Option) *Builder {
+	b.footerOpts = append(b.footerOpts, opts...)
+	if b.index(StageFooter) >= 0 {
+		return b
+	}
+	i := b.index(StageElider)
+	if i < 0 {
+		i = slices.IndexFunc(b.stages, func(s Stage) bool { return s.Out == KindChunk })
+	}
+	if i < 0 {
+		i = len(b.stages)
+	}
+	b.insert(i, b.builtinStage(StageFooter))
+	return b
+}
+
+// WithRedaction adds the redact stage after the elider, or before the
+// stage that makes chunks if there is no elider, to replace personal data
+// such as card numbers and email addresses. Options from repeated calls
+// accumulate; see redact.NewProcessor.
+func (b *Builder) WithRedaction(opts ...redact.Option) *Builder {
+	b.redactOpts = append(b.redactOpts, opts...)
+	if b.index(StageRedact) >= 0 {
+		return b
+	}
+	if b.index(StageElider) >= 0 {
+		return b.After(StageElider, b.builtinStage(StageRedact))
+	}
+	i := slices.IndexFunc(b.stages, func(s Stage) bool { return s.Out == KindChunk })
+	if i < 0 {
+		i = len(b.stages)
+	}
+	b.insert(i, b.builtinStage(StageRedact))
+	return b
+}
+
+// WithDedupe adds the dedupe stage after the chunker, or last if there is
+// no chunker, to drop duplicate and near-duplicate paragraphs. Options
+// from repeated calls accumulate; see dedupe.NewProcessor.
+func (b *Builder) WithDedupe(opts ...dedupe.Option) *Builder {
+	b.dedupeOpts = append(b.dedupeOpts, opts...)
+	if b.index(StageDedupe) >= 0 {
+		return b
+	}
+	if b.index(StageChunker) >= 0 {
+		return b.After(StageChunker, b.builtinStage(StageDedupe))
+	}
+	return b.Append(b.builtinStage(StageDedupe))
+}
+
+// WithSplitterOptions adds options for the splitter stage.
+func (b *Builder) WithSplitterOptions(opts ...splitter.Option) *Builder {
+	b.splitterOpts = append(b.splitterOpts, opts...)
+	return b
+}
+
+// WithCombinerOptions adds options for the combiner stage.
+func (b *Builder) WithCombinerOptions(opts ...combiner.Option) *Builder {
+	b.combinerOpts = append(b.combinerOpts, opts...)
+	return b
+}
+
+// Stage returns the named stage, including built-in stages that have been
+// removed, for use with WithStages.
+func (b *Builder) Stage(name string) Stage {
+	if i := b.index(name); i >= 0 {
+		return b.stages[i]
+	}
+	return b.builtinStage(name)
+}
+
+// Stages returns the names of the configured stages in order.
+func (b *Builder) Stages() []string {
+	names := make([]string, len(b.stages))
+	for i, s := range b.stages {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// WithStages replaces the stages between utf8clean and the combiner.
+func (b *Builder) WithStages(stages ...Stage) *Builder {
+	b.stages = nil
+	for _, s := range stages {
+		b.insert(len(b.stages), s)
+	}
+	return b
+}
+
+// Without removes the named stages, for example StageHTMLStrip for
+// text/plain input.
+func (b *Builder) Without(names ...string) *Builder {
+	for _, name := range names {
+		i := b.index(name)
+		if i < 0 {
+			b.fail(ErrUnknownStage{Name: name})
+			continue
+		}
+		b.stages = slices.Delete(b.stages, i, i+1)
+	}
+	return b
+}
+
+// Before inserts s immediately before the named stage.
+func (b *Builder) Before(name string, s Stage) *Builder {
+	i := b.index(name)
+	if i < 0 {
+		b.fail(ErrUnknownStage{Name: name})
+		return b
+	}
+	b.insert(i, s)
+	return b
+}
+
+// After inserts s immediately after the named stage.
+func (b *Builder) After(name string, s Stage) *Builder {
+	i := b.index(name)
+	if i < 0 {
+		b.fail(ErrUnknownStage{Name: name})
+		return b
+	}
+	b.insert(i+1, s)
+	return b
+}
+
+// Append adds s after the last stage, before the combiner.
+func (b *Builder) Append(s Stage) *Builder {
+	b.insert(len(b.stages), s)
+	return b
+}
+
+func (b *Builder) index(name string) int {
+	return slices.IndexFunc(b.stages, func(s Stage) bool { return s.Name == name })
+}
+
+func (b *Builder) insert(i int, s Stage) {
+	if s.str == nil && s.chunking == nil && s.chunk == nil {
+		b.fail(ErrUnknownStage{Name: s.Name})
+		return
+	}
+	if b.index(s.Name) >= 0 {
+		b.fail(ErrDuplicateStage{Name: s.Name})
+		return
+	}
+	b.stages = slices.Insert(b.stages, i, s)
+}
+
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build validates the stages and assembles the Chain.
+// Returns ErrIncompatibleStage if a stage's input does not match the
+// previous stage's output, or if the last stage does not produce chunks.
+func (b *Builder) Build() (*Chain, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	// Check types before constructing anything
+	kind := KindString
+	for _, s := range b.stages {
+		if s.In != kind {
+			return nil, ErrIncompatibleStage{Stage: s.Name, Want: s.In, Got: kind}
+		}
+		kind = s.Out
+	}
+	if kind != KindChunk {
+		return nil, ErrIncompatibleStage{Stage: "combiner", Want: KindChunk, Got: kind}
+	}
+
+	// Every stage reads through a checkpoint, so NextContext and the limits
+	// stop the chain between blocks
+	g := &guard{limits: b.limits}
+	t, err := b.buildText(g)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks textproc.ChunkProcessor
+	for _, s := range b.stages[t.n:] {
+		switch {
+		case s.chunking != nil:
+			chunks = s.chunking(&checkString{src: t.str, guard: g})
+		case s.chunk != nil:
+			chunks = s.chunk(&checkChunk{src: chunks, guard: g})
+		}
+	}
+
+	return &Chain{
+		combiner: combiner.NewProcessor(&checkChunk{src: chunks, guard: g, count: true}, b.combinerOpts...),
+		guard:    g,
+		utf8:     t.utf8,
+		elider:   b.elider,
+		redact:   b.redact,
+		mappers:  t.mappers,
+		mapped:   t.mapped,
+	}, nil
+}
+
+// BuildText assembles only the reader, utf8clean and the string stages
+// before the chunker, for callers that want the cleaned text rather than
+// chunks, such as Email previews. The front-end chosen by WithContentType,
+// the limits and NextContext cancellation apply as they do to a Chain.
+func (b *Builder) BuildText() (*Text, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	g := &guard{limits: b.limits}
+	t, err := b.buildText(g)
+	if err != nil {
+		return nil, err
+	}
+	return &Text{src: &checkString{src: t.str, guard: g}, guard: g}, nil
+}
+
+// textStages are the assembled stages up to the last string stage.
+type textStages struct {
+	str     textproc.StringProcessor
+	n       int // Number of string stages
+	utf8    *utf8clean.Processor
+	mappers []textproc.OffsetMapper // String stages, in order
+	mapped  bool                    // Every string stage maps offsets
+}
+
+// buildText assembles the reader, utf8clean and the string stages at the
+// start of the chain, each reading through a checkpoint of g.
+func (b *Builder) buildText(g *guard) (textStages, error) {
+	b.elider = nil
+	b.redact = nil
+	readerProc := reader.New(b.r, b.readerOpts...)
+	utf8Proc, err := utf8clean.NewProcessor(&checkBytes{src: readerProc, guard: g}, b.utf8Opts...)
+	if err != nil {
+		return textStages{}, err
+	}
+
+	// Adapter converts BytesProcessor output to strings
+	t := textStages{
+		str:    textproc.NewBytesToStringAdapter(utf8Proc),
+		utf8:   utf8Proc,
+		mapped: true,
+	}
+	for _, s := range b.stages {
+		if s.str == nil {
+			break
+		}
+		t.str = s.str(&checkString{src: t.str, guard: g})
+		if m, ok := t.str.(textproc.OffsetMapper); ok {
+			t.mappers = append(t.mappers, m)
+		} else {
+			t.mapped = false
+		}
+		t.n++
+	}
+	return t, nil
+}
@@ -0,0 +1,505 @@
+package chain
+
+import (
+	"errors"
+	"io"
+	"net/textproto"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/combiner"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/dedupe"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/elider"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/footer"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/redact"
+)
+
+// upperProcessor is a custom StringProcessor that upper-cases its input.
+type upperProcessor struct {
+	src textproc.StringProcessor
+}
+
+func (p *upperProcessor) Next() (string, error) {
+	s, err := p.src.Next()
+	return strings.ToUpper(s), err
+}
+
+func upperStage() Stage {
+	return StringStage("upper", func(src textproc.StringProcessor) textproc.StringProcessor {
+		return &upperProcessor{src: src}
+	})
+}
+
+// collect drains c and returns the text of every chunk.
+func collect(t *testing.T, c *Chain) string {
+	t.Helper()
+	var parts []string
+	for {
+		slice, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, chunk := range slice {
+			parts = append(parts, string(chunk))
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestBuilderDefaultStages(t *testing.T) {
+	b := NewBuilder(strings.NewReader(""))
+	want := []string{StageHTMLStrip, StageElider, StageChunker, StageSplitter}
+	if got := b.Stages(); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBuilderMatchesNewReader(t *testing.T) {
+	input := "<p>Hello <b>world</b></p><p>Second paragraph</p>"
+	legacy, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := NewBuilder(strings.NewReader(input)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := collect(t, legacy), collect(t, built); a != b {
+		t.Errorf("builder output %q differs from NewReader output %q", b, a)
+	}
+}
+
+func TestBuilderWithoutHTMLStrip(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("if a < b && c <= d {}")).
+		Without(StageHTMLStrip).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); got != "if a < b && c <= d {}" {
+		t.Errorf("expected text untouched, got %q", got)
+	}
+}
+
+func TestBuilderCustomStage(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("<p>hello</p>")).
+		After(StageHTMLStrip, upperStage()).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); got != "HELLO" {
+		t.Errorf("expected 'HELLO', got %q", got)
+	}
+}
+
+func TestBuilderReorder(t *testing.T) {
+	b := NewBuilder(strings.NewReader("x"))
+	b.WithStages(b.Stage(StageElider), b.Stage(StageHTMLStrip), b.Stage(StageChunker))
+	want := []string{StageElider, StageHTMLStrip, StageChunker}
+	if got := b.Stages(); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if _, err := b.Build(); err != nil {
+		t.Errorf("expected reordered chain to build, got %v", err)
+	}
+}
+
+func TestBuilderIncompatibleStage(t *testing.T) {
+	// A string stage after the chunker receives chunks
+	_, err := NewBuilder(strings.NewReader("x")).
+		After(StageChunker, upperStage()).
+		Build()
+	var incompatible ErrIncompatibleStage
+	if !errors.As(err, &incompatible) {
+		t.Fatalf("expected ErrIncompatibleStage, got %v", err)
+	}
+	if incompatible.Stage != "upper" || incompatible.Want != KindString || incompatible.Got != KindChunk {
+		t.Errorf("unexpected error details: %+v", incompatible)
+	}
+}
+
+func TestBuilderMissingChunker(t *testing.T) {
+	_, err := NewBuilder(strings.NewReader("x")).Without(StageChunker).Build()
+	var incompatible ErrIncompatibleStage
+	if !errors.As(err, &incompatible) || incompatible.Stage != StageSplitter {
+		t.Fatalf("expected splitter to be incompatible, got %v", err)
+	}
+
+	_, err = NewBuilder(strings.NewReader("x")).Without(StageChunker, StageSplitter).Build()
+	if !errors.As(err, &incompatible) || incompatible.Stage != "combiner" {
+		t.Fatalf("expected combiner to be incompatible, got %v", err)
+	}
+}
+
+func TestBuilderUnknownStage(t *testing.T) {
+	_, err := NewBuilder(strings.NewReader("x")).Without("nope").Build()
+	if !errors.As(err, new(ErrUnknownStage)) {
+		t.Errorf("expected ErrUnknownStage, got %v", err)
+	}
+}
+
+func TestBuilderDuplicateStage(t *testing.T) {
+	b := NewBuilder(strings.NewReader("x"))
+	_, err := b.Append(b.Stage(StageSplitter)).Build()
+	if !errors.As(err, new(ErrDuplicateStage)) {
+		t.Errorf("expected ErrDuplicateStage, got %v", err)
+	}
+}
+
+func TestBuilderStageOptions(t *testing.T) {
+	// One chunk per slice with no overlap
+	c, err := NewBuilder(strings.NewReader("one\n\ntwo\n\nthree")).
+		WithReaderOptions(reader.WithBlockSize(2)).
+		WithCombinerOptions(combiner.WithCharLimit(5), combiner.WithOverlap(0)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slices int
+	for {
+		_, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices++
+	}
+	if slices != 3 {
+		t.Errorf("expected 3 slices, got %d", slices)
+	}
+}
+
+func TestBuilderInvalidCharset(t *testing.T) {
+	_, err := NewBuilder(strings.NewReader("x")).WithCharset("x-unknown").Build()
+	if err == nil {
+		t.Error("expected error for unknown charset")
+	}
+}
+
+func TestBuilderCharsetDetection(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("caf\xe9 cr\xe8me")).
+		WithCharsetDetection().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); got != "café crème" {
+		t.Errorf("expected 'café crème', got %q", got)
+	}
+	if d, ok := c.DetectedCharset(); !ok || d.Charset != "iso-8859-1" {
+		t.Errorf("expected iso-8859-1 detected, got %+v", d)
+	}
+}
+
+func TestBuilderContentTypeStages(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        []string
+	}{
+		{"text/html; charset=utf-8", []string{StageHTMLStrip, StageElider, StageChunker, StageSplitter}},
+		{"text/plain", []string{StageElider, StageChunker, StageSplitter}},
+		{"text/plain; format=flowed; delsp=yes", []string{StageFlowed, StageElider, StageChunker, StageSplitter}},
+		{"text/enriched", []string{StageEnriched, StageElider, StageChunker, StageSplitter}},
+	}
+	for _, tc := range tests {
+		b := NewBuilder(strings.NewReader("")).WithContentType(tc.contentType)
+		if got := b.Stages(); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.contentType, tc.want, got)
+		}
+	}
+}
+
+func TestBuilderContentTypeReplacesFrontEnd(t *testing.T) {
+	b := NewBuilder(strings.NewReader("")).
+		WithContentType("text/enriched").
+		WithContentType("text/html")
+	want := []string{StageHTMLStrip, StageElider, StageChunker, StageSplitter}
+	if got := b.Stages(); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBuilderPlainTextKeepsMarkup(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("Use x<b && y<p in code")).
+		WithContentType("text/plain; charset=us-ascii").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); got != "Use x<b && y<p in code" {
+		t.Errorf("expected markup kept, got %q", got)
+	}
+}
+
+func TestBuilderFlowed(t *testing.T) {
+	input := "This paragraph was \r\nwrapped by the \r\nsender.\r\n"
+	c, err := NewBuilder(strings.NewReader(input)).
+		WithContentType(`text/plain; format="flowed"`).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); got != "This paragraph was wrapped by the sender." {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestBuilderEnriched(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("<bold>Hello</bold>\r\nworld")).
+		WithContentType("text/enriched").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); got != "Hello world" {
+		t.Errorf("expected 'Hello world', got %q", got)
+	}
+}
+
+func TestBuilderInvalidContentType(t *testing.T) {
+	_, err := NewBuilder(strings.NewReader("")).WithContentType(";;").Build()
+	if err == nil {
+		t.Error("expected error for invalid content type")
+	}
+}
+
+func TestChainEliderReport(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("Thanks\n\nSent from my iPhone\n")).
+		WithEliderOptions(elider.WithReplacement(elider.RuleUUID, "[UUID]")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	collect(t, c)
+	if r := c.EliderReport()[elider.RuleSignature]; r.Count != 1 {
+		t.Errorf("expected one signature in report, got %+v", c.EliderReport())
+	}
+
+	c, err = NewBuilder(strings.NewReader("x")).Without(StageElider).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.EliderReport() != nil {
+		t.Error("expected no report without an elider stage")
+	}
+}
+
+// spanText returns the input covered by span.
+func spanText(input string, span textproc.Span) string {
+	return input[span.Start:span.End]
+}
+
+func TestChainNextWithSpansHTML(t *testing.T) {
+	input := "<html><body><p>Hello <b>world</b></p>\n<p>Second &amp; last</p></body></html>"
+	c, err := NewBuilder(strings.NewReader(input)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slice, spans, err := c.NextWithSpans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slice) != 2 || len(spans) != 2 {
+		t.Fatalf("expected 2 chunks with spans, got %q %+v", slice, spans)
+	}
+	if got := spanText(input, spans[0].Text); got != "Hello <b>world" {
+		t.Errorf("expected first chunk at 'Hello <b>world', got %q", got)
+	}
+	if got := spanText(input, spans[1].Text); !strings.HasPrefix(got, "Second &amp;") {
+		t.Errorf("expected second chunk at 'Second &amp; ...', got %q", got)
+	}
+	if spans[0].Raw != spans[0].Text {
+		t.Errorf("expected raw span to equal text span for UTF-8 input, got %+v", spans[0])
+	}
+}
+
+func TestChainNextWithSpansElided(t *testing.T) {
+	input := "See https://example.com/page for details\n\nOn Mon, Bob wrote:\nold"
+	c, err := NewBuilder(strings.NewReader(input)).Without(StageHTMLStrip).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slice, spans, err := c.NextWithSpans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slice) != 1 || string(slice[0]) != "See example.com for details" {
+		t.Fatalf("unexpected chunks %q", slice)
+	}
+	if got := spanText(input, spans[0].Text); got != "See https://example.com/page for details" {
+		t.Errorf("expected span over the original sentence, got %q", got)
+	}
+}
+
+func TestChainNextWithSpansRawOffsets(t *testing.T) {
+	// The invalid byte becomes a 3-byte U+FFFD in the decoded text
+	input := "\xffIntro\n\nBody text"
+	c, err := NewBuilder(strings.NewReader(input)).Without(StageHTMLStrip).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, spans, err := c.NextWithSpans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := spans[len(spans)-1]
+	if last.Text != (textproc.Span{Start: 10, End: 19}) {
+		t.Errorf("unexpected text span %+v", last.Text)
+	}
+	if got := spanText(input, last.Raw); got != "Body text" {
+		t.Errorf("expected raw span at 'Body text', got %q", got)
+	}
+}
+
+func TestChainNextWithSpansNoRawForTransferEncoding(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("SGVsbG8=")).WithTransferEncoding("base64").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, spans, err := c.NextWithSpans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spans[0].Text != (textproc.Span{Start: 0, End: 5}) || !spans[0].Raw.IsZero() {
+		t.Errorf("expected text span only, got %+v", spans[0])
+	}
+}
+
+func TestChainNextWithSpansUnknownForCustomStage(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("hello")).After(StageElider, upperStage()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, spans, err := c.NextWithSpans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !spans[0].Text.IsZero() {
+		t.Errorf("expected unknown span after a stage without offsets, got %+v", spans[0])
+	}
+}
+
+func TestBuilderWithCounter(t *testing.T) {
+	// Two words per slice with no overlap
+	c, err := NewBuilder(strings.NewReader("one two\n\nthree four\n\nfive")).
+		WithCharLimit(2).
+		WithOverlap(0).
+		WithCounter(textproc.WordCounter{}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []textproc.ChunkSlice
+	for {
+		slice, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, slice)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 slices, got %d: %q", len(got), got)
+	}
+	if len(got[1]) != 1 || strings.TrimSpace(string(got[1][0])) != "three four" {
+		t.Errorf("expected second slice to be 'three four', got %q", got[1])
+	}
+}
+
+func TestBuilderWithDedupe(t *testing.T) {
+	footer := "You received this message because you are subscribed to the project mailing list."
+	input := "First update on the build.\n\n" + footer + "\n\nSecond update on the build.\n\n" + footer
+	b := NewBuilder(strings.NewReader(input)).
+		WithContentType("text/plain").
+		WithDedupe(dedupe.WithMinWords(3))
+	if got, want := strings.Join(b.Stages(), ","), "elider,chunker,dedupe,splitter"; got != want {
+		t.Errorf("expected stages %s, got %s", want, got)
+	}
+	c, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); strings.Count(got, footer) != 1 {
+		t.Errorf("expected the footer once, got %q", got)
+	}
+}
+
+func TestBuilderWithFooter(t *testing.T) {
+	h := textproto.MIMEHeader{}
+	h.Set("List-Unsubscribe", "<https://lists.example.org/options/dev>")
+	input := "The build is green again.\n\n-- \nYou received this message because you are subscribed.\n\n" +
+		"Options: https://lists.example.org/options/dev\n"
+	b := NewBuilder(strings.NewReader(input)).
+		WithContentType("text/plain").
+		Without(StageElider).
+		WithFooter(footer.WithListHeaders(h))
+	if got, want := strings.Join(b.Stages(), ","), "footer,chunker,splitter"; got != want {
+		t.Errorf("expected stages %s, got %s", want, got)
+	}
+	c, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); got != "The build is green again." {
+		t.Errorf("expected the footers removed, got %q", got)
+	}
+
+	b = NewBuilder(strings.NewReader(input)).WithFooter()
+	if got, want := strings.Join(b.Stages(), ","), "htmlstrip,footer,elider,chunker,splitter"; got != want {
+		t.Errorf("expected stages %s, got %s", want, got)
+	}
+}
+
+func TestBuilderWithRedaction(t *testing.T) {
+	input := "Reach me at alice@example.com or +44 20 7946 0958.\n\nCard: 4111 1111 1111 1111\n"
+	b := NewBuilder(strings.NewReader(input)).
+		WithContentType("text/plain").
+		WithRedaction(redact.WithPseudonyms()).
+		WithRedaction(redact.WithoutKinds(redact.KindPhone))
+	if got, want := strings.Join(b.Stages(), ","), "elider,redact,chunker,splitter"; got != want {
+		t.Errorf("expected stages %s, got %s", want, got)
+	}
+	c, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slice, spans, err := c.NextWithSpans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := textproc.ChunkSlice{"Reach me at [EMAIL_1] or +44 20 7946 0958.", "Card: [CARD_1]"}
+	if !slices.Equal(slice, want) {
+		t.Fatalf("expected %q, got %q", want, slice)
+	}
+	if got := spanText(input, spans[1].Text); got != "Card: 4111 1111 1111 1111" {
+		t.Errorf("expected card chunk span, got %q", got)
+	}
+	report := c.RedactionReport()
+	if report[redact.KindEmail].Count != 1 || report[redact.KindCard].Count != 1 || len(report) != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	b = NewBuilder(strings.NewReader(input)).Without(StageElider).WithRedaction()
+	if got, want := strings.Join(b.Stages(), ","), "htmlstrip,redact,chunker,splitter"; got != want {
+		t.Errorf("expected stages %s, got %s", want, got)
+	}
+
+	c, err = NewBuilder(strings.NewReader(input)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RedactionReport() != nil {
+		t.Error("expected no report without a redact stage")
+	}
+}
@@ -1,15 +1,14 @@
 package chain
 
 import (
+	"context"
+	"errors"
 	"io"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
-	"github.com/jarrod-lowe/jmap-service-libs/textproc/chunker"
-	"github.com/jarrod-lowe/jmap-service-libs/textproc/combiner"
 	"github.com/jarrod-lowe/jmap-service-libs/textproc/elider"
 	"github.com/jarrod-lowe/jmap-service-libs/textproc/htmlstrip"
-	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
-	"github.com/jarrod-lowe/jmap-service-libs/textproc/splitter"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/redact"
 	"github.com/jarrod-lowe/jmap-service-libs/textproc/utf8clean"
 )
 
@@ -21,9 +20,29 @@ const (
 )
 
 // Chain composes text processors with lazy evaluation.
-// The pipeline is: reader → utf8clean → BytesToStringAdapter → htmlstrip → elider → chunker → splitter → combiner
+// The default pipeline is: reader → utf8clean → BytesToStringAdapter → htmlstrip → elider → chunker → splitter → combiner
+// Use NewBuilder to change it.
 type Chain struct {
-	combiner *combiner.Processor
+	combiner textproc.ChunkCombiner
+	guard    *guard // Context of the Next call in progress, and limits
+	utf8     *utf8clean.Processor
+	elider   *elider.Processor
+	redact   *redact.Processor
+	mappers  []textproc.OffsetMapper // String stages, in order
+	mapped   bool                    // Every string stage maps offsets
+}
+
+// SourceSpan locates a chunk in the chain's input. Either span is zero when
+// it cannot be worked out.
+type SourceSpan struct {
+	// Text is the chunk's byte range in the decoded UTF-8 text, before any
+	// HTML stripping or elision. It is unknown if a string stage does not
+	// implement textproc.OffsetMapper.
+	Text textproc.Span
+	// Raw is the chunk's byte range in the bytes read from the input. It is
+	// only known when the input needed no transfer decoding or charset
+	// conversion.
+	Raw textproc.Span
 }
 
 // NewReader creates a new Chain with an io.Reader as input.
@@ -38,9 +57,13 @@ func NewReaderConfig(r io.Reader, maxBytes, overlap int) (*Chain, error) {
 }
 
 // NewReaderConfigWithByteLimit creates a new Chain with custom configuration including byte limit.
-// Deprecated: Use NewReaderConfigWithCharLimit instead.
+// No ChunkSlice's chunks total more than byteLimit UTF-8 bytes.
 func NewReaderConfigWithByteLimit(r io.Reader, maxBytes, overlap, byteLimit int) (*Chain, error) {
-	return NewReaderConfigWithEncoding(r, maxBytes, overlap, byteLimit, "", "")
+	return NewBuilder(r).
+		WithMaxBytes(maxBytes).
+		WithOverlap(overlap).
+		WithByteLimit(byteLimit).
+		Build()
 }
 
 // NewReaderConfigWithCharLimit creates a new Chain with custom configuration including char limit.
@@ -51,28 +74,118 @@ func NewReaderConfigWithCharLimit(r io.Reader, maxBytes, overlap, charLimit int)
 // NewReaderConfigWithEncoding creates a new Chain with custom configuration including charset and transfer encoding.
 // The charset and transferEncoding parameters are passed through to the utf8clean processor.
 func NewReaderConfigWithEncoding(r io.Reader, maxBytes, overlap, charLimit int, charset, transferEncoding string) (*Chain, error) {
-	// Build the pull-based pipeline
-	readerProc := reader.New(r)
-	utf8Proc, err := utf8clean.NewProcessor(readerProc,
-		utf8clean.WithCharset(charset),
-		utf8clean.WithTransferEncoding(transferEncoding),
-	)
-	if err != nil {
-		return nil, err
-	}
-	// Adapter converts BytesProcessor output to strings
-	adapterProc := textproc.NewBytesToStringAdapter(utf8Proc)
-	htmlProc := htmlstrip.NewProcessor(adapterProc)
-	eliderProc := elider.NewProcessor(htmlProc)
-	chunkerProc := chunker.NewProcessor(eliderProc)
-	splitterProc := splitter.NewProcessor(chunkerProc, maxBytes)
-	combinerProc := combiner.NewProcessor(splitterProc, combiner.WithCharLimit(charLimit), combiner.WithOverlap(overlap))
-
-	return &Chain{combiner: combinerProc}, nil
+	return NewBuilder(r).
+		WithMaxBytes(maxBytes).
+		WithOverlap(overlap).
+		WithCharLimit(charLimit).
+		WithCharset(charset).
+		WithTransferEncoding(transferEncoding).
+		Build()
 }
 
 // Next returns the next ChunkSlice from the chain.
 // It pulls data through the entire pipeline lazily.
 func (c *Chain) Next() (textproc.ChunkSlice, error) {
-	return c.combiner.Next()
+	return c.NextContext(context.Background())
+}
+
+// NextContext is Next, but stops between blocks once ctx is done,
+// returning ctx.Err(). Stages that implement the textproc context
+// interfaces are passed ctx. A chain that returned an error should not be
+// read further.
+func (c *Chain) NextContext(ctx context.Context) (textproc.ChunkSlice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer c.guard.enter(ctx)()
+	slice, err := c.combiner.Next()
+	return slice, limitError(err)
+}
+
+// NextWithSpans returns the next ChunkSlice, and for each chunk where it
+// came from in the input. Spans are approximate where a stage inserted or
+// rewrote text, such as list bullets or character references, and cover any
+// text elided from inside the chunk. Use them for highlighting and
+// citation; do not rely on slicing the input with them giving the chunk.
+func (c *Chain) NextWithSpans() (textproc.ChunkSlice, []SourceSpan, error) {
+	return c.NextWithSpansContext(context.Background())
+}
+
+// NextWithSpansContext is NextWithSpans, but stops between blocks once ctx
+// is done, as NextContext does.
+func (c *Chain) NextWithSpansContext(ctx context.Context) (textproc.ChunkSlice, []SourceSpan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	defer c.guard.enter(ctx)()
+	combiner, ok := c.combiner.(textproc.SpanChunkCombiner)
+	if !ok {
+		slice, err := c.combiner.Next()
+		return slice, make([]SourceSpan, len(slice)), limitError(err)
+	}
+	slice, spans, err := combiner.NextWithSpans()
+	if err != nil {
+		return nil, nil, limitError(err)
+	}
+	sources := make([]SourceSpan, len(spans))
+	for i, span := range spans {
+		sources[i] = c.sourceSpan(span)
+	}
+	return slice, sources, nil
+}
+
+// limitError reports a limit enforced by a stage as a LimitExceededError.
+func limitError(err error) error {
+	var depth htmlstrip.ErrMaxDepth
+	if errors.As(err, &depth) {
+		return LimitExceededError{Limit: LimitHTMLDepth, Max: int64(depth.Max)}
+	}
+	return err
+}
+
+// sourceSpan maps a span in the chunker's input back through the string
+// stages to the decoded text and input bytes.
+func (c *Chain) sourceSpan(span textproc.Span) SourceSpan {
+	if span.IsZero() || !c.mapped {
+		return SourceSpan{}
+	}
+	for i := len(c.mappers) - 1; i >= 0; i-- {
+		m := c.mappers[i].Offsets()
+		if m == nil {
+			return SourceSpan{}
+		}
+		span = m.MapSpan(span)
+	}
+	source := SourceSpan{Text: span}
+	if m := c.utf8.Offsets(); m != nil {
+		source.Raw = m.MapSpan(span)
+	}
+	return source
 }
+
+// DetectedCharset returns the charset chosen when the chain was built with
+// WithCharsetDetection. The second result is false until the first Next().
+func (c *Chain) DetectedCharset() (utf8clean.Detection, bool) {
+	return c.utf8.DetectedCharset()
+}
+
+// EliderReport returns what the elider stage has elided so far; see
+// elider.Processor.Report. It is nil if the chain has no elider stage.
+func (c *Chain) EliderReport() elider.Report {
+	if c.elider == nil {
+		return nil
+	}
+	return c.elider.Report()
+}
+
+// RedactionReport returns what the redact stage has redacted so far; see
+// redact.Processor.Report. It is nil if the chain has no redact stage.
+func (c *Chain) RedactionReport() redact.Report {
+	if c.redact == nil {
+		return nil
+	}
+	return c.redact.Report()
+}
+
+// Ensure Chain implements ContextChunkCombiner
+var _ textproc.ContextChunkCombiner = (*Chain)(nil)
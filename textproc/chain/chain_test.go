@@ -1,9 +1,14 @@
 package chain
 
 import (
+	"context"
+	"errors"
 	"io"
 	"strings"
 	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
 )
 
 func TestNewReader(t *testing.T) {
@@ -168,3 +173,164 @@ func TestNewReaderConfigWithEncodingInvalidTransferEncoding(t *testing.T) {
 		t.Error("expected error for invalid transfer encoding")
 	}
 }
+
+func TestNewReaderConfigWithByteLimit(t *testing.T) {
+	// CJK paragraphs are three bytes per rune, so a rune limit would let
+	// slices grow to three times the byte budget.
+	paragraph := strings.Repeat("这是一个测试句子", 5)
+	data := strings.Repeat(paragraph+"\n\n", 6)
+	c, err := NewReaderConfigWithByteLimit(strings.NewReader(data), 60, 1, 150)
+	if err != nil {
+		t.Fatalf("NewReaderConfigWithByteLimit failed: %v", err)
+	}
+
+	var total int
+	for {
+		slice, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var size int
+		for _, chunk := range slice {
+			if len(chunk) > 60 {
+				t.Errorf("chunk of %d bytes exceeds maxBytes 60", len(chunk))
+			}
+			size += len(chunk)
+		}
+		if size > 150 {
+			t.Errorf("slice of %d bytes exceeds byte limit 150", size)
+		}
+		total += size
+	}
+	if total < len(strings.TrimSpace(data))/2 {
+		t.Errorf("expected the text to be returned, got %d bytes", total)
+	}
+}
+
+// cancellingProcessor is a custom StringProcessor that cancels a context
+// after a number of reads, and counts its reads.
+type cancellingProcessor struct {
+	src    textproc.StringProcessor
+	cancel context.CancelFunc
+	after  int
+	reads  int
+}
+
+func (p *cancellingProcessor) Next() (string, error) {
+	p.reads++
+	if p.reads == p.after {
+		p.cancel()
+	}
+	return p.src.Next()
+}
+
+// valueProcessor is a context-aware custom StringProcessor that records a
+// value from the context it is given.
+type valueProcessor struct {
+	src   textproc.StringProcessor
+	value any
+}
+
+func (p *valueProcessor) Next() (string, error) {
+	return p.src.Next()
+}
+
+func (p *valueProcessor) NextContext(ctx context.Context) (string, error) {
+	p.value = ctx.Value(ctxKey{})
+	return p.src.Next()
+}
+
+type ctxKey struct{}
+
+func TestNextContextCancelled(t *testing.T) {
+	c, err := NewReader(strings.NewReader("Hello world"))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.NextContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, _, err := c.NextWithSpansContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled from NextWithSpansContext, got %v", err)
+	}
+}
+
+func TestNextContextStopsBetweenBlocks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stage := &cancellingProcessor{cancel: cancel, after: 3}
+	input := strings.Repeat("Some words in a paragraph.\n\n", 2000)
+	c, err := NewBuilder(strings.NewReader(input)).
+		WithReaderOptions(reader.WithBlockSize(64)).
+		Without(StageElider).
+		After(StageHTMLStrip, StringStage("cancel", func(src textproc.StringProcessor) textproc.StringProcessor {
+			stage.src = src
+			return stage
+		})).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		_, err = c.NextContext(ctx)
+		if err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if stage.reads > stage.after+1 {
+		t.Errorf("expected reads to stop after cancellation, got %d", stage.reads)
+	}
+}
+
+func TestNextWithSpansContext(t *testing.T) {
+	c, err := NewReader(strings.NewReader("Hello world"))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	slice, spans, err := c.NextWithSpansContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slice) != 1 || string(slice[0]) != "Hello world" {
+		t.Errorf("expected [Hello world], got %q", slice)
+	}
+	// The checkpoints between stages pass spans through
+	if len(spans) != 1 || spans[0].Text != (textproc.Span{Start: 0, End: 11}) {
+		t.Errorf("expected span {0 11}, got %+v", spans)
+	}
+}
+
+func TestNextContextPassesContext(t *testing.T) {
+	stage := &valueProcessor{}
+	c, err := NewBuilder(strings.NewReader("Hello world")).
+		After(StageElider, StringStage("value", func(src textproc.StringProcessor) textproc.StringProcessor {
+			stage.src = src
+			return stage
+		})).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	slice, err := c.NextContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slice) != 1 || string(slice[0]) != "Hello world" {
+		t.Errorf("expected [Hello world], got %q", slice)
+	}
+	if stage.value != "value" {
+		t.Errorf("expected the stage to get the context, got %v", stage.value)
+	}
+}
@@ -1,3 +1,70 @@
 // Package chain provides composition for text processing pipeline.
 // Uses builder pattern with fluent With*() methods and lazy Next() iteration.
+//
+// The default pipeline is:
+//
+//	reader → utf8clean → htmlstrip → elider → chunker → splitter → combiner
+//
+// NewReader and the NewReaderConfig* constructors build it with fixed
+// settings. NewBuilder configures every stage, and can remove, reorder or
+// add stages between utf8clean and the combiner:
+//
+//	c, err := chain.NewBuilder(r).
+//	    WithCharset("ISO-8859-1").
+//	    WithCharLimit(2000).
+//	    Without(chain.StageHTMLStrip).
+//	    After(chain.StageElider, chain.StringStage("lower", newLowercaser)).
+//	    Build()
+//
+// WithContentType picks the front-end stage from a Content-Type value:
+// htmlstrip for HTML, flowed for text/plain; format=flowed, enriched for
+// text/enriched, and none for other plain text. Without it the input is
+// treated as HTML.
+//
+// BuildText assembles only the stages before the chunker and returns a
+// Text yielding the cleaned string, for callers such as previews that
+// want text rather than chunks. Limits and cancellation apply to it too.
+//
+// WithFooter adds the footer stage before the elider, removing legal
+// disclaimers and mailing list footers; footer.WithListHeaders lets it use
+// the message's List-* fields.
+//
+// WithRedaction adds the redact stage after the elider, replacing card
+// numbers, IBANs, phone numbers, email addresses and government IDs with
+// tokens or per-document pseudonyms; RedactionReport says what was found.
+//
+// WithDedupe adds the dedupe stage after the chunker, dropping repeated
+// paragraphs such as list footers and disclaimers; give it a dedupe.Store
+// shared between chains to drop boilerplate across documents.
+//
+// Stages are typed by the data they consume and produce (see StringStage,
+// ChunkingStage and ChunkStage). Build returns ErrIncompatibleStage if the
+// sequence does not fit together, or if it does not end with chunks for
+// the combiner.
+//
+// The splitter keeps chunks within WithMaxBytes UTF-8 bytes. The combiner
+// counts WithCharLimit in runes, or keeps each ChunkSlice within a strict
+// UTF-8 budget with WithByteLimit. WithCounter counts both sizes in another
+// unit instead, such as model tokens from a bpe.Tokenizer.
+//
+// A Chain is a textproc.ChunkCombiner, so textproc.All ranges over its
+// slices.
+//
+// NextContext and NextWithSpansContext stop once their context is done,
+// returning ctx.Err(), so a caller can give up on a huge or pathological
+// body before a deadline. Cancellation is checked before every read between
+// stages; stages implementing the textproc context interfaces also get the
+// context.
+//
+// WithLimits bounds what one input may cost: bytes read, chunks produced,
+// HTML nesting depth and time spent in Next. Once a limit trips, Next
+// returns a LimitExceededError naming it. No limits apply by default;
+// DefaultLimits suits mail bodies.
+//
+// NextWithSpans returns each chunk's location in the input, for search
+// highlighting and citation: its byte range in the decoded text, and in the
+// raw input when no transfer or charset decoding was needed. Stages take
+// part by implementing textproc.OffsetMapper (string stages) or
+// textproc.SpanChunkProcessor (chunk stages); a custom stage that does not
+// leaves the spans unknown.
 package chain
@@ -0,0 +1,53 @@
+package chain
+
+// Error types for the chain builder.
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrUnknownStage indicates a stage name that is not in the chain, or a
+// Stage that was not created by one of the stage constructors.
+type ErrUnknownStage struct {
+	Name string
+}
+
+func (e ErrUnknownStage) Error() string {
+	return fmt.Sprintf("unknown stage: %q", e.Name)
+}
+
+// ErrDuplicateStage indicates a stage was added with a name already in use.
+type ErrDuplicateStage struct {
+	Name string
+}
+
+func (e ErrDuplicateStage) Error() string {
+	return fmt.Sprintf("duplicate stage: %q", e.Name)
+}
+
+// ErrIncompatibleStage indicates a stage would receive the wrong kind of
+// data from the stage before it.
+type ErrIncompatibleStage struct {
+	Stage string
+	Want  DataKind
+	Got   DataKind
+}
+
+func (e ErrIncompatibleStage) Error() string {
+	return fmt.Sprintf("stage %q needs %s input but would receive %s", e.Stage, e.Want, e.Got)
+}
+
+// LimitExceededError indicates a chain stopped because its input needed
+// more than one of its Limits allows.
+type LimitExceededError struct {
+	Limit Limit
+	Max   int64 // The limit, in nanoseconds for LimitDuration
+}
+
+func (e LimitExceededError) Error() string {
+	if e.Limit == LimitDuration {
+		return fmt.Sprintf("%s limit of %s exceeded", e.Limit, time.Duration(e.Max))
+	}
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
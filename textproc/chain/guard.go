@@ -0,0 +1,156 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// guard holds the context of the Next call in progress and the chain's
+// resource use, for the checkpoints between stages.
+type guard struct {
+	ctx     context.Context
+	limits  Limits
+	started time.Time     // Start of the Next call in progress
+	spent   time.Duration // Time spent in earlier Next calls
+	read    int64         // Bytes read from the input
+	chunks  int           // Chunks passed to the combiner
+	err     error         // Limit that tripped
+}
+
+// context returns the current context, or context.Background() outside a
+// NextContext call.
+func (g *guard) context() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// enter makes ctx current and starts the clock for a Next call, and
+// returns a function ending the call.
+func (g *guard) enter(ctx context.Context) func() {
+	prev := g.ctx
+	g.ctx = ctx
+	g.started = time.Now()
+	return func() {
+		g.ctx = prev
+		g.spent += time.Since(g.started)
+	}
+}
+
+// check returns the context's error, or a LimitExceededError once a limit
+// has tripped.
+func (g *guard) check() error {
+	if err := g.context().Err(); err != nil {
+		return err
+	}
+	if max := g.limits.MaxDuration; max > 0 && g.err == nil && g.spent+time.Since(g.started) > max {
+		g.err = LimitExceededError{Limit: LimitDuration, Max: int64(max)}
+	}
+	return g.err
+}
+
+// countBytes adds n bytes read from the input.
+func (g *guard) countBytes(n int) error {
+	g.read += int64(n)
+	if max := g.limits.MaxInputBytes; max > 0 && g.read > max {
+		g.err = LimitExceededError{Limit: LimitInputBytes, Max: max}
+	}
+	return g.err
+}
+
+// countChunk adds a chunk passed to the combiner.
+func (g *guard) countChunk() error {
+	g.chunks++
+	if max := g.limits.MaxChunks; max > 0 && g.chunks > max {
+		g.err = LimitExceededError{Limit: LimitChunks, Max: int64(max)}
+	}
+	return g.err
+}
+
+// checkBytes is the checkpoint after the reader. It counts the input
+// bytes as they are read.
+type checkBytes struct {
+	src   textproc.BytesProcessor
+	guard *guard
+}
+
+func (c *checkBytes) Next() ([]byte, error) {
+	if err := c.guard.check(); err != nil {
+		return nil, err
+	}
+	b, err := textproc.NextBytesContext(c.guard.context(), c.src)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.guard.countBytes(len(b)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// checkString is the checkpoint before each read from a string stage.
+type checkString struct {
+	src   textproc.StringProcessor
+	guard *guard
+}
+
+func (c *checkString) Next() (string, error) {
+	if err := c.guard.check(); err != nil {
+		return "", err
+	}
+	return textproc.NextStringContext(c.guard.context(), c.src)
+}
+
+// checkChunk is the checkpoint before each read from a chunk stage, and
+// counts the chunks read when count is set. It passes spans through, so it
+// does not hide them from the next stage.
+type checkChunk struct {
+	src   textproc.ChunkProcessor
+	guard *guard
+	count bool
+}
+
+func (c *checkChunk) Next() (textproc.Chunk, error) {
+	if err := c.guard.check(); err != nil {
+		return "", err
+	}
+	chunk, err := textproc.NextChunkContext(c.guard.context(), c.src)
+	if err != nil {
+		return "", err
+	}
+	return chunk, c.counted()
+}
+
+func (c *checkChunk) NextWithSpan() (textproc.Chunk, textproc.Span, error) {
+	src, ok := c.src.(textproc.SpanChunkProcessor)
+	if !ok {
+		chunk, err := c.Next()
+		return chunk, textproc.Span{}, err
+	}
+	if err := c.guard.check(); err != nil {
+		return "", textproc.Span{}, err
+	}
+	chunk, span, err := src.NextWithSpan()
+	if err != nil {
+		return "", textproc.Span{}, err
+	}
+	return chunk, span, c.counted()
+}
+
+// counted counts a chunk read, if chunks are counted here.
+func (c *checkChunk) counted() error {
+	if !c.count {
+		return nil
+	}
+	return c.guard.countChunk()
+}
+
+// Ensure the checkpoints implement the processor interfaces
+var (
+	_ textproc.BytesProcessor     = (*checkBytes)(nil)
+	_ textproc.StringProcessor    = (*checkString)(nil)
+	_ textproc.SpanChunkProcessor = (*checkChunk)(nil)
+)
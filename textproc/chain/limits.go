@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"time"
+)
+
+// Limit names a resource limit of a Chain.
+type Limit string
+
+// The limits a Chain enforces.
+const (
+	LimitInputBytes Limit = "input bytes"
+	LimitChunks     Limit = "chunks"
+	LimitHTMLDepth  Limit = "HTML nesting depth"
+	LimitDuration   Limit = "processing time"
+)
+
+// Limits bounds the resources a Chain spends on one input, so adversarial
+// input fails fast with a LimitExceededError instead of tying up the
+// process. A zero field means no limit.
+type Limits struct {
+	// MaxInputBytes is the most bytes read from the input.
+	MaxInputBytes int64
+	// MaxChunks is the most chunks produced for the combiner, counting
+	// each chunk once however many slices it overlaps into.
+	MaxChunks int
+	// MaxHTMLDepth is the most HTML elements open at once in the
+	// htmlstrip stage; see htmlstrip.WithMaxDepth.
+	MaxHTMLDepth int
+	// MaxDuration is the most time spent in the chain's Next methods,
+	// summed over calls. It is checked between blocks, not within them.
+	MaxDuration time.Duration
+}
+
+// DefaultLimits are generous limits for mail bodies. A Builder applies no
+// limits unless given some with WithLimits.
+var DefaultLimits = Limits{
+	MaxInputBytes: 64 << 20,
+	MaxChunks:     100000,
+	MaxHTMLDepth:  512,
+	MaxDuration:   time.Minute,
+}
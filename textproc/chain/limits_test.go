@@ -0,0 +1,147 @@
+package chain
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chunker"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/combiner"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
+)
+
+// slowProcessor waits before passing each block on.
+type slowProcessor struct {
+	src   textproc.StringProcessor
+	delay time.Duration
+}
+
+func (p *slowProcessor) Next() (string, error) {
+	time.Sleep(p.delay)
+	return p.src.Next()
+}
+
+// drain reads c to the end, returning the error that stopped it.
+func drain(c *Chain) error {
+	for {
+		if _, err := c.Next(); err != nil {
+			return err
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	paragraphs := strings.Repeat("Some words in a paragraph.\n\n", 100)
+	tests := []struct {
+		name   string
+		input  string
+		limits Limits
+		want   LimitExceededError
+	}{
+		{"input bytes", paragraphs, Limits{MaxInputBytes: 1000}, LimitExceededError{Limit: LimitInputBytes, Max: 1000}},
+		{"chunks", paragraphs, Limits{MaxChunks: 10}, LimitExceededError{Limit: LimitChunks, Max: 10}},
+		{"HTML depth", strings.Repeat("<div>", 1000) + "text", Limits{MaxHTMLDepth: 100}, LimitExceededError{Limit: LimitHTMLDepth, Max: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewBuilder(strings.NewReader(tt.input)).
+				WithReaderOptions(reader.WithBlockSize(64)).
+				WithLimits(tt.limits).
+				Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = drain(c)
+			var limitErr LimitExceededError
+			if !errors.As(err, &limitErr) || limitErr != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if _, err := c.Next(); !errors.As(err, &limitErr) {
+				t.Errorf("expected the limit error again, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLimitsNotExceeded(t *testing.T) {
+	input := strings.Repeat("Some words in a paragraph.\n\n", 100)
+	c, err := NewBuilder(strings.NewReader(input)).
+		WithLimits(Limits{
+			MaxInputBytes: int64(len(input)),
+			MaxChunks:     100,
+			MaxHTMLDepth:  1,
+			MaxDuration:   time.Minute,
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, c); strings.Count(got, "paragraph") != 100 {
+		t.Errorf("expected every paragraph, got %q", got)
+	}
+}
+
+func TestLimitDuration(t *testing.T) {
+	input := strings.Repeat("Some words in a paragraph.\n\n", 100)
+	c, err := NewBuilder(strings.NewReader(input)).
+		WithReaderOptions(reader.WithBlockSize(64)).
+		Before(StageHTMLStrip, StringStage("slow", func(src textproc.StringProcessor) textproc.StringProcessor {
+			return &slowProcessor{src: src, delay: time.Millisecond}
+		})).
+		WithLimits(Limits{MaxDuration: 10 * time.Millisecond}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = drain(c)
+	want := LimitExceededError{Limit: LimitDuration, Max: int64(10 * time.Millisecond)}
+	var limitErr LimitExceededError
+	if !errors.As(err, &limitErr) || limitErr != want {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
+
+func TestLimitDurationCountsOnlyNextCalls(t *testing.T) {
+	c, err := NewBuilder(strings.NewReader("one\n\ntwo")).
+		WithLimits(Limits{MaxDuration: 50 * time.Millisecond}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := drain(c); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestLimitExceededErrorMessage(t *testing.T) {
+	tests := map[LimitExceededError]string{
+		{Limit: LimitInputBytes, Max: 1000}:                  "input bytes limit of 1000 exceeded",
+		{Limit: LimitHTMLDepth, Max: 512}:                    "HTML nesting depth limit of 512 exceeded",
+		{Limit: LimitDuration, Max: int64(90 * time.Second)}: "processing time limit of 1m30s exceeded",
+	}
+	for err, want := range tests {
+		if got := err.Error(); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestBuilderWithChunkerOptions(t *testing.T) {
+	x := strings.Repeat("x", 100)
+	c, err := NewBuilder(strings.NewReader(x+x+x)).
+		WithReaderOptions(reader.WithBlockSize(100)).
+		Without(StageHTMLStrip, StageElider).
+		WithChunkerOptions(chunker.WithMaxBuffer(100)).
+		WithCombinerOptions(combiner.WithOverlap(0), combiner.WithCharLimit(100)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := collect(t, c), x+"|"+x+"|"+x; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,34 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// Text is the front of a chain, up to the chunker: the input as cleaned
+// text. Create one with Builder.BuildText.
+type Text struct {
+	src   textproc.StringProcessor
+	guard *guard // Context of the Next call in progress, and limits
+}
+
+// Next returns the next block of text.
+// Returns io.EOF when all data has been consumed.
+func (t *Text) Next() (string, error) {
+	return t.NextContext(context.Background())
+}
+
+// NextContext is Next, but stops between blocks once ctx is done, as
+// Chain.NextContext does.
+func (t *Text) NextContext(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	defer t.guard.enter(ctx)()
+	s, err := t.src.Next()
+	return s, limitError(err)
+}
+
+// Ensure Text implements ContextStringProcessor
+var _ textproc.ContextStringProcessor = (*Text)(nil)
@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readText reads t to the end, returning the text and the error that
+// stopped it, or nil at io.EOF.
+func readText(t *Text) (string, error) {
+	var out strings.Builder
+	for {
+		s, err := t.Next()
+		if err == io.EOF {
+			return out.String(), nil
+		}
+		if err != nil {
+			return out.String(), err
+		}
+		out.WriteString(s)
+	}
+}
+
+func TestBuildText(t *testing.T) {
+	input := "<p>Hello <b>world</b></p>\n<blockquote>On Monday, Bob wrote:<br>&gt; old</blockquote>"
+	text, err := NewBuilder(strings.NewReader(input)).BuildText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "Hello world") || strings.Contains(got, "<b>") {
+		t.Errorf("expected stripped text, got %q", got)
+	}
+
+	text, err = NewBuilder(strings.NewReader("para one \r\ncontinued\r\n")).
+		WithContentType("text/plain; format=flowed").
+		BuildText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readText(text); strings.TrimSpace(got) != "para one continued" {
+		t.Errorf("expected the flowed front-end, got %q", got)
+	}
+}
+
+func TestBuildTextLimits(t *testing.T) {
+	text, err := NewBuilder(strings.NewReader(strings.Repeat("<div>", 1000) + "text")).
+		WithLimits(Limits{MaxHTMLDepth: 100}).
+		BuildText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readText(text)
+	var limitErr LimitExceededError
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitHTMLDepth {
+		t.Errorf("expected the HTML depth limit, got %v", err)
+	}
+
+	if _, err := NewBuilder(strings.NewReader("")).WithContentType(";;").BuildText(); err == nil {
+		t.Error("expected the content type error from BuildText")
+	}
+}
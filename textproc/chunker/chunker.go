@@ -4,90 +4,150 @@ import (
 	"io"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
 )
 
+// DefaultMaxBuffer is the most input buffered while looking for a
+// paragraph boundary.
+const DefaultMaxBuffer = 1 << 20 // 1MB
+
 // Processor reads string blocks and splits them into chunks based on paragraph boundaries.
 type Processor struct {
 	src             textproc.StringProcessor
-	buffer          string         // Input from source
+	buffer          []byte         // Input from source
+	scanned         int            // Bytes of buffer searched without finding a boundary
+	base            int64          // Stream offset of buffer[0]
+	maxBuffer       int            // Most input buffered before emitting it as a chunk
 	boundaryPattern *regexp.Regexp // Pre-compiled boundary detection
 }
 
+// Option configures a Processor.
+type Option func(*Processor)
+
+// WithMaxBuffer sets the most input buffered while looking for a paragraph
+// boundary. When a block would take the buffer past n bytes, the buffer is
+// emitted as a chunk of its own. The default is DefaultMaxBuffer.
+func WithMaxBuffer(n int) Option {
+	return func(p *Processor) {
+		if n > 0 {
+			p.maxBuffer = n
+		}
+	}
+}
+
 const boundaryPattern = `\n\n|\r\n\r\n|\n---\n|\n\*\*\*\n`
 
+// maxBoundaryLen is the length of the longest boundary.
+const maxBoundaryLen = len("\n---\n")
+
 // NewProcessor creates a new Processor with the given StringProcessor source.
-func NewProcessor(src textproc.StringProcessor) *Processor {
-	return &Processor{
+func NewProcessor(src textproc.StringProcessor, opts ...Option) *Processor {
+	p := &Processor{
 		src:             src,
+		maxBuffer:       DefaultMaxBuffer,
 		boundaryPattern: regexp.MustCompile(boundaryPattern),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Next returns the next Chunk.
 func (p *Processor) Next() (textproc.Chunk, error) {
-	const maxBufferSize = 1 << 20 // 1MB
+	chunk, _, err := p.NextWithSpan()
+	return chunk, err
+}
 
+// NextWithSpan returns the next Chunk and its byte offsets in the input.
+func (p *Processor) NextWithSpan() (textproc.Chunk, textproc.Span, error) {
 	for {
 		// Read more data if buffer is empty
-		for p.buffer == "" {
+		for len(p.buffer) == 0 {
 			block, err := p.src.Next()
 			if err == io.EOF {
 				// Source exhausted with no more data
-				return "", io.EOF
+				return "", textproc.Span{}, io.EOF
 			}
 			if err != nil {
 				// Other error
-				return "", err
+				return "", textproc.Span{}, err
 			}
-			p.buffer += block
+			p.buffer = append(p.buffer, block...)
 		}
 
-		// Search for boundary pattern in buffer
-		loc := p.boundaryPattern.FindStringIndex(p.buffer)
+		// Search for boundary pattern in the buffer, resuming where the
+		// last search left off so each byte is searched about once
+		from := max(p.scanned-maxBoundaryLen+1, 0)
+		loc := p.boundaryPattern.FindIndex(p.buffer[from:])
 		if loc != nil {
 			// Found boundary: extract paragraph
-			paragraph := p.buffer[:loc[0]]
-			p.buffer = p.buffer[loc[1]:]
+			paragraph := string(p.buffer[:from+loc[0]])
+			base := p.base
+			p.buffer = p.buffer[from+loc[1]:]
+			p.base += int64(from + loc[1])
+			p.scanned = 0
 
 			// Trim whitespace
-			trimmed := strings.TrimSpace(paragraph)
+			trimmed, span := trimSpan(paragraph, base)
 			if trimmed != "" {
-				return textproc.Chunk(trimmed), nil
+				return textproc.Chunk(trimmed), span, nil
 			}
 			// Empty paragraph, continue to next
 			continue
 		}
+		p.scanned = len(p.buffer)
 
 		// No boundary found - try to read more data
 		block, err := p.src.Next()
 		if err == io.EOF {
 			// Source exhausted, emit remaining content
-			trimmed := strings.TrimSpace(p.buffer)
-			p.buffer = ""
-			if trimmed != "" {
-				return textproc.Chunk(trimmed), nil
+			chunk, span := p.flush()
+			if chunk != "" {
+				return chunk, span, nil
 			}
-			return "", io.EOF
+			return "", textproc.Span{}, io.EOF
 		}
 		if err != nil {
 			// Other error
-			return "", err
+			return "", textproc.Span{}, err
 		}
 
 		// Check if buffer would be too large
-		if len(p.buffer)+len(block) > maxBufferSize {
+		if len(p.buffer)+len(block) > p.maxBuffer {
 			// Emit current buffer as-is
-			trimmed := strings.TrimSpace(p.buffer)
-			p.buffer = block
-			if trimmed != "" {
-				return textproc.Chunk(trimmed), nil
+			chunk, span := p.flush()
+			p.buffer = append(p.buffer, block...)
+			if chunk != "" {
+				return chunk, span, nil
 			}
 			continue
 		}
 
 		// Append new block and continue looking for boundary
-		p.buffer += block
+		p.buffer = append(p.buffer, block...)
 	}
 }
+
+// flush empties the buffer, returning its trimmed content as a chunk.
+func (p *Processor) flush() (textproc.Chunk, textproc.Span) {
+	trimmed, span := trimSpan(string(p.buffer), p.base)
+	p.base += int64(len(p.buffer))
+	p.buffer = p.buffer[:0]
+	p.scanned = 0
+	return textproc.Chunk(trimmed), span
+}
+
+// trimSpan trims whitespace from s, which starts at stream offset base, and
+// returns the trimmed text with its span.
+func trimSpan(s string, base int64) (string, textproc.Span) {
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start := base + int64(len(s)-len(trimmed))
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	return trimmed, textproc.Span{Start: start, End: start + int64(len(trimmed))}
+}
+
+// Ensure Processor implements SpanChunkProcessor
+var _ textproc.SpanChunkProcessor = (*Processor)(nil)
@@ -3,7 +3,9 @@ package chunker
 import (
 	"errors"
 	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
 )
@@ -240,3 +242,75 @@ func TestNewProcessorCreatesProcessor(t *testing.T) {
 		t.Fatal("expected Processor to be non-nil")
 	}
 }
+
+func TestNextWithSpan(t *testing.T) {
+	input := "  First para.\n\n\nSecond\npara.  \n\nThird"
+	src := &mockStringProcessor{blocks: []string{input[:10], input[10:20], input[20:]}}
+	p := NewProcessor(src)
+	want := []string{"First para.", "Second\npara.", "Third"}
+	for _, w := range want {
+		chunk, span, err := p.NextWithSpan()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(chunk) != w || input[span.Start:span.End] != w {
+			t.Errorf("expected %q, got %q at %+v (%q)", w, chunk, span, input[span.Start:span.End])
+		}
+	}
+	if _, _, err := p.NextWithSpan(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+// collectChunks reads every chunk from p.
+func collectChunks(t *testing.T, p *Processor) []string {
+	t.Helper()
+	var chunks []string
+	for {
+		chunk, err := p.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		chunks = append(chunks, string(chunk))
+	}
+}
+
+func TestBoundarySplitAcrossSingleByteBlocks(t *testing.T) {
+	input := "Para1\n---\nPara2\r\n\r\nPara3\n***\nPara4"
+	var blocks []string
+	for i := range len(input) {
+		blocks = append(blocks, input[i:i+1])
+	}
+	got := collectChunks(t, NewProcessor(&mockStringProcessor{blocks: blocks}))
+	want := []string{"Para1", "Para2", "Para3", "Para4"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestWithMaxBuffer(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"aaaa", "bbbb", "cccc\n\ndd"}}
+	got := collectChunks(t, NewProcessor(src, WithMaxBuffer(10)))
+	want := []string{"aaaabbbb", "cccc", "dd"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestLongParagraphIsLinear(t *testing.T) {
+	var blocks []string
+	for range 1 << 10 {
+		blocks = append(blocks, strings.Repeat("x", 1<<10))
+	}
+	start := time.Now()
+	got := collectChunks(t, NewProcessor(&mockStringProcessor{blocks: blocks}))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("chunking took %v", elapsed)
+	}
+	if len(got) != 1 || len(got[0]) != 1<<20 {
+		t.Errorf("expected one chunk of 1MB, got %d chunks", len(got))
+	}
+}
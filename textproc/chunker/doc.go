@@ -13,6 +13,11 @@
 // Chunks are automatically trimmed of leading and trailing whitespace.
 // Empty paragraphs (whitespace-only) are skipped.
 //
+// A paragraph with no boundary in sight is buffered up to DefaultMaxBuffer
+// bytes, or the limit set by WithMaxBuffer, and then emitted as it stands.
+// Each byte is searched for a boundary only once, however the input
+// arrives.
+//
 // The Processor implements textproc.ChunkProcessor, making it suitable for
 // pull-based lazy evaluation chains.
 //
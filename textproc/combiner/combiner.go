@@ -2,19 +2,28 @@ package combiner
 
 import (
 	"io"
+	"sort"
+	"unicode/utf8"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
 )
 
 // Processor accumulates chunks with overlap into ChunkSlices up to a character limit.
+// The limit is measured by a textproc.Counter, runes by default.
 type Processor struct {
 	src       textproc.ChunkProcessor
 	charLimit int
+	counter   textproc.Counter
+	strict    bool // Never exceed charLimit, splitting oversized chunks
 	overlap   int
 	buffer    []textproc.Chunk
+	spans     []textproc.Span // Spans of buffer, parallel to it
 	charCount int
 	exhausted bool
 	pending   textproc.Chunk // Chunk that was read but didn't fit
+	pendSpan  textproc.Span  // Span of pending
+	split     textproc.Chunk // Rest of a chunk split to fit, read next
+	splitSpan textproc.Span  // Span of split
 }
 
 // Option configures a Processor.
@@ -34,12 +43,43 @@ func WithOverlap(n int) Option {
 	}
 }
 
+// WithCounter sets how chunk sizes are measured against the limit, so the
+// limit can be given in bytes, words or model tokens. Default is runes.
+// A ChunkSlice's size is the sum of its chunks' sizes.
+func WithCounter(c textproc.Counter) Option {
+	return func(p *Processor) {
+		p.counter = c
+	}
+}
+
+// WithStrictLimit never returns a ChunkSlice over the limit. Overlap is
+// reduced to make room for the next chunk, and a chunk over the limit by
+// itself is split at the last rune boundary that fits, rather than
+// returned whole. Put a splitter first to split on sentences and words.
+func WithStrictLimit() Option {
+	return func(p *Processor) {
+		p.strict = true
+	}
+}
+
+// WithByteLimit sets a strict limit of n UTF-8 bytes per ChunkSlice,
+// replacing the char limit and counter. n is raised to utf8.UTFMax if it
+// is smaller, to always fit a rune.
+func WithByteLimit(n int) Option {
+	return func(p *Processor) {
+		p.charLimit = max(n, utf8.UTFMax)
+		p.counter = textproc.ByteCounter{}
+		p.strict = true
+	}
+}
+
 // NewProcessor creates a new Processor with the given ChunkProcessor source.
 // By default: charLimit=4000, overlap=2.
 func NewProcessor(src textproc.ChunkProcessor, opts ...Option) *Processor {
 	p := &Processor{
 		src:       src,
 		charLimit: 4000,
+		counter:   textproc.RuneCounter{},
 		overlap:   2,
 		buffer:    make([]textproc.Chunk, 0),
 	}
@@ -52,93 +92,173 @@ func NewProcessor(src textproc.ChunkProcessor, opts ...Option) *Processor {
 // Next returns the next ChunkSlice with accumulated chunks and overlap.
 // Returns io.EOF when all chunks have been consumed.
 func (p *Processor) Next() (textproc.ChunkSlice, error) {
+	slice, _, err := p.NextWithSpans()
+	return slice, err
+}
+
+// NextWithSpans returns the next ChunkSlice and the span of each chunk in
+// it, as reported by the source. The spans are zero if the source does not
+// report them.
+func (p *Processor) NextWithSpans() (textproc.ChunkSlice, []textproc.Span, error) {
 	// If we already returned everything and no pending chunks, return EOF
 	if p.exhausted && len(p.buffer) == 0 && p.pending == "" {
-		return nil, io.EOF
+		return nil, nil, io.EOF
 	}
 
 	// Handle overlap: keep last overlap chunks from previous output
 	if len(p.buffer) > p.overlap {
 		p.buffer = p.buffer[len(p.buffer)-p.overlap:]
+		p.spans = p.spans[len(p.spans)-p.overlap:]
 		// Recalculate char count after trimming
 		p.charCount = 0
 		for _, c := range p.buffer {
-			p.charCount += len([]rune(c))
+			p.charCount += p.counter.Count(string(c))
 		}
 
 		// Progress guarantee: if overlap alone exceeds limit, drop from front
 		for p.charCount > p.charLimit && len(p.buffer) > 0 {
 			p.buffer = p.buffer[1:]
+			p.spans = p.spans[1:]
 			p.charCount = 0
 			for _, c := range p.buffer {
-				p.charCount += len([]rune(c))
+				p.charCount += p.counter.Count(string(c))
 			}
 		}
 	}
 
 	// Add pending chunk if we have one
 	if p.pending != "" {
+		size := p.counter.Count(string(p.pending))
+		// In strict mode, drop overlap from the front until it fits
+		for p.strict && p.charCount+size > p.charLimit && len(p.buffer) > 0 {
+			p.charCount -= p.counter.Count(string(p.buffer[0]))
+			p.buffer = p.buffer[1:]
+			p.spans = p.spans[1:]
+		}
 		p.buffer = append(p.buffer, p.pending)
-		p.charCount += len([]rune(p.pending))
+		p.spans = append(p.spans, p.pendSpan)
+		p.charCount += size
 		p.pending = ""
 	}
 
 	// Accumulate chunks until char limit is reached
 	for {
-		chunk, err := p.src.Next()
+		chunk, span, err := p.nextSource()
 		if err == io.EOF {
 			p.exhausted = true
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// If adding this chunk would exceed limit
-		if p.charCount+len([]rune(chunk)) > p.charLimit {
+		size := p.counter.Count(string(chunk))
+		if p.charCount+size > p.charLimit {
 			// Special case: single chunk exceeds limit, return it anyway
 			if len(p.buffer) == 0 {
 				result := textproc.ChunkSlice{chunk}
-				return result, nil
+				return result, []textproc.Span{span}, nil
 			}
 
 			// Otherwise, save as pending and return what we have
 			p.pending = chunk
+			p.pendSpan = span
 			break
 		}
 
 		// Add chunk to buffer
 		p.buffer = append(p.buffer, chunk)
-		p.charCount += len([]rune(chunk))
+		p.spans = append(p.spans, span)
+		p.charCount += size
 	}
 
 	// Return buffer as result
 	if len(p.buffer) == 0 {
-		return nil, io.EOF
+		return nil, nil, io.EOF
 	}
 
 	result := make(textproc.ChunkSlice, len(p.buffer))
 	copy(result, p.buffer)
+	spans := make([]textproc.Span, len(p.spans))
+	copy(spans, p.spans)
 
 	// If source is exhausted, clear buffer for EOF on next call
 	// Don't keep overlap on final output
 	if p.exhausted {
 		p.buffer = make([]textproc.Chunk, 0)
+		p.spans = nil
 		p.charCount = 0
 	} else {
 		// Keep overlap chunks for next iteration
 		if len(p.buffer) > p.overlap {
 			p.buffer = p.buffer[len(p.buffer)-p.overlap:]
+			p.spans = p.spans[len(p.spans)-p.overlap:]
 		} else {
 			p.buffer = make([]textproc.Chunk, 0)
+			p.spans = nil
 		}
 
 		// Recalculate char count
 		p.charCount = 0
 		for _, c := range p.buffer {
-			p.charCount += len([]rune(c))
+			p.charCount += p.counter.Count(string(c))
 		}
 	}
 
-	return result, nil
+	return result, spans, nil
+}
+
+// nextSource reads the next chunk from the source, with its span if the
+// source reports one. In strict mode, chunks over the limit are split.
+func (p *Processor) nextSource() (textproc.Chunk, textproc.Span, error) {
+	if p.split != "" {
+		chunk, span := p.split, p.splitSpan
+		p.split = ""
+		return p.fit(chunk, span)
+	}
+	if src, ok := p.src.(textproc.SpanChunkProcessor); ok {
+		chunk, span, err := src.NextWithSpan()
+		if err != nil {
+			return "", textproc.Span{}, err
+		}
+		return p.fit(chunk, span)
+	}
+	chunk, err := p.src.Next()
+	if err != nil {
+		return "", textproc.Span{}, err
+	}
+	return p.fit(chunk, textproc.Span{})
 }
+
+// fit returns chunk whole, or in strict mode its longest leading runes
+// within the limit, keeping the rest to be read next.
+func (p *Processor) fit(chunk textproc.Chunk, span textproc.Span) (textproc.Chunk, textproc.Span, error) {
+	if !p.strict || p.counter.Count(string(chunk)) <= p.charLimit {
+		return chunk, span, nil
+	}
+	ends := make([]int, 0, len(chunk))
+	for i := 0; i < len(chunk); {
+		_, size := utf8.DecodeRuneInString(string(chunk[i:]))
+		i += size
+		ends = append(ends, i)
+	}
+	n := sort.Search(len(ends), func(i int) bool {
+		return p.counter.Count(string(chunk[:ends[i]])) > p.charLimit
+	})
+	// A rune over the limit by itself is all that can be done
+	cut := ends[max(n-1, 0)]
+	if cut == len(chunk) {
+		return chunk, span, nil
+	}
+	p.split = chunk[cut:]
+	p.splitSpan = textproc.Span{}
+	if !span.IsZero() {
+		p.splitSpan = textproc.Span{Start: span.Start + int64(cut), End: span.End}
+		span.End = span.Start + int64(cut)
+	}
+	return chunk[:cut], span, nil
+}
+
+// Ensure Processor implements SpanChunkCombiner
+var _ textproc.SpanChunkCombiner = (*Processor)(nil)
@@ -2,6 +2,7 @@ package combiner
 
 import (
 	"io"
+	"reflect"
 	"testing"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
@@ -446,3 +447,169 @@ func TestDefaultByteLimit(t *testing.T) {
 		t.Errorf("expected 4000 chars with default limit, got %d", totalChars(result))
 	}
 }
+
+// mockSpanChunkSource reports a span for each chunk.
+type mockSpanChunkSource struct {
+	mockChunkSource
+}
+
+func (m *mockSpanChunkSource) NextWithSpan() (textproc.Chunk, textproc.Span, error) {
+	i := m.index
+	chunk, err := m.Next()
+	if err != nil {
+		return "", textproc.Span{}, err
+	}
+	return chunk, textproc.Span{Start: int64(i * 10), End: int64(i*10 + len(chunk))}, nil
+}
+
+func TestNextWithSpansKeepsSpansWithOverlap(t *testing.T) {
+	src := &mockSpanChunkSource{mockChunkSource{chunks: []textproc.Chunk{"aaaa", "bbbb", "cccc", "dddd"}}}
+	p := NewProcessor(src, WithCharLimit(8), WithOverlap(1))
+	for {
+		slice, spans, err := p.NextWithSpans()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(spans) != len(slice) {
+			t.Fatalf("expected %d spans, got %d", len(slice), len(spans))
+		}
+		for i, chunk := range slice {
+			want := int64(chunk[0]-'a') * 10
+			if spans[i].Start != want || spans[i].End != want+4 {
+				t.Errorf("chunk %q: unexpected span %+v", chunk, spans[i])
+			}
+		}
+	}
+}
+
+// TestWithCounter verifies the limit is measured by the configured counter.
+func TestWithCounter(t *testing.T) {
+	src := &mockChunkSource{
+		chunks: []textproc.Chunk{"one two", "three four", "five six"},
+	}
+	p := NewProcessor(src, WithCharLimit(4), WithOverlap(0), WithCounter(textproc.WordCounter{}))
+
+	var got []textproc.ChunkSlice
+	for {
+		slice, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, slice)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 ChunkSlices, got %d: %v", len(got), got)
+	}
+	if len(got[0]) != 2 || got[0][1] != "three four" {
+		t.Errorf("expected first slice to hold 4 words, got %v", got[0])
+	}
+	if len(got[1]) != 1 || got[1][0] != "five six" {
+		t.Errorf("expected second slice to hold the last chunk, got %v", got[1])
+	}
+}
+
+// TestWithByteCounter verifies multibyte text is limited in bytes.
+func TestWithByteCounter(t *testing.T) {
+	src := &mockChunkSource{
+		chunks: []textproc.Chunk{"日本", "語"},
+	}
+	p := NewProcessor(src, WithCharLimit(6), WithOverlap(0), WithCounter(textproc.ByteCounter{}))
+
+	first, err := p.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first) != 1 || first[0] != "日本" {
+		t.Errorf("expected first slice [日本], got %v", first)
+	}
+	second, err := p.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(second) != 1 || second[0] != "語" {
+		t.Errorf("expected second slice [語], got %v", second)
+	}
+}
+
+// TestByteLimitSplitsOversizedChunk verifies a chunk over the byte limit is
+// split on rune boundaries instead of returned whole.
+func TestByteLimitSplitsOversizedChunk(t *testing.T) {
+	src := &mockSpanChunkSource{mockChunkSource{chunks: []textproc.Chunk{"日本語日本語"}}}
+	p := NewProcessor(src, WithByteLimit(7), WithOverlap(0))
+
+	var got []textproc.Chunk
+	var spans []textproc.Span
+	for {
+		slice, sliceSpans, err := p.NextWithSpans()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(slice) != 1 {
+			t.Fatalf("expected one chunk per slice, got %q", slice)
+		}
+		got = append(got, slice[0])
+		spans = append(spans, sliceSpans[0])
+	}
+
+	want := []textproc.Chunk{"日本", "語日", "本語"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	wantSpans := []textproc.Span{{Start: 0, End: 6}, {Start: 6, End: 12}, {Start: 12, End: 18}}
+	if !reflect.DeepEqual(spans, wantSpans) {
+		t.Errorf("expected spans %+v, got %+v", wantSpans, spans)
+	}
+}
+
+// TestStrictLimitDropsOverlap verifies overlap is reduced rather than
+// letting overlap and the next chunk exceed the limit.
+func TestStrictLimitDropsOverlap(t *testing.T) {
+	chunks := []textproc.Chunk{"aaaa", "bbbb", "cccccccc"}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []textproc.ChunkSlice
+	}{
+		{
+			name: "default",
+			opts: []Option{WithCharLimit(10), WithOverlap(1)},
+			want: []textproc.ChunkSlice{{"aaaa", "bbbb"}, {"bbbb", "cccccccc"}},
+		},
+		{
+			name: "strict",
+			opts: []Option{WithCharLimit(10), WithOverlap(1), WithStrictLimit()},
+			want: []textproc.ChunkSlice{{"aaaa", "bbbb"}, {"cccccccc"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor(&mockChunkSource{chunks: chunks}, tt.opts...)
+			var got []textproc.ChunkSlice
+			for {
+				slice, err := p.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got = append(got, slice)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -24,6 +24,9 @@
 // # Edge Cases
 //
 // - Single chunk exceeding byte limit: Returned as-is
+// - Strict mode (WithStrictLimit, or WithByteLimit for a UTF-8 byte budget):
+// a single chunk exceeding the limit is split at a rune boundary, and overlap
+// is dropped to make room, so no ChunkSlice exceeds the limit
 // - Final chunks: All remaining chunks returned even if under limit
 // - Empty source: Returns io.EOF immediately
 package combiner
@@ -0,0 +1,86 @@
+package textproc
+
+import (
+	"context"
+)
+
+// ContextBytesProcessor is a BytesProcessor that can stop early when a
+// context is done, returning ctx.Err().
+type ContextBytesProcessor interface {
+	BytesProcessor
+	NextContext(ctx context.Context) ([]byte, error)
+}
+
+// ContextStringProcessor is a StringProcessor that can stop early when a
+// context is done, returning ctx.Err().
+type ContextStringProcessor interface {
+	StringProcessor
+	NextContext(ctx context.Context) (string, error)
+}
+
+// ContextChunkProcessor is a ChunkProcessor that can stop early when a
+// context is done, returning ctx.Err().
+type ContextChunkProcessor interface {
+	ChunkProcessor
+	NextContext(ctx context.Context) (Chunk, error)
+}
+
+// ContextChunkCombiner is a ChunkCombiner that can stop early when a
+// context is done, returning ctx.Err().
+// Implemented by: chain, langid
+type ContextChunkCombiner interface {
+	ChunkCombiner
+	NextContext(ctx context.Context) (ChunkSlice, error)
+}
+
+// NextBytesContext returns the next block from p, passing ctx on if p is a
+// ContextBytesProcessor. Otherwise it returns ctx.Err() if ctx is done,
+// and calls Next if not.
+func NextBytesContext(ctx context.Context, p BytesProcessor) ([]byte, error) {
+	if cp, ok := p.(ContextBytesProcessor); ok {
+		return cp.NextContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.Next()
+}
+
+// NextStringContext returns the next block from p, passing ctx on if p is
+// a ContextStringProcessor. Otherwise it returns ctx.Err() if ctx is done,
+// and calls Next if not.
+func NextStringContext(ctx context.Context, p StringProcessor) (string, error) {
+	if cp, ok := p.(ContextStringProcessor); ok {
+		return cp.NextContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return p.Next()
+}
+
+// NextChunkContext returns the next chunk from p, passing ctx on if p is a
+// ContextChunkProcessor. Otherwise it returns ctx.Err() if ctx is done,
+// and calls Next if not.
+func NextChunkContext(ctx context.Context, p ChunkProcessor) (Chunk, error) {
+	if cp, ok := p.(ContextChunkProcessor); ok {
+		return cp.NextContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return p.Next()
+}
+
+// NextChunkSliceContext returns the next ChunkSlice from p, passing ctx on
+// if p is a ContextChunkCombiner. Otherwise it returns ctx.Err() if ctx is
+// done, and calls Next if not.
+func NextChunkSliceContext(ctx context.Context, p ChunkCombiner) (ChunkSlice, error) {
+	if cp, ok := p.(ContextChunkCombiner); ok {
+		return cp.NextContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.Next()
+}
@@ -0,0 +1,66 @@
+package textproc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// contextStringProcessor records the context it is called with.
+type contextStringProcessor struct {
+	ctx context.Context
+}
+
+func (p *contextStringProcessor) Next() (string, error) {
+	return "", io.EOF
+}
+
+func (p *contextStringProcessor) NextContext(ctx context.Context) (string, error) {
+	p.ctx = ctx
+	return "text", nil
+}
+
+type ctxKey struct{}
+
+func TestNextContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bytes := newTestBytesProcessor([][]byte{[]byte("data")})
+	if _, err := NextBytesContext(ctx, bytes); !errors.Is(err, context.Canceled) {
+		t.Errorf("NextBytesContext: expected context.Canceled, got %v", err)
+	}
+	if bytes.index != 0 {
+		t.Error("NextBytesContext read from a cancelled processor")
+	}
+
+	str := NewBytesToStringAdapter(newTestBytesProcessor([][]byte{[]byte("data")}))
+	if _, err := NextStringContext(ctx, str); !errors.Is(err, context.Canceled) {
+		t.Errorf("NextStringContext: expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNextContextLive(t *testing.T) {
+	bytes := newTestBytesProcessor([][]byte{[]byte("data")})
+	got, err := NextBytesContext(context.Background(), bytes)
+	if err != nil || string(got) != "data" {
+		t.Errorf("NextBytesContext: expected data, got %q, %v", got, err)
+	}
+	if _, err := NextBytesContext(context.Background(), bytes); err != io.EOF {
+		t.Errorf("NextBytesContext: expected io.EOF, got %v", err)
+	}
+}
+
+func TestNextContextPassesContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	p := &contextStringProcessor{}
+
+	got, err := NextStringContext(ctx, p)
+	if err != nil || got != "text" {
+		t.Fatalf("expected text from NextContext, got %q, %v", got, err)
+	}
+	if p.ctx == nil || p.ctx.Value(ctxKey{}) != "value" {
+		t.Error("expected the context to be passed to NextContext")
+	}
+}
@@ -0,0 +1,56 @@
+package textproc
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Counter measures the size of text, in whatever unit a limit is expressed
+// in: runes, bytes, words or model tokens.
+type Counter interface {
+	// Count returns the size of s.
+	Count(s string) int
+}
+
+// RuneCounter counts Unicode code points.
+type RuneCounter struct{}
+
+// Count returns the number of runes in s.
+func (RuneCounter) Count(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// ByteCounter counts UTF-8 bytes.
+type ByteCounter struct{}
+
+// Count returns the number of bytes in s.
+func (ByteCounter) Count(s string) int {
+	return len(s)
+}
+
+// WordCounter counts runs of non-whitespace.
+type WordCounter struct{}
+
+// Count returns the number of whitespace-separated words in s.
+func (WordCounter) Count(s string) int {
+	n := 0
+	inWord := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			inWord = false
+			continue
+		}
+		if !inWord {
+			n++
+			inWord = true
+		}
+	}
+	return n
+}
+
+// Ensure the counters implement Counter
+var (
+	_ Counter = RuneCounter{}
+	_ Counter = ByteCounter{}
+	_ Counter = WordCounter{}
+)
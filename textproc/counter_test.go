@@ -0,0 +1,32 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	tests := []struct {
+		name    string
+		counter Counter
+		input   string
+		want    int
+	}{
+		{"runes ascii", RuneCounter{}, "hello", 5},
+		{"runes multibyte", RuneCounter{}, "héllo 世界", 8},
+		{"runes empty", RuneCounter{}, "", 0},
+		{"bytes ascii", ByteCounter{}, "hello", 5},
+		{"bytes multibyte", ByteCounter{}, "héllo 世界", 13},
+		{"words", WordCounter{}, "the quick  brown\tfox\n", 4},
+		{"words leading space", WordCounter{}, "  one", 1},
+		{"words empty", WordCounter{}, "   ", 0},
+		{"words unicode space", WordCounter{}, "a b", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.counter.Count(tt.input); got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package dedupe
+
+import (
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// DefaultMaxDistance is the most bits in which the SimHashes of near
+// duplicates differ.
+const DefaultMaxDistance = 10
+
+// DefaultMinWords is the fewest words a chunk needs to be dropped.
+const DefaultMinWords = 5
+
+// Processor reads chunks and drops those duplicating an earlier chunk.
+type Processor struct {
+	src         textproc.ChunkProcessor
+	local       *MemoryStore // Chunks of this document
+	shared      Store        // Chunks of other documents, or nil
+	maxDistance int
+	minWords    int
+	dropped     int
+}
+
+// Option configures a Processor.
+type Option func(*Processor)
+
+// WithMaxDistance sets the most bits in which the SimHashes of near
+// duplicates differ within a document. Negative drops exact duplicates
+// only. The default is DefaultMaxDistance.
+func WithMaxDistance(n int) Option {
+	return func(p *Processor) {
+		p.maxDistance = n
+	}
+}
+
+// WithMinWords keeps chunks of fewer than n words, however often they
+// appear. The default is DefaultMinWords.
+func WithMinWords(n int) Option {
+	return func(p *Processor) {
+		p.minWords = n
+	}
+}
+
+// WithStore also drops chunks that s has seen, and records the chunks kept
+// in s, so duplicates are dropped across the documents sharing it.
+func WithStore(s Store) Option {
+	return func(p *Processor) {
+		p.shared = s
+	}
+}
+
+// NewProcessor creates a new Processor with the given ChunkProcessor source.
+func NewProcessor(src textproc.ChunkProcessor, opts ...Option) *Processor {
+	p := &Processor{
+		src:         src,
+		maxDistance: DefaultMaxDistance,
+		minWords:    DefaultMinWords,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.local = NewMemoryStore(p.maxDistance)
+	return p
+}
+
+// Next returns the next chunk that is not a duplicate.
+func (p *Processor) Next() (textproc.Chunk, error) {
+	chunk, _, err := p.NextWithSpan()
+	return chunk, err
+}
+
+// NextWithSpan returns the next chunk that is not a duplicate, with the
+// span the source reported for it.
+func (p *Processor) NextWithSpan() (textproc.Chunk, textproc.Span, error) {
+	for {
+		chunk, span, err := p.nextSource()
+		if err != nil {
+			return "", textproc.Span{}, err
+		}
+		dup, err := p.duplicate(string(chunk))
+		if err != nil {
+			return "", textproc.Span{}, err
+		}
+		if !dup {
+			return chunk, span, nil
+		}
+		p.dropped++
+	}
+}
+
+// Dropped returns the number of chunks dropped so far.
+func (p *Processor) Dropped() int {
+	return p.dropped
+}
+
+// nextSource reads the next chunk, with its span if the source has one.
+func (p *Processor) nextSource() (textproc.Chunk, textproc.Span, error) {
+	if src, ok := p.src.(textproc.SpanChunkProcessor); ok {
+		return src.NextWithSpan()
+	}
+	chunk, err := p.src.Next()
+	return chunk, textproc.Span{}, err
+}
+
+// duplicate records text and reports whether it was seen before.
+func (p *Processor) duplicate(text string) (bool, error) {
+	ws := words(text)
+	if len(ws) < p.minWords {
+		return false, nil
+	}
+	f := fingerprint(ws)
+	if dup, _ := p.local.Observe(f); dup {
+		return true, nil
+	}
+	if p.shared == nil {
+		return false, nil
+	}
+	return p.shared.Observe(f)
+}
+
+// Ensure Processor implements SpanChunkProcessor
+var _ textproc.SpanChunkProcessor = (*Processor)(nil)
@@ -0,0 +1,133 @@
+package dedupe
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// mockChunkProcessor implements textproc.SpanChunkProcessor for testing.
+// Each chunk's span is its index, as a one-byte range.
+type mockChunkProcessor struct {
+	chunks []string
+	index  int
+	err    error
+}
+
+func (m *mockChunkProcessor) Next() (textproc.Chunk, error) {
+	chunk, _, err := m.NextWithSpan()
+	return chunk, err
+}
+
+func (m *mockChunkProcessor) NextWithSpan() (textproc.Chunk, textproc.Span, error) {
+	if m.index >= len(m.chunks) {
+		if m.err != nil {
+			return "", textproc.Span{}, m.err
+		}
+		return "", textproc.Span{}, io.EOF
+	}
+	m.index++
+	return textproc.Chunk(m.chunks[m.index-1]), textproc.Span{Start: int64(m.index - 1), End: int64(m.index)}, nil
+}
+
+// failingStore is a Store that always fails.
+type failingStore struct {
+	err error
+}
+
+func (s failingStore) Observe(Fingerprint) (bool, error) {
+	return false, s.err
+}
+
+// collect reads every chunk from p.
+func collect(t *testing.T, p *Processor) []string {
+	t.Helper()
+	var chunks []string
+	for {
+		chunk, err := p.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		chunks = append(chunks, string(chunk))
+	}
+}
+
+const report = "The quarterly report is attached. Revenue grew in every region " +
+	"except the north, where the new warehouse delayed shipments for most of March."
+
+func TestDropsExactDuplicates(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []string{report, disclaimer, "Thanks,", disclaimer, "Thanks,", strings.ToUpper(disclaimer)}}
+	p := NewProcessor(src)
+	got := collect(t, p)
+	want := []string{report, disclaimer, "Thanks,", "Thanks,"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if p.Dropped() != 2 {
+		t.Errorf("expected 2 dropped, got %d", p.Dropped())
+	}
+}
+
+func TestDropsNearDuplicates(t *testing.T) {
+	edited := strings.Replace(disclaimer, "prohibited", "forbidden", 1)
+	got := collect(t, NewProcessor(&mockChunkProcessor{chunks: []string{disclaimer, report, edited}}))
+	if len(got) != 2 {
+		t.Errorf("expected the edited disclaimer to be dropped, got %q", got)
+	}
+
+	got = collect(t, NewProcessor(&mockChunkProcessor{chunks: []string{disclaimer, edited}}, WithMaxDistance(-1)))
+	if len(got) != 2 {
+		t.Errorf("expected only exact duplicates dropped, got %q", got)
+	}
+}
+
+func TestWithMinWords(t *testing.T) {
+	got := collect(t, NewProcessor(&mockChunkProcessor{chunks: []string{"Thanks,", "Thanks,"}}, WithMinWords(1)))
+	if len(got) != 1 {
+		t.Errorf("expected the repeated short chunk dropped, got %q", got)
+	}
+}
+
+func TestWithStoreAcrossDocuments(t *testing.T) {
+	store := NewMemoryStore(DefaultMaxDistance)
+	first := collect(t, NewProcessor(&mockChunkProcessor{chunks: []string{"Hi Bob, here is the report you asked for.", disclaimer}}, WithStore(store)))
+	second := collect(t, NewProcessor(&mockChunkProcessor{chunks: []string{"Hi Alice, the meeting moved to Tuesday afternoon.", disclaimer}}, WithStore(store)))
+	if len(first) != 2 {
+		t.Errorf("expected the first document to keep the disclaimer, got %q", first)
+	}
+	if len(second) != 1 || second[0] == disclaimer {
+		t.Errorf("expected the second document to drop the disclaimer, got %q", second)
+	}
+}
+
+func TestNextWithSpan(t *testing.T) {
+	p := NewProcessor(&mockChunkProcessor{chunks: []string{disclaimer, disclaimer, report}})
+	for _, want := range []int64{0, 2} {
+		_, span, err := p.NextWithSpan()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if span.Start != want {
+			t.Errorf("expected the span of chunk %d, got %+v", want, span)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	boom := errors.New("boom")
+	p := NewProcessor(&mockChunkProcessor{err: boom})
+	if _, err := p.Next(); !errors.Is(err, boom) {
+		t.Errorf("expected the source error, got %v", err)
+	}
+
+	p = NewProcessor(&mockChunkProcessor{chunks: []string{report}}, WithStore(failingStore{err: boom}))
+	if _, err := p.Next(); !errors.Is(err, boom) {
+		t.Errorf("expected the store error, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+// Package dedupe drops duplicate and near-duplicate chunks.
+//
+// Mailing lists and long threads repeat the same paragraphs: footers,
+// disclaimers and quotes that slip past the elider. The Processor sits
+// between the chunker and the combiner and drops each chunk whose
+// Fingerprint matches one seen before, so the repeats do not use up the
+// embedding budget.
+//
+// A Fingerprint has two parts. Exact hashes the chunk's words, so chunks
+// differing only in case, punctuation or spacing match. Sim is a SimHash of
+// overlapping three-word shingles, so chunks differing in a few words have
+// fingerprints a few bits apart; chunks within WithMaxDistance bits
+// (DefaultMaxDistance) are near duplicates. Chunks of fewer than
+// WithMinWords words are always kept, since greetings and sign-offs are
+// short, legitimately repeated, and too small to fingerprint well.
+//
+// Each Processor remembers the chunks of its own document. WithStore adds
+// a Store shared between documents, so boilerplate seen anywhere in an
+// account is suppressed too; the first document to contain it keeps it.
+// MemoryStore is a Store held in memory, safe for concurrent use; other
+// implementations can keep fingerprints in a database. A MemoryStore
+// shared for a long time should be bounded with WithCapacity, which
+// forgets the least recently seen fingerprints, or emptied with Reset.
+//
+// Position in the pipeline:
+//
+//	After: chunker
+//	Before: splitter, combiner
+package dedupe
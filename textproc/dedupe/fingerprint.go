@@ -0,0 +1,75 @@
+package dedupe
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// shingleSize is the number of words in each SimHash feature.
+const shingleSize = 3
+
+// Fingerprint identifies the text of a chunk for duplicate detection.
+type Fingerprint struct {
+	Exact uint64 // Hash of the words, equal for equal text
+	Sim   uint64 // SimHash of the word shingles, near for similar text
+}
+
+// NewFingerprint returns the fingerprint of text.
+func NewFingerprint(text string) Fingerprint {
+	return fingerprint(words(text))
+}
+
+// Distance returns the number of bits in which the SimHashes of f and g
+// differ.
+func (f Fingerprint) Distance(g Fingerprint) int {
+	return bits.OnesCount64(f.Sim ^ g.Sim)
+}
+
+// words returns the lower-cased words of s: runs of letters and digits.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// fingerprint returns the fingerprint of a sequence of words.
+func fingerprint(ws []string) Fingerprint {
+	var counts [64]int
+	for i := range max(len(ws)-shingleSize+1, 1) {
+		h := hash(ws[i:min(i+shingleSize, len(ws))])
+		for b := range counts {
+			if h&(1<<b) != 0 {
+				counts[b]++
+			} else {
+				counts[b]--
+			}
+		}
+	}
+	var sim uint64
+	for b, c := range counts {
+		if c > 0 {
+			sim |= 1 << b
+		}
+	}
+	return Fingerprint{Exact: hash(ws), Sim: sim}
+}
+
+// hash returns a well-mixed 64-bit hash of a sequence of words.
+func hash(ws []string) uint64 {
+	h := fnv.New64a()
+	for _, w := range ws {
+		h.Write([]byte(w))
+		h.Write([]byte{0})
+	}
+	// FNV's high bits depend weakly on the last bytes; mix them in
+	// (the splitmix64 finalizer) so every SimHash bit is fair
+	v := h.Sum64()
+	v ^= v >> 30
+	v *= 0xbf58476d1ce4e5b9
+	v ^= v >> 27
+	v *= 0x94d049bb133111eb
+	v ^= v >> 31
+	return v
+}
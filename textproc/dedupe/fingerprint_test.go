@@ -0,0 +1,51 @@
+package dedupe
+
+import (
+	"testing"
+)
+
+const disclaimer = "This email and any attachments are confidential and may be privileged. " +
+	"If you are not the intended recipient, please notify the sender immediately and delete it. " +
+	"Any unauthorised use, disclosure or copying is strictly prohibited."
+
+func TestNewFingerprintNormalizes(t *testing.T) {
+	a := NewFingerprint("Hello,   World! How are you?")
+	b := NewFingerprint("hello world\nhow ARE you")
+	if a != b {
+		t.Errorf("expected equal fingerprints, got %+v and %+v", a, b)
+	}
+}
+
+func TestFingerprintNearDuplicate(t *testing.T) {
+	a := NewFingerprint(disclaimer)
+	b := NewFingerprint(disclaimer[:len(disclaimer)-len("prohibited.")] + "forbidden.")
+	if a.Exact == b.Exact {
+		t.Error("expected different exact hashes")
+	}
+	if d := a.Distance(b); d > DefaultMaxDistance {
+		t.Errorf("expected a near duplicate, got distance %d", d)
+	}
+}
+
+func TestFingerprintDistinct(t *testing.T) {
+	a := NewFingerprint(disclaimer)
+	b := NewFingerprint("The quarterly report is attached. Revenue grew in every region " +
+		"except the north, where the new warehouse delayed shipments for most of March.")
+	if d := a.Distance(b); d <= DefaultMaxDistance {
+		t.Errorf("expected distinct texts to be far apart, got distance %d", d)
+	}
+}
+
+func TestWords(t *testing.T) {
+	got := words("Don't PANIC — it's 42°C.")
+	want := []string{"don", "t", "panic", "it", "s", "42", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %q, got %q", want, got)
+			break
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package dedupe
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Store remembers the fingerprints of chunks already seen.
+type Store interface {
+	// Observe records f and reports whether it duplicates a fingerprint
+	// recorded before: an equal Exact hash, or a Sim hash within the
+	// store's distance.
+	Observe(f Fingerprint) (bool, error)
+}
+
+// MemoryStore is a Store held in memory. It is safe for concurrent use.
+//
+// Near duplicates are found by splitting each SimHash into maxDistance+1
+// bands: two hashes within maxDistance bits agree exactly on at least one
+// band, so only fingerprints sharing a band are compared.
+//
+// Without WithCapacity a MemoryStore keeps every fingerprint it is given,
+// so it suits one document or one batch. A store shared for the life of a
+// process should have a capacity, or be Reset from time to time.
+type MemoryStore struct {
+	mu          sync.Mutex
+	maxDistance int
+	capacity    int                      // Most fingerprints kept, or zero for no limit
+	exact       map[uint64]*list.Element // Entries by Exact hash
+	recent      list.List                // Fingerprints, most recently seen first
+	bands       []map[uint64][]uint64    // Sim hashes by the value of each band
+	width       int                      // Bits in each band but the last
+}
+
+// StoreOption configures a MemoryStore.
+type StoreOption func(*MemoryStore)
+
+// WithCapacity keeps at most n fingerprints, forgetting the least recently
+// seen first. Zero, the default, means no limit.
+func WithCapacity(n int) StoreOption {
+	return func(s *MemoryStore) {
+		s.capacity = max(n, 0)
+	}
+}
+
+// NewMemoryStore creates an empty MemoryStore matching SimHashes within
+// maxDistance bits. A negative maxDistance matches exact duplicates only.
+func NewMemoryStore(maxDistance int, opts ...StoreOption) *MemoryStore {
+	s := &MemoryStore{
+		maxDistance: min(maxDistance, 63),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.maxDistance >= 0 {
+		s.bands = make([]map[uint64][]uint64, s.maxDistance+1)
+		s.width = 64 / len(s.bands)
+	}
+	s.reset()
+	return s
+}
+
+// Reset forgets every fingerprint recorded.
+func (s *MemoryStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+}
+
+// reset empties the store. The caller holds mu, or is the constructor.
+func (s *MemoryStore) reset() {
+	s.exact = map[uint64]*list.Element{}
+	s.recent.Init()
+	for i := range s.bands {
+		s.bands[i] = map[uint64][]uint64{}
+	}
+}
+
+// Observe records f and reports whether it duplicates a fingerprint
+// recorded before. It never returns an error.
+func (s *MemoryStore) Observe(f Fingerprint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.exact[f.Exact]; ok {
+		s.recent.MoveToFront(e)
+		return true, nil
+	}
+	s.exact[f.Exact] = s.recent.PushFront(f)
+
+	near := false
+	for i, band := range s.bands {
+		key := s.band(i, f.Sim)
+		for _, sim := range band[key] {
+			if (Fingerprint{Sim: sim}).Distance(f) <= s.maxDistance {
+				near = true
+				break
+			}
+		}
+		band[key] = append(band[key], f.Sim)
+	}
+
+	if s.capacity > 0 && s.recent.Len() > s.capacity {
+		s.forget(s.recent.Back())
+	}
+	return near, nil
+}
+
+// forget removes the fingerprint in e.
+func (s *MemoryStore) forget(e *list.Element) {
+	f := s.recent.Remove(e).(Fingerprint)
+	delete(s.exact, f.Exact)
+	for i, band := range s.bands {
+		key := s.band(i, f.Sim)
+		sims := band[key]
+		for j, sim := range sims {
+			if sim == f.Sim {
+				sims = append(sims[:j], sims[j+1:]...)
+				break
+			}
+		}
+		if len(sims) == 0 {
+			delete(band, key)
+		} else {
+			band[key] = sims
+		}
+	}
+}
+
+// Len returns the number of distinct texts recorded and still kept.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.exact)
+}
+
+// band returns the value of band i of sim. The last band takes any bits
+// left over.
+func (s *MemoryStore) band(i int, sim uint64) uint64 {
+	sim >>= i * s.width
+	if i == len(s.bands)-1 {
+		return sim
+	}
+	return sim & (1<<s.width - 1)
+}
+
+// Ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
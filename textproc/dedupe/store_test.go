@@ -0,0 +1,101 @@
+package dedupe
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestMemoryStoreExact(t *testing.T) {
+	s := NewMemoryStore(-1)
+	f := Fingerprint{Exact: 1, Sim: 0xff}
+	if dup, _ := s.Observe(f); dup {
+		t.Error("expected the first observation to be new")
+	}
+	if dup, _ := s.Observe(f); !dup {
+		t.Error("expected the second observation to be a duplicate")
+	}
+	if dup, _ := s.Observe(Fingerprint{Exact: 2, Sim: 0xff}); dup {
+		t.Error("expected no near matching with a negative distance")
+	}
+}
+
+func TestMemoryStoreNear(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, maxDistance := range []int{0, 3, 10, 62} {
+		for trial := range 100 {
+			sim := r.Uint64()
+			bits := r.Perm(64)
+			for _, distance := range []int{maxDistance, maxDistance + 1} {
+				s := NewMemoryStore(maxDistance)
+				s.Observe(Fingerprint{Exact: 1, Sim: sim})
+				flipped := sim
+				for _, b := range bits[:distance] {
+					flipped ^= 1 << b
+				}
+				dup, _ := s.Observe(Fingerprint{Exact: 2, Sim: flipped})
+				if want := distance <= maxDistance; dup != want {
+					t.Fatalf("max %d, trial %d: at distance %d expected duplicate %v, got %v", maxDistance, trial, distance, want, dup)
+				}
+			}
+		}
+	}
+}
+
+func TestMemoryStoreConcurrent(t *testing.T) {
+	s := NewMemoryStore(DefaultMaxDistance)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				s.Observe(NewFingerprint(string(rune('a'+i)) + " shared text number " + string(rune('a'+j%26))))
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.Len(); got == 0 || got > 8*26 {
+		t.Errorf("expected at most %d distinct texts, got %d", 8*26, got)
+	}
+}
+
+func TestMemoryStoreCapacity(t *testing.T) {
+	s := NewMemoryStore(3, WithCapacity(2))
+	a := Fingerprint{Exact: 1, Sim: 0x0f}
+	b := Fingerprint{Exact: 2, Sim: 0xf000}
+	c := Fingerprint{Exact: 3, Sim: 0xf0000000}
+	s.Observe(a)
+	s.Observe(b)
+	s.Observe(a) // a is now the most recent
+	s.Observe(c) // Forgets b
+	if got := s.Len(); got != 2 {
+		t.Fatalf("expected 2 fingerprints kept, got %d", got)
+	}
+	if dup, _ := s.Observe(Fingerprint{Exact: 4, Sim: 0x0e}); !dup {
+		t.Error("expected a near duplicate of the recently seen a")
+	}
+	if dup, _ := s.Observe(b); dup {
+		t.Error("expected b to be forgotten")
+	}
+	for i, band := range s.bands {
+		for key, sims := range band {
+			if len(sims) == 0 {
+				t.Errorf("band %d keeps an empty entry for %x", i, key)
+			}
+		}
+	}
+}
+
+func TestMemoryStoreReset(t *testing.T) {
+	s := NewMemoryStore(DefaultMaxDistance)
+	f := NewFingerprint("a paragraph that will be seen twice")
+	s.Observe(f)
+	s.Reset()
+	if s.Len() != 0 {
+		t.Errorf("expected an empty store, got %d", s.Len())
+	}
+	if dup, _ := s.Observe(f); dup {
+		t.Error("expected the fingerprint to be forgotten")
+	}
+}
@@ -2,6 +2,8 @@
 //
 // The processing chain flows: htmlStripper -> elider -> chunker -> splitter -> combiner.
 // Each processor is memory-efficient (designed for Lambda constraints) and independently usable.
+// Package chain assembles the processors, and package pool runs chains over
+// many documents concurrently.
 //
 // Core types:
 //   - Chunk: a unit of text ([]byte)
@@ -11,4 +13,29 @@
 //   - BytesProcessor: reads bytes via io.Reader, writes []byte blocks
 //   - ChunkProcessor: reads chunks, writes chunks
 //   - ChunkCombiner: reads chunks, writes chunk slices
+//
+// Context-aware variants (ContextBytesProcessor, ContextStringProcessor,
+// ContextChunkProcessor, ContextChunkCombiner) add NextContext, which stops
+// with ctx.Err() once the context is done. NextBytesContext and friends call
+// any processor with a context, checking it first for processors without.
+//
+// Iterators: All, AllChunks, AllStrings and AllBytes range over a
+// processor's output, FromSeq and FromSeq2 turn an iterator into a
+// processor, and CollectAll gathers a sequence with a cap on its length:
+//
+//	for slice, err := range textproc.All(c) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    ...
+//	}
+//
+// Counter measures text for the splitter and combiner limits; RuneCounter,
+// ByteCounter and WordCounter are built in, and package bpe counts model
+// tokens.
+//
+// Offset tracking, used to locate chunks in the original text:
+//   - Span: a byte range in a stream
+//   - OffsetMap, OffsetMapper: map a string processor's output offsets to its input
+//   - SpanChunkProcessor, SpanChunkCombiner: report the span of each chunk
 package textproc
@@ -1,3 +1,28 @@
 // Package elider performs content elision.
-// For initial stub: passes bytes through unmodified.
+//
+// It removes noise that does not help describe a message: URLs are reduced
+// to their domain, and UUIDs, long hex strings and version numbers are
+// dropped. Quoted replies and signatures are recognised line by line:
+//   - lines starting with ">" are removed
+//   - a reply attribution ("On ... wrote:", "Am ... schrieb ...:",
+//     "Le ... a écrit :" and equivalents in other languages), an
+//     "-----Original Message-----" separator, or a block of reply headers
+//     (a From line followed by Sent, To, Subject or similar, in several
+//     languages) elides the rest of the text
+//   - a "-- " signature delimiter or a mobile signature such as "Sent from
+//     my iPhone" elides the rest of the text
+//
+// Each of these rules can be turned off with WithRules or WithoutRules, the
+// pattern rules can write a token such as "[UUID]" in place of what they
+// remove (WithReplacement), and further regex rules can be added with
+// WithCustomRule. Report describes what each rule removed, with byte ranges
+// into the elider's input.
+//
+// Markers are only recognised at the start of a line, and a single header-like
+// line such as "Subject: ..." in the body is kept.
+//
+// The built-in rules are matched by hand in time linear in the input, so
+// long runs of hex digits or text without whitespace cannot slow eliding
+// down. Custom rules are only tried at the current position, but a costly
+// pattern can still make them slow.
 package elider
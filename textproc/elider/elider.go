@@ -34,11 +34,26 @@ type Processor struct {
 	skipURLContent bool // true when we've extracted domain and need to skip path/params
 
 	// Non-word filtering
-	uuidPattern    *regexp.Regexp
-	hexPattern     *regexp.Regexp
-	versionPattern *regexp.Regexp
-
-	done bool
+	hexUntil int64 // Stream offset before which no hex string can start
+
+	// Rule configuration
+	disabled     map[Rule]bool
+	replacements map[Rule]string
+	custom       []customRule
+
+	// Reporting
+	report    map[Rule]*RuleReport
+	pos       int64 // Stream offset of input[0]
+	urlStart  int64 // Stream offset of the URL part being removed
+	elideRule Rule  // Rule eliding the rest of the input in quote or signature mode
+
+	// Offset mapping
+	offsets textproc.OffsetMap
+	outBase int64 // Stream offset of output[0]
+
+	atLineStart bool // Next input byte starts a line
+	eof         bool // Source is exhausted
+	done        bool
 }
 
 // Option configures a Processor.
@@ -55,11 +70,9 @@ func WithBlockSize(n int) Option {
 // This enables pull-based lazy evaluation.
 func NewProcessor(src textproc.StringProcessor, opts ...Option) *Processor {
 	p := &Processor{
-		src:            src,
-		blockSize:      1024,
-		uuidPattern:    regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`),
-		hexPattern:     regexp.MustCompile(`\b[0-9a-fA-F]{16,}\b`),
-		versionPattern: regexp.MustCompile(`\b[vV]\d+(?:\.\d+)*\b`),
+		src:         src,
+		blockSize:   1024,
+		atLineStart: true,
 	}
 	for _, opt := range opts {
 		opt(p)
@@ -73,7 +86,10 @@ func (p *Processor) Next() (string, error) {
 	for {
 		// If we have enough output, return it
 		if p.output.Len() >= p.blockSize {
-			result := strings.TrimRight(p.output.String(), " \t\n\r")
+			// Only the end of the text is trimmed; whitespace here may
+			// separate this block from the next
+			result := p.output.String()
+			p.outBase += int64(len(result))
 			p.output.Reset()
 			return result, nil
 		}
@@ -83,22 +99,29 @@ func (p *Processor) Next() (string, error) {
 			return "", io.EOF
 		}
 
-		// Read more input
-		if p.input == "" {
+		// Read more input, buffering whole lines at the start of a line so
+		// they can be classified
+		if (p.input == "" || p.needLookahead()) && !p.eof {
 			block, err := p.src.Next()
 			if err == io.EOF {
-				p.done = true
-				if p.output.Len() > 0 {
-					result := strings.TrimRight(p.output.String(), " \t\n\r")
-					p.output.Reset()
-					return result, nil
-				}
-				return "", io.EOF
+				p.eof = true
+				continue
 			}
 			if err != nil {
 				return "", err
 			}
-			p.input = block
+			p.input += block
+			continue
+		}
+		if p.input == "" {
+			p.done = true
+			p.finish()
+			if p.output.Len() > 0 {
+				result := strings.TrimRight(p.output.String(), " \t\n\r")
+				p.output.Reset()
+				return result, nil
+			}
+			return "", io.EOF
 		}
 
 		// Process input
@@ -113,9 +136,10 @@ func (p *Processor) processInput() {
 		for p.input != "" {
 			if p.input[0] == ' ' || p.input[0] == '\t' || p.input[0] == '\n' || p.input[0] == '\r' {
 				p.skipURLContent = false
+				p.extend(RuleURL, p.urlStart, p.pos)
 				return
 			}
-			p.input = p.input[1:]
+			p.advance(1)
 		}
 		// Exhausted all input, continue skipping in next block
 		return
@@ -123,7 +147,7 @@ func (p *Processor) processInput() {
 
 	for p.input != "" {
 		// Re-check skipURLContent in case it was set during loop iteration
-		if p.skipURLContent {
+		if p.skipURLContent || p.needLookahead() {
 			return
 		}
 		switch p.mode {
@@ -133,106 +157,191 @@ func (p *Processor) processInput() {
 			p.processURL()
 		case modeQuote, modeSignature:
 			// In quote or signature mode, elide all content
-			p.input = ""
+			p.advance(len(p.input))
 		}
 	}
 }
 
-// processNormal handles normal mode processing
-func (p *Processor) processNormal() {
-	// Check for URL pattern http:// or https://
-	if len(p.input) >= 7 && p.input[:7] == "http://" {
-		p.mode = modeURL
-		p.input = p.input[7:]
-		return
-	}
-	if len(p.input) >= 8 && p.input[:8] == "https://" {
-		p.mode = modeURL
-		p.input = p.input[8:]
-		return
+// needLookahead reports whether a line is about to be classified but fewer
+// than lookaheadLines complete lines are buffered.
+func (p *Processor) needLookahead() bool {
+	if p.eof || !p.atLineStart || p.mode != modeNormal || p.skipURLContent {
+		return false
 	}
+	return !hasLines(p.input, lookaheadLines) && len(p.input) < maxLookahead
+}
 
-	// Check for signature delimiter "--" on its own line
-	if len(p.input) >= 3 && p.input[:3] == "--\n" {
+// processLineStart classifies the line at the start of the input, and
+// returns true if it was elided.
+func (p *Processor) processLineStart() bool {
+	p.atLineStart = false
+	switch kind := classifyLine(p.input); {
+	case kind == lineQuoted && p.enabled(RuleQuotedLine):
+		// Skip the quoted line including its newline
+		n := len(p.input)
+		if newlineIdx := strings.Index(p.input, "\n"); newlineIdx != -1 {
+			n = newlineIdx + 1
+		}
+		p.record(RuleQuotedLine, p.pos, p.pos+int64(n))
+		p.advance(n)
+		p.atLineStart = true
+		return true
+	case kind == lineQuote && p.enabled(RuleReply):
+		p.mode = modeQuote
+		p.elideRest(RuleReply)
+		return true
+	case kind == lineSignature && p.enabled(RuleSignature):
 		p.mode = modeSignature
-		p.input = "" // Elide all remaining input
-		return
+		p.elideRest(RuleSignature)
+		return true
 	}
+	return false
+}
 
-	// Check for email quote lines starting with >
-	// A > at the start of input or after a newline indicates quoted text
-	if len(p.input) >= 1 && p.input[0] == '>' {
-		// Skip everything until the next newline
-		newlineIdx := strings.Index(p.input, "\n")
-		if newlineIdx == -1 {
-			// No newline in this block, skip all remaining input
-			p.input = ""
-		} else {
-			// Skip past the newline
-			p.input = p.input[newlineIdx+1:]
-		}
+// processNormal handles normal mode processing
+func (p *Processor) processNormal() {
+	// Quotes, reply headers and signatures are recognised by whole lines
+	if p.atLineStart && p.processLineStart() {
 		return
 	}
 
-	// Check for quote markers
-	// RFC 5322: "On [date] [name] wrote:" pattern
-	if len(p.input) >= 3 && p.input[:3] == "On " {
-		// Look for "wrote:" after this
-		wroteIdx := strings.Index(p.input, " wrote:")
-		if wroteIdx > 0 && wroteIdx < 200 { // Reasonable limit for email headers
-			p.mode = modeQuote
-			p.input = "" // Elide all remaining input
-			return
+	// Check for URL pattern http:// or https://
+	if p.enabled(RuleURL) {
+		for _, scheme := range []string{"http://", "https://"} {
+			if strings.HasPrefix(p.input, scheme) {
+				p.mode = modeURL
+				p.urlStart = p.pos
+				p.record(RuleURL, p.pos, p.pos+int64(len(scheme)))
+				p.advance(len(scheme))
+				return
+			}
 		}
 	}
 
-	// Common quote markers
-	quoteMarkers := []string{
-		"-----Original Message-----",
-		"From:",
-		"Sent:",
-		"To:",
-		"Subject:",
-		"Cc:",
-	}
-	for _, marker := range quoteMarkers {
-		if len(p.input) >= len(marker) && p.input[:len(marker)] == marker {
-			p.mode = modeQuote
-			p.input = "" // Elide all remaining input
+	// Custom rules replace their matches
+	for _, rule := range p.custom {
+		if p.elidePattern(rule.name, rule.pattern, rule.replacement, true) {
 			return
 		}
 	}
 
 	// Check for UUID pattern and skip it
-	uuidIdx := p.uuidPattern.FindStringIndex(p.input)
-	if uuidIdx != nil && uuidIdx[0] == 0 {
-		// Skip the UUID and any trailing whitespace
-		p.input = p.input[uuidIdx[1]:]
-		p.skipWhitespace()
-		return
+	if p.enabled(RuleUUID) {
+		if n := matchUUID(p.input); n > 0 {
+			p.elide(RuleUUID, n, p.replacements[RuleUUID], false)
+			return
+		}
 	}
 
 	// Check for hex string pattern (16+ consecutive hex chars) and skip it
-	hexIdx := p.hexPattern.FindStringIndex(p.input)
-	if hexIdx != nil && hexIdx[0] == 0 {
-		// Skip the hex string and any trailing whitespace
-		p.input = p.input[hexIdx[1]:]
-		p.skipWhitespace()
-		return
+	if p.enabled(RuleHex) && p.pos >= p.hexUntil {
+		n, run := matchHex(p.input)
+		if n > 0 {
+			p.elide(RuleHex, n, p.replacements[RuleHex], false)
+			return
+		}
+		if run < len(p.input) {
+			// No hex string starts inside a run that failed to match
+			p.hexUntil = p.pos + int64(run)
+		}
 	}
 
 	// Check for version string pattern and skip it
-	versionIdx := p.versionPattern.FindStringIndex(p.input)
-	if versionIdx != nil && versionIdx[0] == 0 {
-		// Skip the version string and any trailing whitespace
-		p.input = p.input[versionIdx[1]:]
-		p.skipWhitespace()
-		return
+	if p.enabled(RuleVersion) {
+		if n := matchVersion(p.input); n > 0 {
+			p.elide(RuleVersion, n, p.replacements[RuleVersion], false)
+			return
+		}
 	}
 
 	// Copy character to output
+	if p.input[0] == '\n' {
+		p.atLineStart = true
+	}
+	p.offsets.Add(p.outPos(), p.pos)
 	p.output.WriteByte(p.input[0])
-	p.input = p.input[1:]
+	p.advance(1)
+}
+
+// elidePattern removes a match of pattern at the start of the input,
+// writing replacement in its place. Without a replacement, whitespace after
+// the match is also removed unless keepSpace is set. It returns false if
+// the pattern does not match at the start of the input.
+func (p *Processor) elidePattern(rule Rule, pattern *regexp.Regexp, replacement string, keepSpace bool) bool {
+	idx := pattern.FindStringIndex(p.input)
+	if idx == nil || idx[0] != 0 || idx[1] == 0 {
+		return false
+	}
+	p.elide(rule, idx[1], replacement, keepSpace)
+	return true
+}
+
+// elide removes the n bytes at the start of the input, as elidePattern
+// does for a match.
+func (p *Processor) elide(rule Rule, n int, replacement string, keepSpace bool) {
+	p.record(rule, p.pos, p.pos+int64(n))
+	p.offsets.Add(p.outPos(), p.pos)
+	if strings.Contains(p.input[:n], "\n") {
+		p.atLineStart = strings.HasSuffix(p.input[:n], "\n")
+	}
+	p.advance(n)
+	if replacement != "" {
+		p.output.WriteString(replacement)
+	} else if !keepSpace {
+		// Skip any trailing whitespace
+		p.skipWhitespace()
+	}
+}
+
+// elideRest removes the rest of the input, crediting it to rule.
+func (p *Processor) elideRest(rule Rule) {
+	p.elideRule = rule
+	p.record(rule, p.pos, p.pos)
+	p.advance(len(p.input))
+}
+
+// finish completes the report at the end of the input.
+func (p *Processor) finish() {
+	if p.mode == modeURL || p.skipURLContent {
+		// The input ended inside a URL
+		p.flushURL()
+		if p.skipURLContent {
+			p.extend(RuleURL, p.urlStart, p.pos)
+		}
+		p.mode = modeNormal
+		p.skipURLContent = false
+	}
+	if p.elideRule != "" {
+		r := p.report[p.elideRule]
+		r.Ranges[len(r.Ranges)-1].End = p.pos
+		p.elideRule = ""
+	}
+}
+
+// flushURL writes the domain of the URL that ends at the current input.
+func (p *Processor) flushURL() {
+	if p.mode == modeURL {
+		p.offsets.Add(p.outPos(), p.pos-int64(p.urlBuf.Len()))
+	}
+	p.output.WriteString(p.urlBuf.String())
+	p.urlBuf.Reset()
+}
+
+// outPos returns the stream offset of the next output byte.
+func (p *Processor) outPos() int64 {
+	return p.outBase + int64(p.output.Len())
+}
+
+// Offsets maps offsets in the elided output back to the input. Inserted
+// replacement tokens map to the start of the text they replaced.
+func (p *Processor) Offsets() *textproc.OffsetMap {
+	return &p.offsets
+}
+
+// advance consumes n bytes of input.
+func (p *Processor) advance(n int) {
+	p.input = p.input[n:]
+	p.pos += int64(n)
 }
 
 // processURL handles URL mode processing
@@ -243,19 +352,25 @@ func (p *Processor) processURL() {
 	}
 
 	c := p.input[0]
-	if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '?' || c == '#' || c == '/' {
+	if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+		// URL ended at whitespace, which is kept so line breaks survive
+		p.flushURL()
+		p.mode = modeNormal
+		return
+	}
+	if c == '?' || c == '#' || c == '/' {
 		// URL ended, flush domain and return to normal mode
-		p.output.WriteString(p.urlBuf.String())
-		p.urlBuf.Reset()
+		p.flushURL()
 		p.mode = modeNormal
 		// Skip the current character (/ ? #) and set flag to skip rest of URL content
-		p.input = p.input[1:]
+		p.urlStart = p.pos
+		p.advance(1)
 		p.skipURLContent = true
 		return
 	}
 
 	p.urlBuf.WriteByte(c)
-	p.input = p.input[1:]
+	p.advance(1)
 }
 
 // skipWhitespace skips leading whitespace in the input
@@ -265,9 +380,15 @@ func (p *Processor) skipWhitespace() {
 		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
 			break
 		}
-		p.input = p.input[1:]
+		if c == '\n' {
+			p.atLineStart = true
+		}
+		p.advance(1)
 	}
 }
 
-// Ensure Processor implements StringProcessor
-var _ textproc.StringProcessor = (*Processor)(nil)
+// Ensure Processor implements StringProcessor and OffsetMapper
+var (
+	_ textproc.StringProcessor = (*Processor)(nil)
+	_ textproc.OffsetMapper    = (*Processor)(nil)
+)
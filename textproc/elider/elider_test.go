@@ -4,6 +4,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
 	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
@@ -393,3 +394,167 @@ func TestElideEmailQuotesWithTrailingWhitespace(t *testing.T) {
 		t.Errorf("expected io.EOF, got %v", err)
 	}
 }
+
+// readAllElided runs the elider over the given blocks and returns all output.
+func readAllElided(t *testing.T, blocks ...string) string {
+	t.Helper()
+	p := NewProcessor(&mockStringSource{blocks: blocks})
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+func TestElideMultilingualAttribution(t *testing.T) {
+	tests := []string{
+		"On Mon, Jan 1, 2024 at 10:00 AM John Doe <john@example.com> wrote:",
+		"Am Mo., 1. Jan. 2024 um 10:00 Uhr schrieb John Doe <john@example.com>:",
+		"Le lun. 1 janv. 2024 à 10:00, John Doe <john@example.com> a écrit :",
+		"El lun, 1 ene 2024 a las 10:00, John Doe (<john@example.com>) escribió:",
+		"Em seg., 1 de jan. de 2024 às 10:00, John Doe <john@example.com> escreveu:",
+		"Il giorno lun 1 gen 2024 alle ore 10:00 John Doe <john@example.com> ha scritto:",
+		"Op ma 1 jan. 2024 om 10:00 schreef John Doe <john@example.com>:",
+		"Den mån 1 jan. 2024 kl 10:00 skrev John Doe <john@example.com>:",
+		"W dniu 1.01.2024 o 10:00, John Doe pisze napisał:",
+		"1 января 2024 г., в 10:00, Иван <ivan@example.com> написал:",
+		"2024年1月1日(月) 10:00 山田太郎 <taro@example.com>:",
+		"2024/01/01 10:00、山田太郎 <taro@example.com>のメール:",
+		"张三 <zs@example.com> 于2024年1月1日周一 10:00写道：",
+		"2024년 1월 1일 (월) 오전 10:00, 홍길동 <hong@example.com>님이 작성:",
+	}
+	for _, attribution := range tests {
+		got := readAllElided(t, "Reply text\n"+attribution+"\nQuoted text\n")
+		if got != "Reply text" {
+			t.Errorf("%q: expected 'Reply text', got %q", attribution, got)
+		}
+	}
+}
+
+func TestElideWrappedAttribution(t *testing.T) {
+	got := readAllElided(t, "Reply text\nOn Mon, Jan 1, 2024 at 10:00 AM John Doe <\njohn@example.com> wrote:\nQuoted\n")
+	if got != "Reply text" {
+		t.Errorf("expected 'Reply text', got %q", got)
+	}
+}
+
+func TestElideAttributionSplitAcrossBlocks(t *testing.T) {
+	got := readAllElided(t, "Reply text\nAm 01.01.2024 um 10:00 sch", "rieb John Doe:\nQuoted\n")
+	if got != "Reply text" {
+		t.Errorf("expected 'Reply text', got %q", got)
+	}
+}
+
+func TestElideOriginalMessageSeparator(t *testing.T) {
+	for _, sep := range []string{"-----Original Message-----", "-----Ursprüngliche Nachricht-----", "----- Message d'origine -----"} {
+		got := readAllElided(t, "Reply text\n"+sep+"\nQuoted\n")
+		if got != "Reply text" {
+			t.Errorf("%q: expected 'Reply text', got %q", sep, got)
+		}
+	}
+}
+
+func TestElideHeaderBlock(t *testing.T) {
+	tests := []string{
+		"From: Bob <bob@example.com>\nSent: Monday, 1 January 2024 10:00\nTo: Alice\nSubject: Hi\n",
+		"Von: Bob <bob@example.com>\nGesendet: Montag, 1. Januar 2024 10:00\n",
+		"*De :* Bob\n*Envoyé :* lundi 1 janvier 2024\n",
+		"差出人: Bob\n送信日時: 2024年1月1日\n",
+	}
+	for _, block := range tests {
+		got := readAllElided(t, "Reply text\n\n"+block+"Quoted\n")
+		if got != "Reply text" {
+			t.Errorf("%q: expected 'Reply text', got %q", block, got)
+		}
+	}
+}
+
+func TestElideKeepsLoneHeaderLikeLines(t *testing.T) {
+	input := "Please send it\nTo: the whole team\nSubject: is the budget\nFrom: my notes, the total is up.\nThanks"
+	if got := readAllElided(t, input); got != input {
+		t.Errorf("expected text kept, got %q", got)
+	}
+}
+
+func TestElideMobileSignature(t *testing.T) {
+	for _, sig := range []string{"Sent from my iPhone", "Von meinem iPhone gesendet", "Envoyé de mon iPad", "Get Outlook for Android", "iPhoneから送信"} {
+		got := readAllElided(t, "See you there\n\n"+sig+"\n\nOn Mon, Bob wrote:\nQuoted\n")
+		if got != "See you there" {
+			t.Errorf("%q: expected 'See you there', got %q", sig, got)
+		}
+	}
+}
+
+func TestElideRFCSignatureDelimiter(t *testing.T) {
+	got := readAllElided(t, "Message body\n-- \nJohn Doe\n")
+	if got != "Message body" {
+		t.Errorf("expected 'Message body', got %q", got)
+	}
+}
+
+func TestElideMarkersOnlyAtLineStart(t *testing.T) {
+	input := "If a > b then stop--\nThe subject is On wrote: things. From: me"
+	if got := readAllElided(t, input); got != input {
+		t.Errorf("expected text kept, got %q", got)
+	}
+}
+
+func TestElideQuoteAfterURL(t *testing.T) {
+	got := readAllElided(t, "See https://example.com/page\n> quoted\nEnd")
+	if got != "See example.com\nEnd" {
+		t.Errorf("expected 'See example.com\\nEnd', got %q", got)
+	}
+}
+
+func TestElideKeepsWhitespaceBetweenBlocks(t *testing.T) {
+	p := NewProcessor(&mockStringSource{blocks: []string{"Hello\nWorld"}}, WithBlockSize(6))
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.WriteString(s)
+	}
+	if out.String() != "Hello\nWorld" {
+		t.Errorf("expected 'Hello\\nWorld', got %q", out.String())
+	}
+}
+
+func TestElidePathologicalInputIsLinear(t *testing.T) {
+	tests := map[string]string{
+		"no whitespace": strings.Repeat("x", 1<<20),
+		"hex run":       "Key: " + strings.Repeat("0123456789abcdef", 1<<16) + "_",
+		"short lines":   strings.Repeat("x\n", 1<<19),
+		"versions":      strings.Repeat("v1.", 1<<18) + "x",
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			var blocks []string
+			for i := 0; i < len(input); i += 4096 {
+				blocks = append(blocks, input[i:min(i+4096, len(input))])
+			}
+			start := time.Now()
+			readAllElided(t, blocks...)
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("eliding took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestElideHexRunFollowedByWordCharacter(t *testing.T) {
+	input := "id 0123456789abcdef0123456789abcdef_x end"
+	if got := readAllElided(t, input); got != input {
+		t.Errorf("expected %q unchanged, got %q", input, got)
+	}
+}
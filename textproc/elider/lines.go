@@ -0,0 +1,184 @@
+package elider
+
+import (
+	"regexp"
+	"strings"
+)
+
+// lookaheadLines is how many complete lines are buffered before a line is
+// classified. Attribution lines are often wrapped onto a second line, and a
+// header block needs at least two lines to be recognised.
+const lookaheadLines = 2
+
+// maxLookahead bounds the bytes buffered while looking for complete lines.
+const maxLookahead = 4096
+
+// maxMarkerLine is the longest line considered as an attribution or header.
+const maxMarkerLine = 500
+
+// space matches whitespace including the no-break spaces French uses before
+// a colon.
+const space = `[\s\x{00a0}\x{202f}]`
+
+// attributionPatterns match the "On <date>, <name> wrote:" line that mail
+// clients put before a quoted reply, in several languages. They are matched
+// against a line, and against a line joined with the next, since long
+// attributions are often wrapped.
+var attributionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^on` + space + `.{1,250}` + space + `wrote:$`),                              // English
+	regexp.MustCompile(`(?i)^am` + space + `.{1,250}` + space + `schrieb.{0,250}:$`),                    // German
+	regexp.MustCompile(`(?i)^le` + space + `.{1,250}` + space + `a` + space + `+écrit` + space + `*:$`), // French
+	regexp.MustCompile(`(?i)^el` + space + `.{1,250}` + space + `escribió:$`),                           // Spanish
+	regexp.MustCompile(`(?i)^(em|no dia)` + space + `.{1,250}` + space + `escreveu:$`),                  // Portuguese
+	regexp.MustCompile(`(?i)^il` + space + `.{1,250}` + space + `ha` + space + `scritto:$`),             // Italian
+	regexp.MustCompile(`(?i)^op` + space + `.{1,250}` + space + `schreef.{0,250}:$`),                    // Dutch
+	regexp.MustCompile(`(?i)^den` + space + `.{1,250}` + space + `skrev.{0,250}:$`),                     // Scandinavian
+	regexp.MustCompile(`(?i)^(w dniu|dnia)` + space + `.{1,250}` + space + `napisał(a|\(a\))?:$`),       // Polish
+	regexp.MustCompile(`(?i)^.{1,250}` + space + `(пишет|написал|написала|написал\(а\)):$`),             // Russian
+	regexp.MustCompile(`^\d{4}年\d{1,2}月\d{1,2}日.{1,250}[:：]$`),                                          // Japanese and Chinese dates
+	regexp.MustCompile(`^.{1,250}のメール[:：]$`),                                                            // Japanese
+	regexp.MustCompile(`^.{1,250}(写道|寫道)[:：]$`),                                                         // Chinese
+	regexp.MustCompile(`^.{1,250}작성[:：]$`),                                                              // Korean
+}
+
+// separatorPattern matches the "-----Original Message-----" line Outlook
+// and others put before a quoted message.
+var separatorPattern = regexp.MustCompile(`(?i)^-{2,}` + space + `*(original message|ursprüngliche nachricht|message d'origine|mensaje original|messaggio originale|mensagem original|oorspronkelijk bericht|ursprungligt meddelande|oprindelig meddelelse|opprinnelig melding|исходное сообщение|wiadomość oryginalna|元のメッセージ|原始邮件)` + space + `*-{2,}$`)
+
+// mobileSignaturePatterns match the one-line signatures added by mobile mail
+// apps.
+var mobileSignaturePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^(sent from|sent with|sent via|get outlook for|envoyé de|envoyé depuis|enviado desde|enviado do|enviado de|inviato da|verzonden met|verstuurd vanaf|skickat från|sendt fra|wysłane z|отправлено с) .{1,60}$`),
+	regexp.MustCompile(`(?i)^von meinem .{1,40} gesendet$`),
+	regexp.MustCompile(`^.{1,30}から送信$`),
+	regexp.MustCompile(`^(发自我的|發自我的).{1,30}$`),
+}
+
+// headerPattern splits a "Name: value" line, allowing bold markers around
+// the name and a space before the colon.
+var headerPattern = regexp.MustCompile(`^\*?([^:：*]{1,25}?)\*?` + space + `*[:：]`)
+
+// headerField is the field a reply header line names.
+type headerField int
+
+const (
+	fieldFrom headerField = iota + 1
+	fieldDate
+	fieldTo
+	fieldCc
+	fieldSubject
+)
+
+// headerNames maps header names, in several languages, to their field.
+var headerNames = map[string]headerField{
+	"from": fieldFrom, "von": fieldFrom, "de": fieldFrom, "da": fieldFrom, "van": fieldFrom,
+	"från": fieldFrom, "fra": fieldFrom, "od": fieldFrom, "от": fieldFrom, "差出人": fieldFrom,
+	"发件人": fieldFrom, "寄件者": fieldFrom, "보낸 사람": fieldFrom,
+
+	"sent": fieldDate, "date": fieldDate, "gesendet": fieldDate, "datum": fieldDate, "envoyé": fieldDate,
+	"enviado": fieldDate, "fecha": fieldDate, "inviato": fieldDate, "data": fieldDate, "verzonden": fieldDate,
+	"skickat": fieldDate, "sendt": fieldDate, "wysłano": fieldDate, "отправлено": fieldDate, "送信日時": fieldDate,
+	"日付": fieldDate, "发送时间": fieldDate, "日期": fieldDate, "보낸 날짜": fieldDate,
+
+	"to": fieldTo, "an": fieldTo, "à": fieldTo, "a": fieldTo, "para": fieldTo, "aan": fieldTo,
+	"till": fieldTo, "til": fieldTo, "do": fieldTo, "кому": fieldTo, "宛先": fieldTo, "收件人": fieldTo,
+	"받는 사람": fieldTo,
+
+	"cc": fieldCc, "kopie": fieldCc, "抄送": fieldCc, "참조": fieldCc, "копия": fieldCc,
+
+	"subject": fieldSubject, "betreff": fieldSubject, "objet": fieldSubject, "asunto": fieldSubject,
+	"oggetto": fieldSubject, "assunto": fieldSubject, "onderwerp": fieldSubject, "ämne": fieldSubject,
+	"emne": fieldSubject, "temat": fieldSubject, "тема": fieldSubject, "件名": fieldSubject,
+	"主题": fieldSubject, "主旨": fieldSubject, "제목": fieldSubject,
+}
+
+// lineKind is how a line affects elision.
+type lineKind int
+
+const (
+	lineText      lineKind = iota // Ordinary text
+	lineQuoted                    // A "> " quoted line, elided on its own
+	lineQuote                     // Starts a quoted message; the rest is elided
+	lineSignature                 // Starts a signature; the rest is elided
+)
+
+// classifyLine decides how the line at the start of text affects elision.
+// text holds the line and, where available, those after it.
+func classifyLine(text string) lineKind {
+	lines := strings.SplitN(text, "\n", lookaheadLines+1)
+	if len(lines) > lookaheadLines {
+		lines = lines[:lookaheadLines]
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	line := lines[0]
+
+	if strings.HasPrefix(line, ">") {
+		return lineQuoted
+	}
+	if strings.TrimRight(line, " \t") == "--" {
+		return lineSignature
+	}
+
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || len(trimmed) > maxMarkerLine {
+		return lineText
+	}
+	for _, re := range mobileSignaturePatterns {
+		if re.MatchString(trimmed) {
+			return lineSignature
+		}
+	}
+	if separatorPattern.MatchString(trimmed) {
+		return lineQuote
+	}
+	if isAttribution(trimmed) {
+		return lineQuote
+	}
+	if len(lines) > 1 {
+		next := strings.TrimSpace(lines[1])
+		// If the next line is an attribution by itself, this line is not
+		// part of it
+		if next != "" && len(next) <= maxMarkerLine && !isAttribution(next) && isAttribution(trimmed+" "+next) {
+			return lineQuote
+		}
+	}
+	if isHeaderBlock(lines) {
+		return lineQuote
+	}
+	return lineText
+}
+
+func isAttribution(line string) bool {
+	for _, re := range attributionPatterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHeaderBlock reports whether lines start with a block of reply headers:
+// a From line followed directly by a different header. A lone "To:" or
+// "Subject:" line in the body is not enough.
+func isHeaderBlock(lines []string) bool {
+	if len(lines) < 2 || headerLineField(lines[0]) != fieldFrom {
+		return false
+	}
+	f := headerLineField(lines[1])
+	return f != 0 && f != fieldFrom
+}
+
+// headerLineField returns the field named by a header line, or zero.
+func headerLineField(line string) headerField {
+	line = strings.TrimSpace(line)
+	if len(line) > maxMarkerLine {
+		return 0
+	}
+	m := headerPattern.FindStringSubmatch(line)
+	if m == nil {
+		return 0
+	}
+	return headerNames[strings.ToLower(strings.TrimSpace(m[1]))]
+}
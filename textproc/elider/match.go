@@ -0,0 +1,118 @@
+package elider
+
+import (
+	"strings"
+)
+
+// The built-in rules match by hand rather than by regular expression. Each
+// looks only at the start of the input and a bounded distance into it, or
+// consumes what it scans, so eliding is linear in the input. They match as
+// these expressions would at the start of the input:
+//
+//	UUID:    [0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}
+//	Hex:     \b[0-9a-fA-F]{16,}\b
+//	Version: \b[vV]\d+(?:\.\d+)*\b
+
+// uuidLen is the length of a UUID in its canonical form.
+const uuidLen = 36
+
+// minHexLen is the shortest run of hex digits elided.
+const minHexLen = 16
+
+// matchUUID returns the length of the UUID at the start of s, or 0.
+func matchUUID(s string) int {
+	if len(s) < uuidLen {
+		return 0
+	}
+	for i := 0; i < uuidLen; i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return 0
+			}
+		default:
+			if !isHex(s[i]) {
+				return 0
+			}
+		}
+	}
+	return uuidLen
+}
+
+// matchHex returns the length of the hex string at the start of s, or 0.
+// It also returns the length of the run of hex digits at the start of s:
+// when there is no match, there is none starting inside the run either.
+func matchHex(s string) (n, run int) {
+	for run < len(s) && isHex(s[run]) {
+		run++
+	}
+	if run >= minHexLen && atWordBoundary(s, run) {
+		return run, run
+	}
+	return 0, run
+}
+
+// matchVersion returns the length of the version string at the start of s,
+// or 0. It is the longest run of dot-separated numbers after the "v" that
+// ends at a word boundary.
+func matchVersion(s string) int {
+	if s == "" || (s[0] != 'v' && s[0] != 'V') {
+		return 0
+	}
+	n := 1 + digits(s[1:])
+	if n == 1 {
+		return 0
+	}
+	match := 0
+	for {
+		if atWordBoundary(s, n) {
+			match = n
+		}
+		if n >= len(s) || s[n] != '.' {
+			return match
+		}
+		d := digits(s[n+1:])
+		if d == 0 {
+			return match
+		}
+		n += 1 + d
+	}
+}
+
+// digits returns the length of the run of ASCII digits at the start of s.
+func digits(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// atWordBoundary reports whether s[i] starts a non-word character or the
+// end of s, after a word character, as \b does.
+func atWordBoundary(s string, i int) bool {
+	return i == len(s) || !isWordByte(s[i])
+}
+
+// isHex reports whether b is a hex digit.
+func isHex(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+// isWordByte reports whether b is an ASCII word character, as \w matches.
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// hasLines reports whether s holds at least n line breaks, looking no
+// further than the nth.
+func hasLines(s string, n int) bool {
+	for ; n > 0; n-- {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return false
+		}
+		s = s[i+1:]
+	}
+	return true
+}
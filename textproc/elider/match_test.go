@@ -0,0 +1,100 @@
+package elider
+
+import (
+	"regexp"
+	"testing"
+)
+
+// The expressions the matchers replace, anchored at the start of the input.
+var (
+	uuidRegexp    = regexp.MustCompile(`\A[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	hexRegexp     = regexp.MustCompile(`\A\b[0-9a-fA-F]{16,}\b`)
+	versionRegexp = regexp.MustCompile(`\A\b[vV]\d+(?:\.\d+)*\b`)
+)
+
+// matchLen returns the length of re's match at the start of s, or 0.
+func matchLen(re *regexp.Regexp, s string) int {
+	if idx := re.FindStringIndex(s); idx != nil {
+		return idx[1]
+	}
+	return 0
+}
+
+// checkMatchers compares each matcher with the expression it replaces.
+func checkMatchers(t *testing.T, s string) {
+	t.Helper()
+	if got, want := matchUUID(s), matchLen(uuidRegexp, s); got != want {
+		t.Errorf("matchUUID(%q) = %d, want %d", s, got, want)
+	}
+	if got, _ := matchHex(s); got != matchLen(hexRegexp, s) {
+		t.Errorf("matchHex(%q) = %d, want %d", s, got, matchLen(hexRegexp, s))
+	}
+	if got, want := matchVersion(s), matchLen(versionRegexp, s); got != want {
+		t.Errorf("matchVersion(%q) = %d, want %d", s, got, want)
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []string{
+		"",
+		"123e4567-e89b-12d3-a456-426614174000",
+		"123e4567-e89b-12d3-a456-426614174000abc",
+		"123E4567-E89B-12D3-A456-42661417400",
+		"123e4567-e89b-12d3-a456_426614174000",
+		"0123456789abcdef",
+		"0123456789abcdef0123 rest",
+		"0123456789abcdef_",
+		"0123456789abcdeg",
+		"0123456789abcde",
+		"0123456789ABCDEF.",
+		"0123456789abcdefé",
+		"v1",
+		"V10.2.33 rest",
+		"v1.2.",
+		"v1.2.x",
+		"v1.2x",
+		"v1x",
+		"v1.2.3_",
+		"v.1",
+		"v",
+		"version",
+	}
+	for _, s := range tests {
+		checkMatchers(t, s)
+	}
+}
+
+func TestMatchHexRun(t *testing.T) {
+	n, run := matchHex("0123456789abcdef0123_")
+	if n != 0 || run != 20 {
+		t.Errorf("expected no match over a run of 20, got %d over %d", n, run)
+	}
+}
+
+func TestHasLines(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want bool
+	}{
+		{"", 0, true},
+		{"", 1, false},
+		{"a\nb", 1, true},
+		{"a\nb", 2, false},
+		{"a\nb\n", 2, true},
+	}
+	for _, tt := range tests {
+		if got := hasLines(tt.s, tt.n); got != tt.want {
+			t.Errorf("hasLines(%q, %d) = %v, want %v", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func FuzzMatchers(f *testing.F) {
+	for _, s := range []string{"123e4567-e89b-12d3-a456-426614174000", "0123456789abcdef0123", "v1.2.3", "v1.2."} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		checkMatchers(t, s)
+	})
+}
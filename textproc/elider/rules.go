@@ -0,0 +1,147 @@
+package elider
+
+import (
+	"regexp"
+)
+
+// Rule names an elision rule. The built-in rules are the constants below;
+// custom rules are named by WithCustomRule.
+type Rule string
+
+// Built-in rules, all enabled by default.
+const (
+	// RuleURL reduces http and https URLs to their domain.
+	RuleURL Rule = "url"
+	// RuleUUID removes UUIDs.
+	RuleUUID Rule = "uuid"
+	// RuleHex removes hex strings of 16 or more digits.
+	RuleHex Rule = "hex"
+	// RuleVersion removes version numbers such as v1.2.3.
+	RuleVersion Rule = "version"
+	// RuleQuotedLine removes lines starting with ">".
+	RuleQuotedLine Rule = "quoted-line"
+	// RuleReply removes everything from a reply attribution, original
+	// message separator or reply header block to the end.
+	RuleReply Rule = "reply"
+	// RuleSignature removes everything from a signature delimiter or mobile
+	// signature to the end.
+	RuleSignature Rule = "signature"
+)
+
+// BuiltinRules lists the built-in rules in the order they are applied.
+var BuiltinRules = []Rule{RuleQuotedLine, RuleReply, RuleSignature, RuleURL, RuleUUID, RuleHex, RuleVersion}
+
+// customRule is a rule added with WithCustomRule.
+type customRule struct {
+	name        Rule
+	pattern     *regexp.Regexp // Anchored at the start of the input
+	replacement string
+}
+
+// ByteRange is a span of the elider's input, as byte offsets from the start
+// of the stream. End is exclusive.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// RuleReport describes what one rule elided. Count is the number of times
+// the rule matched and Ranges are the input bytes it removed or replaced; a
+// URL can contribute two ranges, its scheme and its path.
+type RuleReport struct {
+	Count  int
+	Ranges []ByteRange
+}
+
+// Report describes what was elided, by rule. Rules that never matched are
+// absent.
+type Report map[Rule]RuleReport
+
+// WithRules enables only the given built-in rules. Custom rules are not
+// affected.
+func WithRules(rules ...Rule) Option {
+	return func(p *Processor) {
+		p.disabled = map[Rule]bool{}
+		for _, r := range BuiltinRules {
+			p.disabled[r] = true
+		}
+		for _, r := range rules {
+			delete(p.disabled, r)
+		}
+	}
+}
+
+// WithoutRules disables the given built-in rules.
+func WithoutRules(rules ...Rule) Option {
+	return func(p *Processor) {
+		if p.disabled == nil {
+			p.disabled = map[Rule]bool{}
+		}
+		for _, r := range rules {
+			p.disabled[r] = true
+		}
+	}
+}
+
+// WithReplacement writes token in place of the text removed by RuleUUID,
+// RuleHex or RuleVersion, for example "[UUID]". Without a replacement the
+// text is removed along with the whitespace after it.
+func WithReplacement(rule Rule, token string) Option {
+	return func(p *Processor) {
+		if p.replacements == nil {
+			p.replacements = map[Rule]string{}
+		}
+		p.replacements[rule] = token
+	}
+}
+
+// WithCustomRule adds a rule that elides text matching pattern, writing
+// replacement in its place. Matches are tried at each position before the
+// built-in pattern rules; the pattern is anchored there, so a leading "^" is
+// not needed. Matches do not extend past the input buffered at the time,
+// which always includes the rest of the current line.
+func WithCustomRule(name string, pattern *regexp.Regexp, replacement string) Option {
+	return func(p *Processor) {
+		p.custom = append(p.custom, customRule{
+			name:        Rule(name),
+			pattern:     regexp.MustCompile(`\A(?:` + pattern.String() + `)`),
+			replacement: replacement,
+		})
+	}
+}
+
+// Report returns what has been elided so far. It is complete once Next has
+// returned io.EOF.
+func (p *Processor) Report() Report {
+	report := make(Report, len(p.report))
+	for rule, r := range p.report {
+		report[rule] = RuleReport{Count: r.Count, Ranges: append([]ByteRange(nil), r.Ranges...)}
+	}
+	return report
+}
+
+// enabled reports whether a built-in rule is enabled.
+func (p *Processor) enabled(rule Rule) bool {
+	return !p.disabled[rule]
+}
+
+// record notes that rule matched and removed the input from start to end.
+func (p *Processor) record(rule Rule, start, end int64) {
+	if p.report == nil {
+		p.report = map[Rule]*RuleReport{}
+	}
+	r := p.report[rule]
+	if r == nil {
+		r = &RuleReport{}
+		p.report[rule] = r
+	}
+	r.Count++
+	r.Ranges = append(r.Ranges, ByteRange{Start: start, End: end})
+}
+
+// extend adds a range to the last match of rule.
+func (p *Processor) extend(rule Rule, start, end int64) {
+	if r := p.report[rule]; r != nil && start < end {
+		r.Ranges = append(r.Ranges, ByteRange{Start: start, End: end})
+	}
+}
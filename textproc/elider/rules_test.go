@@ -0,0 +1,104 @@
+package elider
+
+import (
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// runElider reads all output from p.
+func runElider(t *testing.T, p *Processor) string {
+	t.Helper()
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+func TestWithRulesEnablesOnlyGiven(t *testing.T) {
+	input := "id 550e8400-e29b-41d4-a716-446655440000 at https://example.com/x v1.2"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}}, WithRules(RuleURL))
+	got := runElider(t, p)
+	want := "id 550e8400-e29b-41d4-a716-446655440000 at example.com v1.2"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestWithoutRules(t *testing.T) {
+	input := "Reply\n> quoted\n-- \nsig"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}}, WithoutRules(RuleQuotedLine))
+	if got := runElider(t, p); got != "Reply\n> quoted" {
+		t.Errorf("expected quoted line kept, got %q", got)
+	}
+}
+
+func TestWithReplacement(t *testing.T) {
+	input := "id 550e8400-e29b-41d4-a716-446655440000 done"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}}, WithReplacement(RuleUUID, "[UUID]"))
+	if got := runElider(t, p); got != "id [UUID] done" {
+		t.Errorf("expected 'id [UUID] done', got %q", got)
+	}
+}
+
+func TestWithCustomRule(t *testing.T) {
+	input := "Call 555-0123 or 555-0199 today"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}},
+		WithCustomRule("phone", regexp.MustCompile(`\d{3}-\d{4}`), "[PHONE]"))
+	if got := runElider(t, p); got != "Call [PHONE] or [PHONE] today" {
+		t.Errorf("expected phones replaced, got %q", got)
+	}
+	report := p.Report()
+	want := RuleReport{Count: 2, Ranges: []ByteRange{{5, 13}, {17, 25}}}
+	if !reflect.DeepEqual(report["phone"], want) {
+		t.Errorf("expected %+v, got %+v", want, report["phone"])
+	}
+}
+
+func TestReport(t *testing.T) {
+	input := "See https://example.com/a?b v2\n> old\nBye\n-- \nAlice\n"
+	p := NewProcessor(&mockStringSource{blocks: []string{input[:20], input[20:]}})
+	if got := runElider(t, p); got != "See example.com Bye" {
+		t.Errorf("unexpected output %q", got)
+	}
+	want := Report{
+		RuleURL:        {Count: 1, Ranges: []ByteRange{{4, 12}, {23, 27}}},
+		RuleVersion:    {Count: 1, Ranges: []ByteRange{{28, 30}}},
+		RuleQuotedLine: {Count: 1, Ranges: []ByteRange{{31, 37}}},
+		RuleSignature:  {Count: 1, Ranges: []ByteRange{{41, int64(len(input))}}},
+	}
+	if got := p.Report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected report %+v, got %+v", want, got)
+	}
+}
+
+func TestReportReplyToEnd(t *testing.T) {
+	input := "Thanks\nOn Mon, Bob wrote:\nquoted\n"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}})
+	runElider(t, p)
+	r := p.Report()[RuleReply]
+	if r.Count != 1 || len(r.Ranges) != 1 || r.Ranges[0] != (ByteRange{7, int64(len(input))}) {
+		t.Errorf("unexpected reply report %+v", r)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	input := "id 550e8400-e29b-41d4-a716-446655440000 at https://example.com/x ok\n> q\nend"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}}, WithReplacement(RuleUUID, "[UUID]"))
+	out := runElider(t, p)
+	for _, tc := range []struct{ out, in string }{{"[UUID]", "550e8400"}, {"at", "at"}, {"example.com", "example.com"}, {"ok", "ok"}, {"end", "end"}} {
+		o := int64(strings.Index(out, tc.out))
+		if got := p.Offsets().Map(o); got != int64(strings.Index(input, tc.in)) {
+			t.Errorf("%q: expected input offset %d, got %d", tc.out, strings.Index(input, tc.in), got)
+		}
+	}
+}
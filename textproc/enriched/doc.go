@@ -0,0 +1,18 @@
+// Package enriched converts text/enriched content (RFC 1896) to plain text.
+//
+// Formatting commands such as <bold> and </italic> are removed, "<<" becomes
+// a literal "<", and the contents of <param> commands (font names, colours
+// and the like) are dropped. Outside <nofill> sections line breaks follow
+// the RFC 1896 rules: a single newline is a space and a run of n newlines
+// becomes n-1 newlines. Inside <nofill> newlines are kept as they are.
+//
+// Example:
+//
+//	p := enriched.NewProcessor(src)
+//	text, err := p.Next()
+//
+// Position in the pipeline:
+//
+//	After: utf8clean (in place of htmlstrip)
+//	Before: elider
+package enriched
@@ -0,0 +1,167 @@
+package enriched
+
+import (
+	"io"
+	"strings"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// maxCommandLength is the longest formatting command RFC 1896 allows.
+// A "<" not closed within this many bytes is treated as text.
+const maxCommandLength = 60
+
+// Processor reads text/enriched content and returns plain text.
+type Processor struct {
+	src    textproc.StringProcessor
+	input  string // Unprocessed input: an incomplete command or newline run
+	param  int    // Depth of <param> commands
+	nofill int    // Depth of <nofill> commands
+	done   bool
+
+	offsets textproc.OffsetMap
+	inBase  int64 // Input offset of input[0]
+	outBase int64 // Output offset of the next block
+}
+
+// NewProcessor creates a new Processor with the given StringProcessor source.
+func NewProcessor(src textproc.StringProcessor) *Processor {
+	return &Processor{src: src}
+}
+
+// Next returns the next block of plain text.
+// Returns io.EOF when all data has been consumed.
+func (p *Processor) Next() (string, error) {
+	for !p.done {
+		block, err := p.src.Next()
+		if err == io.EOF {
+			p.done = true
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		var out string
+		out, p.input = p.convert(p.input+block, false)
+		if out != "" {
+			return out, nil
+		}
+	}
+
+	out, _ := p.convert(p.input, true)
+	p.input = ""
+	if out != "" {
+		return out, nil
+	}
+	return "", io.EOF
+}
+
+// convert converts as much of data as possible. Unless final is set, a
+// trailing command or newline run that may continue in the next block is
+// returned as rest.
+func (p *Processor) convert(data string, final bool) (out, rest string) {
+	var b strings.Builder
+	i := 0
+	text := func(s string) {
+		if p.param == 0 {
+			p.offsets.Add(p.outBase+int64(b.Len()), p.inBase+int64(i))
+			b.WriteString(s)
+		}
+	}
+	defer func() {
+		p.inBase += int64(len(data) - len(rest))
+		p.outBase += int64(len(out))
+	}()
+
+	for i < len(data) {
+		switch c := data[i]; c {
+		case '<':
+			if i+1 == len(data) {
+				if !final {
+					return b.String(), data[i:]
+				}
+				text("<")
+				i++
+				continue
+			}
+			if data[i+1] == '<' {
+				text("<")
+				i += 2
+				continue
+			}
+			end := strings.IndexByte(data[i:], '>')
+			if end < 0 || end > maxCommandLength+2 {
+				if end < 0 && !final && len(data)-i <= maxCommandLength+2 {
+					return b.String(), data[i:]
+				}
+				// Not a command
+				text("<")
+				i++
+				continue
+			}
+			p.command(strings.ToLower(data[i+1 : i+end]))
+			i += end + 1
+		case '\r', '\n':
+			j, newlines := i, 0
+			for j < len(data) && (data[j] == '\r' || data[j] == '\n') {
+				if data[j] == '\n' {
+					newlines++
+				}
+				j++
+			}
+			if j == len(data) && !final {
+				return b.String(), data[i:]
+			}
+			switch {
+			case p.nofill > 0:
+				text(strings.Repeat("\n", newlines))
+			case newlines == 1:
+				text(" ")
+			case newlines > 1:
+				text(strings.Repeat("\n", newlines-1))
+			}
+			i = j
+		default:
+			j := i + 1
+			for j < len(data) && data[j] != '<' && data[j] != '\r' && data[j] != '\n' {
+				j++
+			}
+			text(data[i:j])
+			i = j
+		}
+	}
+	return b.String(), ""
+}
+
+// Offsets maps offsets in the plain text back to the enriched input.
+func (p *Processor) Offsets() *textproc.OffsetMap {
+	return &p.offsets
+}
+
+// command applies a formatting command. Only commands that change what text
+// is output matter; the rest are formatting and are dropped.
+func (p *Processor) command(cmd string) {
+	closing := strings.HasPrefix(cmd, "/")
+	name := strings.TrimSpace(strings.TrimPrefix(cmd, "/"))
+
+	var depth *int
+	switch name {
+	case "param":
+		depth = &p.param
+	case "nofill":
+		depth = &p.nofill
+	default:
+		return
+	}
+	if !closing {
+		*depth++
+	} else if *depth > 0 {
+		*depth--
+	}
+}
+
+var (
+	_ textproc.StringProcessor = (*Processor)(nil)
+	_ textproc.OffsetMapper    = (*Processor)(nil)
+)
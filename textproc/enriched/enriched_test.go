@@ -0,0 +1,113 @@
+package enriched
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// mockStringProcessor implements textproc.StringProcessor for testing
+type mockStringProcessor struct {
+	blocks []string
+	index  int
+	err    error
+}
+
+func (m *mockStringProcessor) Next() (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if m.index >= len(m.blocks) {
+		return "", io.EOF
+	}
+	result := m.blocks[m.index]
+	m.index++
+	return result, nil
+}
+
+// readAll drains p and returns everything it produced.
+func readAll(t *testing.T, p *Processor) string {
+	t.Helper()
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+// TestRFC1896Example uses the sample text from RFC 1896 section 6.
+func TestRFC1896Example(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{
+		"<bold>Now</bold> is the time for <italic>all</italic>\r\n" +
+			"good men\r\n" +
+			" <smaller>(and <<women>)</smaller> to\r\n" +
+			"<ignoreme>come</ignoreme>\r\n" +
+			"to the aid of their\r\n" +
+			"\r\n" +
+			"<color><param>red</param>beloved</color> country.\r\n"}}
+	got := readAll(t, NewProcessor(src))
+	want := "Now is the time for all good men  (and <women>) to come to the aid of their\nbeloved country. "
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCommandSplitAcrossBlocks(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"a <bo", "ld>b</bold", "> c"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "a b c" {
+		t.Errorf("expected 'a b c', got %q", got)
+	}
+}
+
+func TestNewlineRunSplitAcrossBlocks(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"one\n", "\ntwo"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "one\ntwo" {
+		t.Errorf("expected 'one\\ntwo', got %q", got)
+	}
+}
+
+func TestNofillKeepsNewlines(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"<nofill>line 1\nline 2\n</nofill>joined\nline"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "line 1\nline 2\njoined line" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestUnclosedAngleBracketIsText(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"a < b"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "a < b" {
+		t.Errorf("expected 'a < b', got %q", got)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	srcErr := errors.New("boom")
+	p := NewProcessor(&mockStringProcessor{err: srcErr})
+	if _, err := p.Next(); !errors.Is(err, srcErr) {
+		t.Errorf("expected source error, got %v", err)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	input := "<bold>Hi</bold> there\n\nnext"
+	p := NewProcessor(&mockStringProcessor{blocks: []string{input}})
+	out := readAll(t, p)
+	m := p.Offsets()
+	for _, word := range []string{"Hi", "there", "next"} {
+		o := int64(strings.Index(out, word))
+		if got := m.Map(o); got != int64(strings.Index(input, word)) {
+			t.Errorf("%q: expected input offset %d, got %d", word, strings.Index(input, word), got)
+		}
+	}
+}
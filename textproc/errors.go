@@ -0,0 +1,17 @@
+package textproc
+
+// Error types for textproc.
+
+import (
+	"fmt"
+)
+
+// ErrTooMany indicates a collector stopped because its input had more
+// values than its limit.
+type ErrTooMany struct {
+	Max int
+}
+
+func (e ErrTooMany) Error() string {
+	return fmt.Sprintf("more than %d values", e.Max)
+}
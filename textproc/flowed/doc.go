@@ -0,0 +1,24 @@
+// Package flowed unwraps text/plain; format=flowed content (RFC 3676).
+//
+// Lines ending in a space are soft breaks and are joined with the line that
+// follows, so paragraphs wrapped by the sender come out as single lines.
+// Lines without a trailing space are hard breaks and are kept.
+//
+// Features:
+//   - Joins soft-broken lines of the same quote depth
+//   - Removes space-stuffing (one leading space added by the sender)
+//   - DelSp=yes support, removing the trailing space of each soft break
+//   - Keeps quote markers, normalised to ">> " form, so quoted text can still be elided
+//   - Treats the signature separator "-- " as a hard break
+//   - Writes the start of a line over 64KB as it arrives rather than holding it
+//
+// Example:
+//
+//	p := flowed.NewProcessor(src, flowed.WithDelSp(params["delsp"] == "yes"))
+//	text, err := p.Next()
+//
+// Position in the pipeline:
+//
+//	After: utf8clean (in place of htmlstrip)
+//	Before: elider
+package flowed
@@ -0,0 +1,225 @@
+package flowed
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// maxPending is the most input, in bytes, held back waiting for the end of
+// a line. The start of a longer line is written as it arrives.
+const maxPending = 64 * 1024
+
+// Processor reads format=flowed text and returns it with soft line breaks
+// removed.
+type Processor struct {
+	src   textproc.StringProcessor
+	delSp bool
+
+	input     []byte          // Unprocessed input, always less than a line
+	paragraph strings.Builder // Content of the flowed paragraph being joined
+	depth     int             // Quote depth of the paragraph
+	open      bool            // A paragraph is being joined
+	started   bool            // Some of the paragraph has been written
+	long      bool            // The start of the line being read has been written
+	done      bool
+
+	offsets  textproc.OffsetMap
+	segments []segment // Where each line of the paragraph came from
+	inPos    int64     // Input offset of input[0]
+	outBase  int64     // Output offset of the next block
+}
+
+// segment records that paragraph text from offset off came from input
+// offset in.
+type segment struct {
+	off int
+	in  int64
+}
+
+// Option configures a Processor.
+type Option func(*Processor)
+
+// WithDelSp enables DelSp=yes handling: the space ending each soft-broken
+// line was added by the sender and is removed when joining.
+func WithDelSp(delSp bool) Option {
+	return func(p *Processor) {
+		p.delSp = delSp
+	}
+}
+
+// NewProcessor creates a new Processor with the given StringProcessor source.
+func NewProcessor(src textproc.StringProcessor, opts ...Option) *Processor {
+	p := &Processor{src: src}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Next returns the next block of unwrapped text.
+// Returns io.EOF when all data has been consumed.
+func (p *Processor) Next() (string, error) {
+	for !p.done {
+		block, err := p.src.Next()
+		if err == io.EOF {
+			p.done = true
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// Only the new block can hold a newline
+		from := len(p.input)
+		p.input = append(p.input, block...)
+		var out strings.Builder
+		start := 0
+		for {
+			i := bytes.IndexByte(p.input[from:], '\n')
+			if i < 0 {
+				break
+			}
+			end := from + i
+			line := strings.TrimSuffix(string(p.input[start:end]), "\r")
+			p.line(&out, line, true, p.inPos)
+			p.inPos += int64(end + 1 - start)
+			start, from = end+1, end+1
+		}
+		if len(p.input)-start > maxPending {
+			// A line too long to hold. Keep back its last bytes, which
+			// may be the space of a soft break, and write the rest.
+			cut := len(p.input) - 2
+			for cut > start && !utf8.RuneStart(p.input[cut]) {
+				cut--
+			}
+			p.partial(&out, string(p.input[start:cut]), p.inPos)
+			p.inPos += int64(cut - start)
+			start = cut
+		}
+		p.input = append(p.input[:0], p.input[start:]...)
+		if out.Len() > 0 {
+			p.outBase += int64(out.Len())
+			return out.String(), nil
+		}
+	}
+
+	// A final line without a newline, and any paragraph still open
+	var out strings.Builder
+	if len(p.input) > 0 {
+		p.line(&out, string(p.input), false, p.inPos)
+		p.inPos += int64(len(p.input))
+		p.input = nil
+	}
+	if p.open {
+		p.flush(&out, false)
+	}
+	if out.Len() > 0 {
+		p.outBase += int64(out.Len())
+		return out.String(), nil
+	}
+	return "", io.EOF
+}
+
+// line processes one line of input, writing any completed paragraph to out.
+// newline reports whether the line was terminated, and in is the line's
+// input offset.
+func (p *Processor) line(out *strings.Builder, line string, newline bool, in int64) {
+	signature := false
+	if p.long {
+		// The rest of a line whose start has been written
+		p.long = false
+	} else {
+		line, in = p.begin(out, line, in)
+		signature = line == "-- "
+	}
+
+	soft := strings.HasSuffix(line, " ") && !signature
+	if soft && p.delSp {
+		line = line[:len(line)-1]
+	}
+	p.segments = append(p.segments, segment{off: p.paragraph.Len(), in: in})
+	p.paragraph.WriteString(line)
+	if !soft {
+		p.flush(out, newline)
+	}
+}
+
+// partial processes the start of a line too long to hold, writing it to
+// out. in is its input offset.
+func (p *Processor) partial(out *strings.Builder, text string, in int64) {
+	if !p.long {
+		text, in = p.begin(out, text, in)
+		p.long = true
+	}
+	p.segments = append(p.segments, segment{off: p.paragraph.Len(), in: in})
+	p.paragraph.WriteString(text)
+	p.spill(out)
+}
+
+// begin reads the quote depth and space-stuffing at the start of a line,
+// ending the open paragraph if the line cannot continue it. It returns the
+// line's content and the content's input offset.
+func (p *Processor) begin(out *strings.Builder, line string, in int64) (string, int64) {
+	depth := 0
+	for depth < len(line) && line[depth] == '>' {
+		depth++
+	}
+	line = line[depth:]
+	// Space-stuffing
+	unstuffed := strings.TrimPrefix(line, " ")
+	in += int64(depth + len(line) - len(unstuffed))
+	line = unstuffed
+
+	if p.open && depth != p.depth {
+		// Quote depth changed without a hard break; RFC 3676 section 4.5
+		// says to treat the previous line as fixed
+		p.flush(out, true)
+	}
+	if line == "-- " && p.open {
+		p.flush(out, true)
+	}
+	p.depth = depth
+	p.open = true
+	return line, in
+}
+
+// flush writes the rest of the current paragraph to out and ends it.
+func (p *Processor) flush(out *strings.Builder, newline bool) {
+	p.spill(out)
+	if newline {
+		out.WriteByte('\n')
+	}
+	p.started = false
+	p.open = false
+}
+
+// spill writes the paragraph read so far to out, with its quote prefix if
+// none of it has been written yet, leaving it open.
+func (p *Processor) spill(out *strings.Builder) {
+	if p.depth > 0 && !p.started {
+		out.WriteString(strings.Repeat(">", p.depth))
+		out.WriteByte(' ')
+	}
+	p.started = true
+	base := p.outBase + int64(out.Len())
+	for _, seg := range p.segments {
+		p.offsets.Add(base+int64(seg.off), seg.in)
+	}
+	p.segments = p.segments[:0]
+	out.WriteString(p.paragraph.String())
+	p.paragraph.Reset()
+}
+
+// Offsets maps offsets in the unwrapped text back to the flowed input.
+func (p *Processor) Offsets() *textproc.OffsetMap {
+	return &p.offsets
+}
+
+var (
+	_ textproc.StringProcessor = (*Processor)(nil)
+	_ textproc.OffsetMapper    = (*Processor)(nil)
+)
@@ -0,0 +1,160 @@
+package flowed
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// mockStringProcessor implements textproc.StringProcessor for testing
+type mockStringProcessor struct {
+	blocks []string
+	index  int
+	err    error
+}
+
+func (m *mockStringProcessor) Next() (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if m.index >= len(m.blocks) {
+		return "", io.EOF
+	}
+	result := m.blocks[m.index]
+	m.index++
+	return result, nil
+}
+
+// readAll drains p and returns everything it produced.
+func readAll(t *testing.T, p *Processor) string {
+	t.Helper()
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+func TestSoftBreaksJoined(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"This is a long \r\nparagraph that \r\nwraps.\r\nNext line.\r\n"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "This is a long paragraph that wraps.\nNext line.\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestSoftBreakSplitAcrossBlocks(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"one ", "\r", "\ntwo\r\n"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "one two\n" {
+		t.Errorf("expected 'one two\\n', got %q", got)
+	}
+}
+
+func TestDelSp(t *testing.T) {
+	// With DelSp=yes the trailing space is not part of the text
+	src := &mockStringProcessor{blocks: []string{"日本語の \r\n文章です。\r\n"}}
+	got := readAll(t, NewProcessor(src, WithDelSp(true)))
+	if got != "日本語の文章です。\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestSpaceStuffingRemoved(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{" From the start\r\n  indented\r\n"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "From the start\n indented\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestQuotedParagraphs(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{">> deeply \r\n>> quoted\r\n> quoted \r\n> text\r\nreply\r\n"}}
+	got := readAll(t, NewProcessor(src))
+	if got != ">> deeply quoted\n> quoted text\nreply\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestQuoteDepthChangeEndsParagraph(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"> quoted \r\nnot quoted\r\n"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "> quoted \nnot quoted\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestSignatureSeparatorIsHard(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"Bye \r\n-- \r\nJane\r\n"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "Bye \n-- \nJane\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestFinalLineWithoutNewline(t *testing.T) {
+	src := &mockStringProcessor{blocks: []string{"soft \r\nend"}}
+	got := readAll(t, NewProcessor(src))
+	if got != "soft end" {
+		t.Errorf("expected 'soft end', got %q", got)
+	}
+}
+
+func TestLongLineNotHeld(t *testing.T) {
+	long := strings.Repeat("abcdéfgh ", 3*maxPending/9)
+	input := "> " + long + "\r\n> end\r\n"
+	var blocks []string
+	for i := 0; i < len(input); i += 1000 {
+		blocks = append(blocks, input[i:min(i+1000, len(input))])
+	}
+	p := NewProcessor(&mockStringProcessor{blocks: blocks}, WithDelSp(true))
+	first, err := p.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first) == 0 || len(first) > maxPending+1000 {
+		t.Errorf("expected the start of the long line once %d bytes were held, got %d bytes", maxPending, len(first))
+	}
+	if !strings.HasPrefix(first, "> abcdéfgh") {
+		t.Errorf("expected the quote prefix once, got %q", first[:20])
+	}
+	got := first + readAll(t, p)
+	if want := "> " + strings.TrimSuffix(long, " ") + "end\n"; got != want {
+		t.Errorf("expected %d bytes with the soft break joined, got %d bytes", len(want), len(got))
+	}
+	if !utf8.ValidString(first) {
+		t.Error("expected the long line split on a character boundary")
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	srcErr := errors.New("boom")
+	p := NewProcessor(&mockStringProcessor{err: srcErr})
+	if _, err := p.Next(); !errors.Is(err, srcErr) {
+		t.Errorf("expected source error, got %v", err)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	input := "> One \n> two\nThree"
+	p := NewProcessor(&mockStringProcessor{blocks: []string{input}})
+	out := readAll(t, p)
+	if out != "> One two\nThree" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	m := p.Offsets()
+	for _, tc := range []struct{ word, in string }{{"two", "two"}, {"Three", "Three"}, {"One", "One"}} {
+		o := int64(strings.Index(out, tc.word))
+		if got := m.Map(o); got != int64(strings.Index(input, tc.in)) {
+			t.Errorf("%q: expected input offset %d, got %d", tc.word, strings.Index(input, tc.in), got)
+		}
+	}
+}
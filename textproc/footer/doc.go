@@ -0,0 +1,29 @@
+// Package footer removes legal disclaimers and mailing list footers.
+//
+// Corporate disclaimers ("This email and any attachments are
+// confidential...") and list footers ("You received this message because
+// you are subscribed to...", unsubscribe blocks) repeat across messages and
+// say nothing about them. The Processor reads text a paragraph at a time
+// and removes each paragraph recognised as one, along with separator lines
+// ("-- ", rules of dashes or underscores) just before it.
+//
+// Paragraphs are recognised by how they start: DefaultRules covers common
+// disclaimers and footers in English, German, French, Spanish, Italian,
+// Portuguese, Dutch, Japanese and Chinese. WithRule adds rules and
+// WithoutRules turns rules off by name. WithListHeaders passes the
+// message's RFC 2369 List-* fields, so a paragraph mentioning the list's
+// own unsubscribe, help or posting URLs is removed too. Paragraphs longer
+// than 4KB are never removed.
+//
+// A recognised paragraph is removed only where footers go: after a
+// separator line, after another footer removed there, or in the block of
+// recognised paragraphs that ends the message. The same words in the
+// middle of the body are kept.
+//
+// Report counts the paragraphs each rule removed.
+//
+// Position in the pipeline:
+//
+//	After: htmlstrip, flowed or enriched
+//	Before: elider, chunker
+package footer
@@ -0,0 +1,391 @@
+package footer
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/jarrod-lowe/jmap-service-libs/headers"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// maxParagraph is the longest paragraph, in bytes, considered as a footer.
+// Longer paragraphs are passed through as they arrive.
+const maxParagraph = 4096
+
+// maxTrailing is the most text, in bytes, held back as a possible trailing
+// block of footers. Once more is held it is passed through, as text that
+// long is unlikely to be footers.
+const maxTrailing = 4 * maxParagraph
+
+// minListMarker is the shortest List-* URL looked for in the text.
+const minListMarker = 6
+
+// listFields are the RFC 2369 fields whose URLs appear in list footers.
+var listFields = []string{"List-Unsubscribe", "List-Subscribe", "List-Help", "List-Post", "List-Owner", "List-Archive"}
+
+// Processor reads text and returns it with disclaimer and footer
+// paragraphs removed.
+type Processor struct {
+	src         textproc.StringProcessor
+	rules       []Rule
+	disabled    map[string]bool
+	listMarkers []string // Lower-cased List-* URLs, without their schemes
+
+	input   string // Unprocessed input, always less than a line
+	para    []line // Lines of the paragraph being read
+	paraLen int    // Bytes in para
+	held    []line // Separator lines, and blank lines after them, awaiting the next paragraph
+	// Paragraphs matching a rule without a separator before them, with
+	// the blank lines between them. They are removed only if nothing but
+	// footers follows them.
+	trailing      []line
+	trailingLen   int      // Bytes in trailing
+	trailingRules []string // Rule matching each paragraph in trailing
+	inFooter      bool     // The last paragraph was a footer removed after a separator
+	long          bool     // The paragraph is too long to be a footer; pass it through
+	done          bool
+
+	offsets textproc.OffsetMap
+	inPos   int64 // Input offset of input[0]
+	outBase int64 // Output offset of the next block
+	report  map[string]int
+}
+
+// line is a line of input, with its newline if it has one.
+type line struct {
+	text string
+	in   int64 // Input offset of the line
+}
+
+// Option configures a Processor.
+type Option func(*Processor)
+
+// WithRule adds a rule recognising paragraphs that start with a match for
+// pattern; see Rule. Added rules are tried after the built-in ones.
+func WithRule(name string, pattern *regexp.Regexp) Option {
+	return func(p *Processor) {
+		p.rules = append(p.rules, Rule{Name: name, Pattern: anchor(pattern.String())})
+	}
+}
+
+// WithoutRules disables the named rules, built-in or added.
+func WithoutRules(names ...string) Option {
+	return func(p *Processor) {
+		if p.disabled == nil {
+			p.disabled = map[string]bool{}
+		}
+		for _, name := range names {
+			p.disabled[name] = true
+		}
+	}
+}
+
+// WithListHeaders gives the message's header fields, such as a
+// textproto.MIMEHeader or mail.Header. The URLs of its RFC 2369 List-*
+// fields mark paragraphs as list footers: a paragraph mentioning the
+// list's unsubscribe link or posting address is removed under RuleList.
+func WithListHeaders(h map[string][]string) Option {
+	return func(p *Processor) {
+		for key, values := range h {
+			if !isListField(key) {
+				continue
+			}
+			for _, value := range values {
+				for _, url := range headers.AsURLs(value) {
+					if m := listMarker(url); len(m) >= minListMarker {
+						p.listMarkers = append(p.listMarkers, m)
+					}
+				}
+			}
+		}
+	}
+}
+
+// isListField reports whether key names an RFC 2369 field with URLs.
+func isListField(key string) bool {
+	for _, f := range listFields {
+		if strings.EqualFold(key, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// listMarker returns the part of a List-* URL a footer would show: the
+// address of a mailto URL, or an http URL without its scheme.
+func listMarker(url string) string {
+	url = strings.ToLower(url)
+	if addr, ok := strings.CutPrefix(url, "mailto:"); ok {
+		addr, _, _ = strings.Cut(addr, "?")
+		return addr
+	}
+	for _, scheme := range []string{"https://", "http://"} {
+		if rest, ok := strings.CutPrefix(url, scheme); ok {
+			return strings.TrimSuffix(rest, "/")
+		}
+	}
+	return ""
+}
+
+// NewProcessor creates a new Processor with the given StringProcessor source.
+func NewProcessor(src textproc.StringProcessor, opts ...Option) *Processor {
+	p := &Processor{
+		src:   src,
+		rules: append([]Rule(nil), DefaultRules...),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Next returns the next block of text with footers removed.
+// Returns io.EOF when all data has been consumed.
+func (p *Processor) Next() (string, error) {
+	for !p.done {
+		block, err := p.src.Next()
+		if err == io.EOF {
+			p.done = true
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		p.input += block
+		var out strings.Builder
+		for {
+			i := strings.IndexByte(p.input, '\n')
+			if i < 0 {
+				break
+			}
+			p.line(&out, line{text: p.input[:i+1], in: p.inPos})
+			p.input = p.input[i+1:]
+			p.inPos += int64(i + 1)
+		}
+		if len(p.input) > maxParagraph {
+			// A line too long to be part of a footer
+			p.flushLong(&out)
+			p.emit(&out, line{text: p.input, in: p.inPos})
+			p.inPos += int64(len(p.input))
+			p.input = ""
+		}
+		if out.Len() > 0 {
+			p.outBase += int64(out.Len())
+			return out.String(), nil
+		}
+	}
+
+	// A final line without a newline, and any paragraph still open
+	var out strings.Builder
+	if p.input != "" {
+		p.line(&out, line{text: p.input, in: p.inPos})
+		p.inPos += int64(len(p.input))
+		p.input = ""
+	}
+	p.endParagraph(&out)
+	// Footers at the end of the message are removed
+	for _, name := range p.trailingRules {
+		p.record(name)
+	}
+	p.trailing, p.trailingLen, p.trailingRules = nil, 0, nil
+	p.emit(&out, p.held...)
+	p.held = nil
+	if out.Len() > 0 {
+		p.outBase += int64(out.Len())
+		return out.String(), nil
+	}
+	return "", io.EOF
+}
+
+// line processes one line of input, writing any text decided on to out.
+func (p *Processor) line(out *strings.Builder, l line) {
+	switch {
+	case strings.TrimSpace(l.text) == "":
+		p.endParagraph(out)
+		p.long = false
+		switch {
+		case len(p.held) > 0:
+			p.held = append(p.held, l)
+		case len(p.trailing) > 0:
+			p.hold(l)
+		default:
+			p.emit(out, l)
+		}
+		return
+	case isSeparator(l.text):
+		// A separator starts a paragraph of its own
+		if !onlySeparators(p.para) {
+			p.endParagraph(out)
+		}
+		p.long = false
+	case p.long:
+		p.emit(out, l)
+		return
+	}
+
+	p.para = append(p.para, l)
+	p.paraLen += len(l.text)
+	if p.paraLen > maxParagraph {
+		p.flushLong(out)
+	}
+}
+
+// flushLong passes the paragraph being read through as too long to be a
+// footer, along with the rest of it.
+func (p *Processor) flushLong(out *strings.Builder) {
+	p.emit(out, p.held...)
+	p.emit(out, p.para...)
+	p.held = nil
+	p.para = nil
+	p.paraLen = 0
+	p.long = true
+}
+
+// endParagraph decides whether the paragraph just read is a footer,
+// writing it to out if not.
+func (p *Processor) endParagraph(out *strings.Builder) {
+	if len(p.para) == 0 {
+		return
+	}
+	para := p.para
+	p.para = nil
+	p.paraLen = 0
+
+	if onlySeparators(para) {
+		// Hold separators until the paragraph after them is decided
+		p.emit(out, p.held...)
+		p.held = para
+		return
+	}
+	name, ok := p.match(para)
+	switch {
+	case ok && (len(p.held) > 0 || isSeparator(para[0].text) || p.inFooter):
+		// A footer after a separator, or after another such footer
+		p.record(name)
+		p.held = nil
+		p.inFooter = true
+		return
+	case ok && p.trailingLen+length(para) <= maxTrailing:
+		// Possibly a footer, if it is at the end of the message
+		p.emit(out, p.held...)
+		p.held = nil
+		p.hold(para...)
+		p.trailingRules = append(p.trailingRules, name)
+		return
+	}
+	p.inFooter = false
+	p.emit(out, p.held...)
+	p.emit(out, para...)
+	p.held = nil
+}
+
+// length returns the bytes in lines.
+func length(lines []line) int {
+	n := 0
+	for _, l := range lines {
+		n += len(l.text)
+	}
+	return n
+}
+
+// hold adds lines to the possible trailing footers.
+func (p *Processor) hold(lines ...line) {
+	p.trailing = append(p.trailing, lines...)
+	p.trailingLen += length(lines)
+}
+
+// record counts a paragraph removed under the named rule.
+func (p *Processor) record(name string) {
+	if p.report == nil {
+		p.report = map[string]int{}
+	}
+	p.report[name]++
+}
+
+// match returns the name of the rule recognising para as a footer.
+func (p *Processor) match(para []line) (string, bool) {
+	for len(para) > 0 && isSeparator(para[0].text) {
+		para = para[1:]
+	}
+	var words []string
+	for _, l := range para {
+		words = append(words, strings.Fields(l.text)...)
+	}
+	text := strings.Join(words, " ")
+
+	for _, r := range p.rules {
+		if !p.disabled[r.Name] && r.Pattern.MatchString(text) {
+			return r.Name, true
+		}
+	}
+	if !p.disabled[RuleList] && len(p.listMarkers) > 0 {
+		lower := strings.ToLower(text)
+		for _, m := range p.listMarkers {
+			if strings.Contains(lower, m) {
+				return RuleList, true
+			}
+		}
+	}
+	return "", false
+}
+
+// onlySeparators reports whether lines are all separator lines.
+func onlySeparators(lines []line) bool {
+	for _, l := range lines {
+		if !isSeparator(l.text) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSeparator reports whether s is a line that sets off a footer: a "-- "
+// signature delimiter, or a rule of dashes, underscores, equals signs or
+// asterisks.
+func isSeparator(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "--" {
+		return true
+	}
+	if len(s) < 3 {
+		return false
+	}
+	return strings.Trim(s, string(s[0])) == "" && strings.ContainsRune("-_=*", rune(s[0]))
+}
+
+// emit writes lines to out, mapping their offsets. Any possible trailing
+// footers are written first: text after them means they are not trailing.
+func (p *Processor) emit(out *strings.Builder, lines ...line) {
+	if len(lines) > 0 && len(p.trailing) > 0 {
+		trailing := p.trailing
+		p.trailing, p.trailingLen, p.trailingRules = nil, 0, nil
+		p.emit(out, trailing...)
+	}
+	for _, l := range lines {
+		p.offsets.Add(p.outBase+int64(out.Len()), l.in)
+		out.WriteString(l.text)
+	}
+}
+
+// Report returns how many paragraphs each rule has removed so far, by rule
+// name. It is complete once Next has returned io.EOF.
+func (p *Processor) Report() map[string]int {
+	report := make(map[string]int, len(p.report))
+	for name, n := range p.report {
+		report[name] = n
+	}
+	return report
+}
+
+// Offsets maps offsets in the output back to the input.
+func (p *Processor) Offsets() *textproc.OffsetMap {
+	return &p.offsets
+}
+
+// Ensure Processor implements StringProcessor and OffsetMapper
+var (
+	_ textproc.StringProcessor = (*Processor)(nil)
+	_ textproc.OffsetMapper    = (*Processor)(nil)
+)
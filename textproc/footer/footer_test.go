@@ -0,0 +1,227 @@
+package footer
+
+import (
+	"errors"
+	"io"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// mockStringSource implements textproc.StringProcessor for testing.
+type mockStringSource struct {
+	blocks []string
+	err    error
+}
+
+func (m *mockStringSource) Next() (string, error) {
+	if len(m.blocks) == 0 {
+		if m.err != nil {
+			return "", m.err
+		}
+		return "", io.EOF
+	}
+	block := m.blocks[0]
+	m.blocks = m.blocks[1:]
+	return block, nil
+}
+
+// readAll runs a Processor over the given blocks and returns all output.
+func readAll(t *testing.T, p *Processor) string {
+	t.Helper()
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+// strip runs a Processor with opts over input.
+func strip(t *testing.T, input string, opts ...Option) string {
+	t.Helper()
+	return readAll(t, NewProcessor(&mockStringSource{blocks: []string{input}}, opts...))
+}
+
+const body = "Hi team,\n\nThe release is on Friday.\n\nThanks,\nAlice\n"
+
+func TestRemovesFooters(t *testing.T) {
+	tests := map[string]string{
+		"disclaimer": "This email and any attachments are confidential and intended solely for the addressee.\n" +
+			"If you are not the intended recipient, please delete it.\n",
+		"separated disclaimer": "________________\nCONFIDENTIALITY NOTICE: The information in this message is privileged.\n",
+		"google groups": "-- \nYou received this message because you are subscribed to the Google Groups \"dev\" group.\n" +
+			"To unsubscribe from this group and stop receiving emails from it, send an email to dev+unsubscribe@googlegroups.com.\n",
+		"mailman":  "_______________________________________________\nDev mailing list\ndev@lists.example.org\nhttps://lists.example.org/mailman/listinfo/dev\n",
+		"german":   "Diese E-Mail enthält vertrauliche Informationen. Wenn Sie nicht der richtige Adressat sind, informieren Sie bitte den Absender.\n",
+		"french":   "Ce message et ses pièces jointes sont confidentiels et établis à l'attention exclusive de leurs destinataires.\n",
+		"spanish":  "Este mensaje es confidencial. Si usted no es el destinatario, bórrelo.\n",
+		"japanese": "このメールには機密情報が含まれています。\n",
+	}
+	for name, footer := range tests {
+		got := strip(t, body+"\n"+footer)
+		if strings.TrimSpace(got) != strings.TrimSpace(body) {
+			t.Errorf("%s: expected the footer removed, got %q", name, got)
+		}
+	}
+}
+
+func TestKeepsOrdinaryText(t *testing.T) {
+	tests := []string{
+		"Please keep this email between us until the launch.\n",
+		"The information you sent over was great, thanks.\n",
+		"--\n\nThe numbers are in the attached sheet.\n",
+		"Header\n---\nMarkdown-style section text.\n",
+	}
+	for _, tail := range tests {
+		input := body + "\n" + tail
+		if got := strip(t, input); got != input {
+			t.Errorf("expected %q unchanged, got %q", input, got)
+		}
+	}
+}
+
+func TestKeepsMidBodyProse(t *testing.T) {
+	tests := []string{
+		"If you are not able to make Tuesday's review, send your notes ahead.\n",
+		"This message is intended as a summary of the planning meeting.\n",
+		"Unsubscribe requests from customers have doubled since the redesign.\n",
+		"Abmelden können sich Kunden jetzt auch telefonisch.\n",
+		"Afmelden kan voortaan ook via de app.\n",
+	}
+	for _, prose := range tests {
+		input := "Hi team,\n\n" + prose + "\nThanks,\nAlice\n"
+		if got := strip(t, input); got != input {
+			t.Errorf("expected %q unchanged, got %q", input, got)
+		}
+	}
+}
+
+func TestFooterOnlyAfterSeparatorOrAtEnd(t *testing.T) {
+	footer := "You received this message because you subscribed.\n"
+	input := "First.\n\n" + footer + "\nLast.\n"
+	if got := strip(t, input); got != input {
+		t.Errorf("expected a mid-body match kept, got %q", got)
+	}
+
+	// Footers after a separator are removed with those following them
+	input = "First.\n\n___\n" + footer + "\nUnsubscribe here.\n\nLast.\n"
+	if got := strip(t, input); got != "First.\n\n\n\nLast.\n" {
+		t.Errorf("expected the separated footers removed, got %q", got)
+	}
+
+	// A trailing block of several footers is removed whole
+	input = body + "\n" + footer + "\nUnsubscribe: https://example.org/u\n"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}})
+	if got := readAll(t, p); strings.TrimSpace(got) != strings.TrimSpace(body) {
+		t.Errorf("expected the trailing footers removed, got %q", got)
+	}
+	if p.Report()["list-footer-en"] != 2 {
+		t.Errorf("expected two removals, got %v", p.Report())
+	}
+}
+
+func TestRemovesHeldSeparator(t *testing.T) {
+	input := body + "\n--\n\nTo unsubscribe, email dev-leave@example.org\n"
+	if got := strip(t, input); strings.TrimSpace(got) != strings.TrimSpace(body) {
+		t.Errorf("expected the separator and footer removed, got %q", got)
+	}
+}
+
+func TestFooterSplitAcrossBlocks(t *testing.T) {
+	input := body + "\nYou received this message because you subscribed.\n"
+	var blocks []string
+	for i := 0; i < len(input); i += 7 {
+		blocks = append(blocks, input[i:min(i+7, len(input))])
+	}
+	got := readAll(t, NewProcessor(&mockStringSource{blocks: blocks}))
+	if strings.TrimSpace(got) != strings.TrimSpace(body) {
+		t.Errorf("expected the footer removed, got %q", got)
+	}
+}
+
+func TestWithRuleAndWithoutRules(t *testing.T) {
+	input := body + "\nSent by Example CRM on behalf of Alice.\n"
+	got := strip(t, input, WithRule("crm", regexp.MustCompile(`sent by example crm`)))
+	if strings.TrimSpace(got) != strings.TrimSpace(body) {
+		t.Errorf("expected the custom footer removed, got %q", got)
+	}
+
+	input = body + "\nUnsubscribe here.\n"
+	if got := strip(t, input, WithoutRules("list-footer-en")); got != input {
+		t.Errorf("expected the disabled rule not to apply, got %q", got)
+	}
+}
+
+func TestWithListHeaders(t *testing.T) {
+	h := textproto.MIMEHeader{}
+	h.Set("List-Unsubscribe", "<mailto:dev-request@example.org?subject=unsubscribe>, <https://lists.example.org/options/dev>")
+	h.Set("List-Post", "<mailto:dev@example.org>")
+	h.Set("Subject", "<mailto:ignored@example.org>")
+	footer := "Manage your options at https://lists.example.org/options/dev or write to dev-request@example.org\n"
+
+	got := strip(t, body+"\n"+footer, WithListHeaders(h))
+	if strings.TrimSpace(got) != strings.TrimSpace(body) {
+		t.Errorf("expected the list footer removed, got %q", got)
+	}
+	p := NewProcessor(&mockStringSource{blocks: []string{body + "\n" + footer}}, WithListHeaders(h))
+	readAll(t, p)
+	if p.Report()[RuleList] != 1 {
+		t.Errorf("expected one removal under RuleList, got %v", p.Report())
+	}
+	if input := body + "\n" + footer; strip(t, input) != input {
+		t.Error("expected the paragraph kept without list headers")
+	}
+}
+
+func TestLongParagraphKept(t *testing.T) {
+	input := "This email is confidential. " + strings.Repeat("More words follow here. ", 300) + "\n"
+	if got := strip(t, input); got != input {
+		t.Errorf("expected a long paragraph kept, got %d bytes", len(got))
+	}
+	long := strings.Repeat("x", 3*maxParagraph) + "\n\nUnsubscribe here.\n"
+	got := readAll(t, NewProcessor(&mockStringSource{blocks: []string{long[:maxParagraph+10], long[maxParagraph+10:]}}))
+	if got != strings.Repeat("x", 3*maxParagraph)+"\n\n" {
+		t.Errorf("expected the long line kept and the footer removed, got %d bytes", len(got))
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	footer := "You received this message because you subscribed.\n"
+	input := "First.\n\n-- \n" + footer + "\nLast.\n"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}})
+	got := readAll(t, p)
+	if got != "First.\n\n\nLast.\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+	out := int64(strings.Index(got, "Last."))
+	if in := p.Offsets().Map(out); in != int64(strings.Index(input, "Last.")) {
+		t.Errorf("expected Last. to map to %d, got %d", strings.Index(input, "Last."), in)
+	}
+}
+
+func TestErrorPropagation(t *testing.T) {
+	boom := errors.New("boom")
+	p := NewProcessor(&mockStringSource{err: boom})
+	if _, err := p.Next(); !errors.Is(err, boom) {
+		t.Errorf("expected boom, got %v", err)
+	}
+}
+
+func TestIsSeparator(t *testing.T) {
+	tests := map[string]bool{
+		"-- \n": true, "--": true, "___\n": true, "=====": true, "***": true,
+		"-": false, "-=-": false, "a--": false, "": false, "~~~": false,
+	}
+	for s, want := range tests {
+		if got := isSeparator(s); got != want {
+			t.Errorf("isSeparator(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
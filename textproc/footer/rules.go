@@ -0,0 +1,84 @@
+package footer
+
+import (
+	"regexp"
+)
+
+// Rule recognises a disclaimer or footer paragraph by how it starts.
+type Rule struct {
+	Name string
+	// Pattern is matched at the start of each paragraph, with leading
+	// separator lines removed and whitespace collapsed to single spaces,
+	// ignoring case.
+	Pattern *regexp.Regexp
+}
+
+// RuleList names removals of paragraphs mentioning one of the message's
+// RFC 2369 List-* URLs; see WithListHeaders.
+const RuleList = "list"
+
+// rule creates a Rule whose pattern is anchored at the start of the
+// paragraph and ignores case.
+func rule(name, pattern string) Rule {
+	return Rule{Name: name, Pattern: anchor(pattern)}
+}
+
+// anchor compiles pattern to match only at the start of the text,
+// ignoring case.
+func anchor(pattern string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\A(?:` + pattern + `)`)
+}
+
+// bare ends a pattern for a word that is a footer only when it stands
+// alone, as a link label, or before a link: "Unsubscribe here." or
+// "Abmelden: https://…", but not "Unsubscribe requests have doubled".
+const bare = `( here| hier)?\s*([.:|<(\[]|https?://|$)`
+
+// DefaultRules are the built-in rules: legal disclaimers and mailing list
+// footers in several languages.
+var DefaultRules = []Rule{
+	// Legal disclaimers
+	rule("disclaimer-en", `(confidentiality notice|confidentiality statement|legal notice|privileged (and|&) confidential)\b|`+
+		`this (e-?mail|message|communication|transmission)( and any (attachments?|files?)[^.]{0,80})? (is|are|may be|may contain|contains?) (strictly )?(confidential|privileged|intended (only |solely |exclusively )?for the (sole |exclusive )?(use of the )?(named |intended )?(addressee|recipient|individual|person|entity))|`+
+		`the (information|contents?) (contained )?in this (e-?mail|message|communication|transmission)|`+
+		`if you are not the (intended|named|designated) (recipient|addressee)|`+
+		`if you have received this (e-?mail|message|communication) in error|`+
+		`please consider the environment before printing`),
+	rule("disclaimer-de", `(vertraulichkeitshinweis|haftungsausschluss|rechtlicher hinweis)\b|`+
+		`diese (e-?mail|nachricht)[^.]{0,80} (enthält|ist) vertraulich|`+
+		`(wenn|falls) sie nicht der (richtige|vorgesehene|beabsichtigte) (adressat|empfänger)|`+
+		`sollten sie diese (e-?mail|nachricht) (irrtümlich|fälschlicherweise)`),
+	rule("disclaimer-fr", `avis de confidentialité|clause de confidentialité|`+
+		`ce (message|courriel|e-?mail)[^.]{0,80} (est|sont|peut contenir|contient)[^.]{0,40}confidenti|`+
+		`si vous n'êtes pas (le|la|les) destinataire|`+
+		`si vous avez reçu ce (message|courriel|e-?mail) par erreur`),
+	rule("disclaimer-es", `(aviso legal|aviso de confidencialidad|cláusula de confidencialidad)\b|`+
+		`este (mensaje|correo)[^.]{0,80} (es|son|contiene|puede contener)[^.]{0,40}confidencial|`+
+		`si (usted )?no es el destinatario|`+
+		`si (usted )?(ha recibido|recibió) este (mensaje|correo) por error`),
+	rule("disclaimer-it", `(avviso di riservatezza|clausola di riservatezza)\b|`+
+		`questo (messaggio|e-?mail)[^.]{0,80} (è|sono|contiene|può contenere)[^.]{0,40}(riservat|confidenzial)|`+
+		`le informazioni contenute in questo (messaggio|e-?mail)|`+
+		`se (avete|hai) ricevuto questo (messaggio|e-?mail) per errore`),
+	rule("disclaimer-pt", `(aviso legal|aviso de confidencialidade)\b|`+
+		`esta (mensagem|e-?mail)[^.]{0,80} (é|são|contém|pode conter)[^.]{0,40}confidencia|`+
+		`se (você )?não (for|é) o destinatário`),
+	rule("disclaimer-nl", `dit (e-?mail)?bericht[^.]{0,80} (is|bevat|kan)[^.]{0,40}vertrouwelijk|`+
+		`indien (u|je) niet de (beoogde )?geadresseerde`),
+	rule("disclaimer-ja", `(このメール|本メール)(には|は).{0,80}(機密|秘密)`),
+	rule("disclaimer-zh", `(本邮件|此邮件|本郵件|此郵件).{0,80}(保密|机密|機密)`),
+
+	// Mailing list and newsletter footers
+	rule("list-footer-en", `you (are receiving|received|are getting) this (message|e-?mail|because)|`+
+		`to unsubscribe( from this (group|list))?\b|`+
+		`(unsubscribe|manage (your )?subscription|update your (email )?preferences|view this (e-?mail )?in your browser)`+bare+`|`+
+		`this (message|e-?mail) was sent (to|by)|`+
+		`\S[^@]{0,80} mailing list \S+@\S+`),
+	rule("list-footer-de", `sie erhalten diese (e-?mail|nachricht)|(hier |zum )?abmelden`+bare+`|newsletter abbestellen`),
+	rule("list-footer-fr", `vous (recevez|avez reçu) ce (message|courriel|e-?mail)|pour (vous|se) désabonner|((se )?désabonner|désinscription)`+bare),
+	rule("list-footer-es", `(recibiste|has recibido|recibes|ha recibido|recibe) este (mensaje|correo)|para (darte|darse) de baja|cancelar (la )?suscripción`),
+	rule("list-footer-it", `(hai|ha) ricevuto questo (messaggio|e-?mail)|per annullare l'iscrizione|annulla (l'iscrizione|iscrizione)`),
+	rule("list-footer-pt", `(você recebeu|recebeu) esta (mensagem|e-?mail)|para cancelar (a )?(sua )?inscrição|cancelar inscrição`),
+	rule("list-footer-nl", `(je|u) ontvangt (dit bericht|deze e-?mail)|(hier )?(afmelden|uitschrijven)`+bare),
+	rule("list-footer-ja", `配信停止|このメールは.{0,40}配信`),
+}
@@ -11,6 +11,23 @@
 //   - Handles malformed HTML gracefully using the HTML5 tokenizer
 //   - Returns data in configurable blocks (default 1024 bytes)
 //
+// With WithReadableText the output is laid out for reading instead: list
+// items are bulleted or numbered, <pre> and <code> keep their whitespace,
+// blockquotes are marked with "> ", table rows become lines with
+// tab-separated cells, and hidden elements are skipped. The <title> is kept
+// out of the text and made available through Title. WithLinkURLs adds link
+// targets after their text.
+//
+// WithRegions finds the quote and signature regions that mail clients mark
+// in HTML (div.gmail_quote, blockquote[type=cite], div.gmail_signature and
+// so on; see DefaultRegions) and either drops them or tags them with the
+// plain text conventions the elider understands, since the markers are lost
+// once the HTML is stripped. Regions are matched with simple selectors; see
+// ParseSelector.
+//
+// WithMaxDepth guards against deeply nested markup: Next fails with
+// ErrMaxDepth once more elements are open than the limit allows.
+//
 // The processor is designed for memory efficiency - it does not build a DOM tree
 // and processes tokens in a streaming fashion.
 //
@@ -0,0 +1,27 @@
+package htmlstrip
+
+// Error types for htmlstrip.
+
+import (
+	"fmt"
+)
+
+// ErrInvalidSelector indicates a region selector that could not be parsed.
+type ErrInvalidSelector struct {
+	Selector string
+	Reason   string
+}
+
+func (e ErrInvalidSelector) Error() string {
+	return fmt.Sprintf("invalid selector %q: %s", e.Selector, e.Reason)
+}
+
+// ErrMaxDepth indicates HTML with more elements open at once than the
+// limit set by WithMaxDepth.
+type ErrMaxDepth struct {
+	Max int
+}
+
+func (e ErrMaxDepth) Error() string {
+	return fmt.Sprintf("HTML nested more than %d elements deep", e.Max)
+}
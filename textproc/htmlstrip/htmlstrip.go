@@ -17,6 +17,45 @@ type Processor struct {
 	buf       strings.Builder
 	done      bool
 	skipTag   string // tag name being skipped ("script", "style", or "")
+
+	// Offset mapping
+	offsets    textproc.OffsetMap
+	inPos      int64 // Input offset of the next token
+	tokenStart int64 // Input offset of the current token
+	markAt     int64 // Input offset for the next text written in readable mode
+	markNext   bool  // Record markAt when text is next written
+	outBase    int64 // Output offset of buf[0]
+
+	// Quote and signature regions
+	regions      []Region
+	regionAction RegionAction
+	regionKind   RegionKind // Kind of region being read, or zero
+	regionTag    string     // Tag that opened the region
+	regionDepth  int        // Open elements named regionTag within the region
+	regionToEnd  bool       // Region runs to the end of the document
+	dropRest     bool       // A dropped ToEnd region was seen (readable mode)
+
+	// Nesting limit
+	maxDepth int      // Most open elements allowed, or zero for no limit
+	open     []string // Open elements, tracked only with a limit
+	err      error    // Error that stopped processing
+
+	// Readable mode
+	readable      bool
+	linkURLs      bool
+	stack         []element // Open elements
+	hiddenDepth   int       // Open hidden elements
+	verbatimDepth int       // Open <pre>, <code> and <textarea> elements
+	linkDepth     int       // Open <a> elements
+	regionOpen    int       // Open elements starting a quote or signature region
+	title         strings.Builder
+	inTitle       bool
+	linkText      strings.Builder // Text of the innermost open link
+	quoteDepth    int
+	atLineStart   bool
+	blankLines    int  // Consecutive newlines at the end of the output
+	pendingSpace  bool // Collapsed whitespace not yet written
+	lastByte      byte // Last byte written, zero before any text
 }
 
 // Option configures a Processor.
@@ -29,6 +68,52 @@ func WithBlockSize(n int) Option {
 	}
 }
 
+// WithReadableText produces text laid out for reading rather than plain
+// stripped text: list items get bullets or numbers, <pre> and <code> keep
+// their whitespace, blockquotes are marked with "> ", table rows become lines
+// with tab-separated cells, and hidden elements (the hidden attribute,
+// aria-hidden="true", display:none and visibility:hidden) are skipped. The
+// document <title> is not included in the text; see Title.
+func WithReadableText() Option {
+	return func(p *Processor) {
+		p.readable = true
+		p.atLineStart = true
+	}
+}
+
+// WithLinkURLs writes the target of each http, https or mailto link after
+// its text, as "text <url>", unless the text is the URL. It only has an
+// effect with WithReadableText.
+func WithLinkURLs() Option {
+	return func(p *Processor) {
+		p.linkURLs = true
+	}
+}
+
+// WithRegions drops or tags the quote and signature regions of HTML email
+// matched by the given regions, so reply text can be isolated before the
+// HTML markers are lost. With no regions, DefaultRegions are used. A region
+// nested inside another is treated as part of the outer one.
+func WithRegions(action RegionAction, regions ...Region) Option {
+	return func(p *Processor) {
+		if len(regions) == 0 {
+			regions = DefaultRegions
+		}
+		p.regions = regions
+		p.regionAction = action
+	}
+}
+
+// WithMaxDepth fails with ErrMaxDepth when more than n elements are open at
+// once, so deeply nested markup cannot slow the stripping down. End tags
+// that HTML lets authors leave out, such as those of <p> and <li>, are
+// implied where they would be. Zero, the default, means no limit.
+func WithMaxDepth(n int) Option {
+	return func(p *Processor) {
+		p.maxDepth = n
+	}
+}
+
 // processorReader adapts a StringProcessor to an io.Reader for use with html.Tokenizer.
 type processorReader struct {
 	proc *Processor
@@ -75,18 +160,25 @@ func (p *Processor) Next() (string, error) {
 		p.tokenizer = html.NewTokenizer(&processorReader{proc: p})
 	}
 
+	if p.err != nil {
+		return "", p.err
+	}
 	if p.done && p.buf.Len() == 0 {
 		return "", io.EOF
 	}
+	if p.readable {
+		return p.nextReadable()
+	}
 
 	for {
 		if p.buf.Len() >= p.blockSize {
 			result := strings.TrimRight(p.buf.String(), " \t\n\r")
+			p.outBase += int64(len(result))
 			p.buf.Reset()
 			return result, nil
 		}
 
-		tokenType := p.tokenizer.Next()
+		tokenType := p.nextToken()
 		switch tokenType {
 		case html.ErrorToken:
 			err := p.tokenizer.Err()
@@ -105,29 +197,27 @@ func (p *Processor) Next() (string, error) {
 			// Only add text if we're not inside a script or style tag
 			if p.skipTag == "" {
 				text := string(p.tokenizer.Text())
-				p.buf.WriteString(text)
+				p.mark()
+				p.emit(text)
 			}
 
 		case html.StartTagToken:
-			tagName, _ := p.tokenizer.TagName()
+			tagName, hasAttr := p.tokenizer.TagName()
 			tag := string(tagName)
+			if err := p.enter(tag); err != nil {
+				return "", err
+			}
+			attrs := p.tagAttrs(hasAttr)
+			p.startRegion(tag, attrs)
 			// Check for script/style tags
 			if tag == "script" || tag == "style" {
 				if p.skipTag == "" {
 					p.skipTag = tag
 				}
-			} else if tag == "img" {
+			} else if tag == "img" && attrs["alt"] != "" {
 				// Extract alt attribute from img tags
-				for {
-					key, val, more := p.tokenizer.TagAttr()
-					if string(key) == "alt" && string(val) != "" {
-						p.buf.WriteString(string(val))
-						break
-					}
-					if !more {
-						break
-					}
-				}
+				p.mark()
+				p.emit(attrs["alt"])
 			}
 
 		case html.EndTagToken:
@@ -138,11 +228,13 @@ func (p *Processor) Next() (string, error) {
 				p.skipTag = ""
 			} else if p.isCellTag(tag) {
 				// Insert tab separator after table cells
-				p.buf.WriteString("\t")
+				p.emit("\t")
 			} else if p.isBlockTag(tag) {
 				// Insert newline after block elements
-				p.buf.WriteString("\n")
+				p.emit("\n")
 			}
+			p.endRegion(tag)
+			p.leave(tag)
 
 		case html.SelfClosingTagToken:
 			tagName, _ := p.tokenizer.TagName()
@@ -152,7 +244,8 @@ func (p *Processor) Next() (string, error) {
 				for {
 					key, val, more := p.tokenizer.TagAttr()
 					if string(key) == "alt" && string(val) != "" {
-						p.buf.WriteString(string(val))
+						p.mark()
+						p.emit(string(val))
 						break
 					}
 					if !more {
@@ -161,7 +254,7 @@ func (p *Processor) Next() (string, error) {
 				}
 			} else if tag == "br" || tag == "hr" {
 				// Insert newline after br and hr
-				p.buf.WriteString("\n")
+				p.emit("\n")
 			}
 
 		case html.CommentToken, html.DoctypeToken:
@@ -170,6 +263,149 @@ func (p *Processor) Next() (string, error) {
 	}
 }
 
+// impliedEnds maps elements whose end tag may be left out to the elements
+// whose start closes them when they are the innermost open element.
+var impliedEnds = map[string]func(next string) bool{
+	"p":      func(next string) bool { return next == "p" || paragraphElements[next] || lineElements[next] },
+	"li":     func(next string) bool { return next == "li" },
+	"dt":     func(next string) bool { return next == "dt" || next == "dd" },
+	"dd":     func(next string) bool { return next == "dt" || next == "dd" },
+	"option": func(next string) bool { return next == "option" },
+	"tr":     func(next string) bool { return next == "tr" },
+	"td":     func(next string) bool { return next == "td" || next == "th" || next == "tr" },
+	"th":     func(next string) bool { return next == "td" || next == "th" || next == "tr" },
+}
+
+// enter records a start tag against the nesting limit, failing with
+// ErrMaxDepth when it is exceeded.
+func (p *Processor) enter(tag string) error {
+	if p.maxDepth <= 0 || voidElements[tag] {
+		return nil
+	}
+	for len(p.open) > 0 {
+		if closes, ok := impliedEnds[p.open[len(p.open)-1]]; !ok || !closes(tag) {
+			break
+		}
+		p.open = p.open[:len(p.open)-1]
+	}
+	p.open = append(p.open, tag)
+	if len(p.open) > p.maxDepth {
+		p.err = ErrMaxDepth{Max: p.maxDepth}
+		return p.err
+	}
+	return nil
+}
+
+// leave records an end tag, closing the elements inside the one it ends.
+// Stray end tags are ignored.
+func (p *Processor) leave(tag string) {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		if p.open[i] == tag {
+			p.open = p.open[:i]
+			return
+		}
+	}
+}
+
+// nextToken reads the next token, tracking its input offset.
+func (p *Processor) nextToken() html.TokenType {
+	tokenType := p.tokenizer.Next()
+	p.tokenStart = p.inPos
+	p.inPos += int64(len(p.tokenizer.Raw()))
+	return tokenType
+}
+
+// mark records that the next output byte comes from the current token, or
+// from markAt in readable mode.
+func (p *Processor) mark() {
+	in := p.tokenStart
+	if p.markNext {
+		in = p.markAt
+	}
+	p.offsets.Add(p.outBase+int64(p.buf.Len()), in)
+}
+
+// Offsets maps offsets in the stripped text back to the HTML input. Text
+// maps to the start of the text token or tag it came from; positions within
+// a token are approximate where it contains character references.
+func (p *Processor) Offsets() *textproc.OffsetMap {
+	return &p.offsets
+}
+
+// tagAttrs reads the attributes of the current tag.
+func (p *Processor) tagAttrs(hasAttr bool) map[string]string {
+	attrs := map[string]string{}
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = p.tokenizer.TagAttr()
+		attrs[string(key)] = string(val)
+	}
+	return attrs
+}
+
+// emit writes text in the default mode, dropping or marking it when inside
+// a quote or signature region.
+func (p *Processor) emit(s string) {
+	if p.regionKind != 0 && p.regionAction == RegionDrop {
+		return
+	}
+	if p.regionKind == RegionQuote && p.regionAction == RegionTag {
+		s = strings.ReplaceAll(s, "\n", "\n> ")
+	}
+	p.buf.WriteString(s)
+}
+
+// startRegion opens a region if the element matches one, or counts the
+// element if it has the same name as the one that opened the current region.
+func (p *Processor) startRegion(tag string, attrs map[string]string) {
+	if p.regionKind != 0 {
+		if tag == p.regionTag {
+			p.regionDepth++
+		}
+		return
+	}
+	r, ok := p.matchRegion(tag, attrs)
+	if !ok {
+		return
+	}
+	p.regionKind = r.Kind
+	p.regionTag = tag
+	p.regionDepth = 1
+	p.regionToEnd = r.ToEnd
+	if p.regionAction != RegionTag {
+		return
+	}
+	switch r.Kind {
+	case RegionQuote:
+		p.emit("\n")
+	case RegionSignature:
+		p.buf.WriteString("\n-- \n")
+	}
+}
+
+// endRegion closes the current region when its opening element ends.
+func (p *Processor) endRegion(tag string) {
+	if p.regionKind == 0 || p.regionToEnd || tag != p.regionTag {
+		return
+	}
+	p.regionDepth--
+	if p.regionDepth > 0 {
+		return
+	}
+	if p.regionKind == RegionQuote && p.regionAction == RegionTag {
+		p.buf.WriteString("\n")
+	}
+	p.regionKind = 0
+	p.regionTag = ""
+}
+
+// Title returns the text of the document's <title> element, with
+// whitespace collapsed. It is only collected with WithReadableText, and is
+// complete once the title element has been read (usually by the first Next).
+func (p *Processor) Title() string {
+	return strings.Join(strings.Fields(p.title.String()), " ")
+}
+
 // isCellTag returns true if the tag is a table cell element
 func (p *Processor) isCellTag(tag string) bool {
 	return tag == "td" || tag == "th"
@@ -186,3 +422,9 @@ func (p *Processor) isBlockTag(tag string) bool {
 		return false
 	}
 }
+
+// Ensure Processor implements StringProcessor and OffsetMapper
+var (
+	_ textproc.StringProcessor = (*Processor)(nil)
+	_ textproc.OffsetMapper    = (*Processor)(nil)
+)
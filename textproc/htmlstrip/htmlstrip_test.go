@@ -1,6 +1,7 @@
 package htmlstrip
 
 import (
+	"errors"
 	"io"
 	"strings"
 	"testing"
@@ -452,3 +453,54 @@ func TestNestedContentInCells(t *testing.T) {
 		t.Fatalf("expected io.EOF on second Next(), got %v", err)
 	}
 }
+
+// readErr converts input and returns the error that stopped it, or nil.
+func readErr(input string, opts ...Option) error {
+	p := NewProcessor(textproc.NewBytesToStringAdapter(reader.New(strings.NewReader(input))), opts...)
+	for {
+		if _, err := p.Next(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	deep := strings.Repeat("<div>", 100) + "text" + strings.Repeat("</div>", 100)
+	for _, opts := range [][]Option{{WithMaxDepth(50)}, {WithMaxDepth(50), WithReadableText()}} {
+		p := NewProcessor(textproc.NewBytesToStringAdapter(reader.New(strings.NewReader(deep))), opts...)
+		_, err := p.Next()
+		var depthErr ErrMaxDepth
+		if !errors.As(err, &depthErr) || depthErr.Max != 50 {
+			t.Fatalf("expected ErrMaxDepth{50}, got %v", err)
+		}
+		if _, err := p.Next(); !errors.As(err, &depthErr) {
+			t.Errorf("expected the error again, got %v", err)
+		}
+	}
+	if err := readErr(deep, WithMaxDepth(100)); err != nil {
+		t.Errorf("expected nesting at the limit to pass, got %v", err)
+	}
+	if err := readErr(deep); err != nil {
+		t.Errorf("expected no limit by default, got %v", err)
+	}
+}
+
+func TestMaxDepthCountsOpenElements(t *testing.T) {
+	tests := map[string]string{
+		"siblings":      strings.Repeat("<div><b>text</b><br><img src=x></div>", 100),
+		"implied p":     "<div>" + strings.Repeat("<p>para", 100) + "</div>",
+		"implied li":    "<ul>" + strings.Repeat("<li>item", 100) + "</ul>",
+		"implied cells": "<table>" + strings.Repeat("<tr><td>a<td>b", 100) + "</table>",
+		"p before div":  strings.Repeat("<p>para<div>block</div>", 100),
+		"unclosed":      "<div><span>" + strings.Repeat("<i>x</i></span>", 100) + "</div>",
+	}
+	for name, input := range tests {
+		for _, opts := range [][]Option{{WithMaxDepth(5)}, {WithMaxDepth(5), WithReadableText()}} {
+			if err := readErr(input, opts...); err != nil {
+				t.Errorf("%s: unexpected error: %v", name, err)
+			}
+		}
+	}
+}
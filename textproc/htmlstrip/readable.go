@@ -0,0 +1,453 @@
+package htmlstrip
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// element is an open element in readable mode.
+type element struct {
+	tag     string
+	hidden  bool   // Element and its content are not shown
+	href    string // Link target for <a>
+	ordered bool   // <ol> rather than <ul>
+	counter int    // Next item number for <ol>
+	cells   int    // Cells seen so far in a <tr>
+	region  bool   // Starts a quote or signature region
+	quote   bool   // Tagged quote region, shown like a blockquote
+	toEnd   bool   // Region continues after the element ends
+}
+
+// voidElements never have content or an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// paragraphElements are separated from surrounding text by a blank line.
+var paragraphElements = map[string]bool{
+	"p": true, "blockquote": true, "pre": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// lineElements start and end on their own line.
+var lineElements = map[string]bool{
+	"div": true, "li": true, "ul": true, "ol": true, "dl": true, "dt": true, "dd": true,
+	"tr": true, "header": true, "footer": true, "section": true, "article": true,
+	"aside": true, "nav": true, "main": true, "figure": true, "figcaption": true,
+	"address": true, "fieldset": true, "form": true, "details": true, "summary": true,
+	"center": true,
+}
+
+// skippedElements never contribute text.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "head": true, "template": true,
+}
+
+// nextReadable is Next for readable mode.
+func (p *Processor) nextReadable() (string, error) {
+	for {
+		if p.buf.Len() >= p.blockSize {
+			result := p.buf.String()
+			p.outBase += int64(len(result))
+			p.buf.Reset()
+			return result, nil
+		}
+
+		tokenType := p.nextToken()
+		switch tokenType {
+		case html.ErrorToken:
+			err := p.tokenizer.Err()
+			if err == io.EOF {
+				p.done = true
+				result := strings.TrimRight(p.buf.String(), " \t\n\r")
+				p.buf.Reset()
+				if result != "" {
+					return result, nil
+				}
+				return "", io.EOF
+			}
+			return "", err
+
+		case html.TextToken:
+			p.readableText(string(p.tokenizer.Text()))
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := p.tokenizer.TagName()
+			tag := string(name)
+			if tokenType == html.StartTagToken {
+				if err := p.enter(tag); err != nil {
+					return "", err
+				}
+			}
+			p.readableStart(tag, p.tagAttrs(hasAttr), tokenType == html.SelfClosingTagToken)
+
+		case html.EndTagToken:
+			name, _ := p.tokenizer.TagName()
+			tag := string(name)
+			p.readableEnd(tag)
+			p.leave(tag)
+
+		case html.CommentToken, html.DoctypeToken:
+			// Skip these tokens
+		}
+	}
+}
+
+// hidden reports whether text at the current position is not shown.
+func (p *Processor) hidden() bool {
+	return p.dropRest || p.hiddenDepth > 0
+}
+
+// verbatim reports whether whitespace at the current position is kept.
+func verbatim(tag string) bool {
+	return tag == "pre" || tag == "code" || tag == "textarea"
+}
+
+// push opens e, counting it in the depths it affects.
+func (p *Processor) push(e element) {
+	p.stack = append(p.stack, e)
+	p.count(e, 1)
+}
+
+// pop closes the innermost open element and returns it.
+func (p *Processor) pop() element {
+	e := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	p.count(e, -1)
+	return e
+}
+
+// count adds n to the depths e is counted in.
+func (p *Processor) count(e element, n int) {
+	if e.hidden {
+		p.hiddenDepth += n
+	}
+	if verbatim(e.tag) {
+		p.verbatimDepth += n
+	}
+	if e.tag == "a" {
+		p.linkDepth += n
+	}
+	if e.region {
+		p.regionOpen += n
+	}
+}
+
+// isHidden reports whether an element's attributes hide it.
+func isHidden(tag string, attrs map[string]string) bool {
+	if skippedElements[tag] {
+		return true
+	}
+	if _, ok := attrs["hidden"]; ok {
+		return true
+	}
+	if strings.EqualFold(attrs["aria-hidden"], "true") {
+		return true
+	}
+	style := strings.ToLower(strings.Join(strings.Fields(attrs["style"]), ""))
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+func (p *Processor) readableStart(tag string, attrs map[string]string, selfClosing bool) {
+	if !voidElements[tag] {
+		// Close elements whose end tag this start implies, such as an
+		// unclosed <p> or <li>
+		for len(p.stack) > 0 {
+			if closes, ok := impliedEnds[p.stack[len(p.stack)-1].tag]; !ok || !closes(tag) {
+				break
+			}
+			p.closeTop()
+		}
+	}
+	if tag == "title" && !selfClosing {
+		p.push(element{tag: tag, hidden: true})
+		p.inTitle = true
+		return
+	}
+	hidden := isHidden(tag, attrs)
+	if voidElements[tag] || selfClosing {
+		if hidden || p.hidden() {
+			return
+		}
+		switch tag {
+		case "br":
+			p.write("\n")
+		case "hr":
+			p.blockBreak(true)
+			p.write("---")
+			p.blockBreak(true)
+		case "img":
+			if alt := attrs["alt"]; alt != "" {
+				p.readableText(alt)
+			}
+		}
+		return
+	}
+
+	e := element{tag: tag, hidden: hidden}
+	if !p.hidden() && !hidden && !p.inRegion() {
+		if r, ok := p.matchRegion(tag, attrs); ok {
+			p.readableRegion(&e, r)
+		}
+	}
+	if !p.hidden() && !e.hidden {
+		switch {
+		case tag == "li":
+			p.blockBreak(false)
+			p.listItem(attrs)
+		case tag == "td" || tag == "th":
+			if row := p.nearest("tr"); row != nil {
+				if row.cells > 0 {
+					p.write("\t")
+				}
+				row.cells++
+			}
+		case tag == "a":
+			e.href = attrs["href"]
+			p.linkText.Reset()
+		case paragraphElements[tag]:
+			p.blockBreak(true)
+		case lineElements[tag]:
+			p.blockBreak(false)
+		}
+		if tag == "ol" {
+			e.ordered = true
+			e.counter = 1
+			if start, err := strconv.Atoi(attrs["start"]); err == nil {
+				e.counter = start
+			}
+		}
+	}
+	p.push(e)
+	if (tag == "blockquote" || e.quote) && !p.hidden() {
+		p.quoteDepth++
+	}
+}
+
+func (p *Processor) readableEnd(tag string) {
+	// Find the matching open element; stray end tags are ignored
+	i := len(p.stack) - 1
+	for i >= 0 && p.stack[i].tag != tag {
+		i--
+	}
+	if i < 0 {
+		return
+	}
+
+	// Close everything down to and including the match
+	for len(p.stack) > i {
+		p.closeTop()
+	}
+}
+
+// closeTop closes the innermost open element, ending its block.
+func (p *Processor) closeTop() {
+	wasHidden := p.hidden()
+	e := p.pop()
+	if e.tag == "title" {
+		p.inTitle = false
+		return
+	}
+	if wasHidden {
+		return
+	}
+	switch {
+	case e.tag == "a":
+		p.linkURL(e.href)
+	case e.quote && e.toEnd:
+		// The quote continues to the end of the document
+	case e.tag == "blockquote" || e.quote:
+		p.blockBreak(true)
+		p.quoteDepth--
+	case paragraphElements[e.tag]:
+		p.blockBreak(true)
+	case lineElements[e.tag]:
+		p.blockBreak(false)
+	}
+}
+
+// inRegion reports whether a quote or signature region is open.
+func (p *Processor) inRegion() bool {
+	return p.regionToEnd || p.regionOpen > 0
+}
+
+// readableRegion applies the region action to an element that starts a
+// quote or signature region.
+func (p *Processor) readableRegion(e *element, r Region) {
+	e.region = true
+	p.regionToEnd = r.ToEnd
+	if p.regionAction == RegionDrop {
+		e.hidden = true
+		p.dropRest = r.ToEnd
+		return
+	}
+	p.blockBreak(true)
+	switch r.Kind {
+	case RegionQuote:
+		e.quote = true
+		e.toEnd = r.ToEnd
+	case RegionSignature:
+		p.write("-- \n")
+	}
+}
+
+// listItem writes the bullet or number for an <li>.
+func (p *Processor) listItem(attrs map[string]string) {
+	depth := 0
+	var list *element
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].tag == "ul" || p.stack[i].tag == "ol" {
+			if list == nil {
+				list = &p.stack[i]
+			}
+			depth++
+		}
+	}
+	if depth > 1 {
+		p.write(strings.Repeat("  ", depth-1))
+	}
+	if list == nil || !list.ordered {
+		p.write("- ")
+		return
+	}
+	if v, err := strconv.Atoi(attrs["value"]); err == nil {
+		list.counter = v
+	}
+	p.write(strconv.Itoa(list.counter) + ". ")
+	list.counter++
+}
+
+// linkURL writes the target of a link after its text when link URLs are
+// enabled and the text does not already show it.
+func (p *Processor) linkURL(href string) {
+	if !p.linkURLs || href == "" {
+		return
+	}
+	lower := strings.ToLower(href)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "mailto:") {
+		return
+	}
+	text := strings.TrimSpace(p.linkText.String())
+	if text == href || "mailto:"+text == href || text == "" {
+		return
+	}
+	p.write(" <" + href + ">")
+}
+
+// nearest returns the innermost open element with the given tag.
+func (p *Processor) nearest(tag string) *element {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].tag == tag {
+			return &p.stack[i]
+		}
+	}
+	return nil
+}
+
+// readableText writes a text token, collapsing whitespace outside <pre>
+// and <code>.
+func (p *Processor) readableText(text string) {
+	if p.inTitle {
+		p.title.WriteString(text)
+		return
+	}
+	if p.hidden() {
+		return
+	}
+	// The first text written maps to the first non-space of the token
+	p.markNext = true
+	p.markAt = p.tokenStart + int64(len(text)-len(strings.TrimLeft(text, " \t\n\r\f")))
+	defer func() { p.markNext = false }()
+	if p.verbatimDepth > 0 {
+		p.write(text)
+		return
+	}
+
+	var b strings.Builder
+	lineHasText := !p.atLineStart && p.lastByte != ' ' && p.lastByte != '\t'
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\u00a0' {
+			p.pendingSpace = true
+			continue
+		}
+		if p.pendingSpace && lineHasText {
+			b.WriteByte(' ')
+		}
+		p.pendingSpace = false
+		lineHasText = true
+		b.WriteRune(r)
+	}
+	pending := p.pendingSpace
+	p.write(b.String())
+	p.pendingSpace = pending
+}
+
+// write appends s to the output, adding blockquote markers to each line.
+func (p *Processor) write(s string) {
+	if p.linkDepth > 0 {
+		p.linkText.WriteString(s)
+	}
+	p.pendingSpace = false
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		line := s
+		if i >= 0 {
+			line = s[:i]
+		}
+		if line != "" {
+			if p.atLineStart && p.quoteDepth > 0 {
+				p.buf.WriteString(strings.Repeat(">", p.quoteDepth) + " ")
+			}
+			if p.markNext {
+				if text := strings.TrimLeft(line, " \t"); text != "" {
+					p.buf.WriteString(line[:len(line)-len(text)])
+					p.mark()
+					p.markNext = false
+					line = text
+				}
+			}
+			p.buf.WriteString(line)
+			p.atLineStart = false
+			p.blankLines = 0
+			p.lastByte = line[len(line)-1]
+		}
+		if i < 0 {
+			break
+		}
+		p.newline()
+		s = s[i+1:]
+	}
+}
+
+func (p *Processor) newline() {
+	p.buf.WriteByte('\n')
+	p.atLineStart = true
+	p.blankLines++
+	p.lastByte = '\n'
+}
+
+// blockBreak ends the current line. With blank set it also leaves an empty
+// line, separating paragraphs for the chunker. Breaks never accumulate, and
+// none are written before the first text.
+func (p *Processor) blockBreak(blank bool) {
+	p.pendingSpace = false
+	if p.lastByte == 0 {
+		return
+	}
+	if !p.atLineStart {
+		p.newline()
+		p.blankLines = 1
+	}
+	want := 1
+	if blank {
+		want = 2
+	}
+	for p.blankLines < want {
+		p.newline()
+	}
+}
@@ -0,0 +1,190 @@
+package htmlstrip
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
+)
+
+// readReadable converts input in readable mode and returns all output.
+func readReadable(t *testing.T, input string, opts ...Option) (string, *Processor) {
+	t.Helper()
+	opts = append([]Option{WithReadableText()}, opts...)
+	p := NewProcessor(textproc.NewBytesToStringAdapter(reader.New(strings.NewReader(input))), opts...)
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String(), p
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+func TestReadableLists(t *testing.T) {
+	input := `<ul><li>one</li><li>two<ol start="3"><li>three</li><li>four</li></ol></li></ul>`
+	got, _ := readReadable(t, input)
+	want := "- one\n- two\n  3. three\n  4. four"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadableListItemValue(t *testing.T) {
+	got, _ := readReadable(t, `<ol><li>a</li><li value="10">b</li><li>c</li></ol>`)
+	want := "1. a\n10. b\n11. c"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadablePreservesPre(t *testing.T) {
+	got, _ := readReadable(t, "<p>Run   this:</p><pre>if x {\n    y()\n}</pre><p>done</p>")
+	want := "Run this:\n\nif x {\n    y()\n}\n\ndone"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadableBlockquote(t *testing.T) {
+	got, _ := readReadable(t, "<p>Reply</p><blockquote><p>Original<br>text</p></blockquote><p>End</p>")
+	want := "Reply\n\n> Original\n> text\n\nEnd"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadableLinks(t *testing.T) {
+	input := `<p>See <a href="https://example.com/x">the docs</a>, <a href="https://example.com">https://example.com</a> and <a href="#top">top</a>.</p>`
+
+	got, _ := readReadable(t, input)
+	if want := "See the docs, https://example.com and top."; got != want {
+		t.Errorf("without URLs: expected %q, got %q", want, got)
+	}
+
+	got, _ = readReadable(t, input, WithLinkURLs())
+	if want := "See the docs <https://example.com/x>, https://example.com and top."; got != want {
+		t.Errorf("with URLs: expected %q, got %q", want, got)
+	}
+}
+
+func TestReadableTable(t *testing.T) {
+	got, _ := readReadable(t, "<table><tr><th>Name</th><th>Qty</th></tr><tr><td>Apple</td><td>3</td></tr></table>")
+	want := "Name\tQty\nApple\t3"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadableSkipsHidden(t *testing.T) {
+	input := `<p>shown</p>` +
+		`<div style="display: none">preheader</div>` +
+		`<span hidden>secret</span>` +
+		`<div aria-hidden="true">decoration</div>` +
+		`<p style="visibility:hidden">gone</p>` +
+		`<p>also shown</p>`
+	got, _ := readReadable(t, input)
+	want := "shown\n\nalso shown"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadableImpliedEnds(t *testing.T) {
+	tests := map[string]string{
+		`<p hidden>secret<p>visible text</p><div>more</div>`:   "visible text\n\nmore",
+		`<ul><li hidden>secret<li>one<li>two</ul><p>after</p>`: "- one\n- two\n\nafter",
+		`<p><a href="https://example.com/">link<p>text`:        "link\n\ntext",
+		`<p>one<pre>  two  </pre>three`:                        "one\n\n  two  \n\nthree",
+	}
+	for input, want := range tests {
+		if got, _ := readReadable(t, input); got != want {
+			t.Errorf("%s: expected %q, got %q", input, want, got)
+		}
+	}
+}
+
+func BenchmarkReadableUnclosedListItems(b *testing.B) {
+	input := "<ul>" + strings.Repeat("<li>item", 40000) + "</ul>"
+	for b.Loop() {
+		p := NewProcessor(textproc.NewBytesToStringAdapter(reader.New(strings.NewReader(input))), WithReadableText())
+		for {
+			if _, err := p.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func TestReadableTitle(t *testing.T) {
+	got, p := readReadable(t, "<html><head><title>  Weekly\n  news </title><style>p{}</style></head><body><p>Body</p></body></html>")
+	if got != "Body" {
+		t.Errorf("expected 'Body', got %q", got)
+	}
+	if p.Title() != "Weekly news" {
+		t.Errorf("expected title 'Weekly news', got %q", p.Title())
+	}
+}
+
+func TestReadableHorizontalRule(t *testing.T) {
+	got, _ := readReadable(t, "<p>above</p><hr><p>below</p>")
+	want := "above\n\n---\n\nbelow"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadableSmallBlocks(t *testing.T) {
+	input := "<ul><li>first item</li><li>second item</li></ul>"
+	want, _ := readReadable(t, input)
+	got, _ := readReadable(t, input, WithBlockSize(4))
+	if got != want {
+		t.Errorf("expected %q with small blocks, got %q", want, got)
+	}
+}
+
+func TestDefaultModeUnchanged(t *testing.T) {
+	r := strings.NewReader("<ul><li>one</li></ul>")
+	p := NewProcessor(textproc.NewBytesToStringAdapter(reader.New(r)))
+	got, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "- ") {
+		t.Errorf("expected no bullets in default mode, got %q", got)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	input := "<p>Hello <b>world</b></p><ul><li>item</li></ul>"
+	for _, readable := range []bool{false, true} {
+		var opts []Option
+		if readable {
+			opts = append(opts, WithReadableText())
+		}
+		p := NewProcessor(textproc.NewBytesToStringAdapter(reader.New(strings.NewReader(input))), opts...)
+		var out strings.Builder
+		for {
+			s, err := p.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.WriteString(s)
+		}
+		for _, word := range []string{"Hello", "world", "item"} {
+			o := int64(strings.Index(out.String(), word))
+			if got := p.Offsets().Map(o); got != int64(strings.Index(input, word)) {
+				t.Errorf("readable=%v %q: expected input offset %d, got %d", readable, word, strings.Index(input, word), got)
+			}
+		}
+	}
+}
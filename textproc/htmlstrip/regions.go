@@ -0,0 +1,223 @@
+package htmlstrip
+
+import (
+	"strings"
+)
+
+// RegionKind is what a marked region of an HTML email contains.
+type RegionKind int
+
+const (
+	// RegionQuote is quoted text from an earlier message.
+	RegionQuote RegionKind = iota + 1
+	// RegionSignature is the sender's signature.
+	RegionSignature
+)
+
+// String returns the name of the region kind.
+func (k RegionKind) String() string {
+	switch k {
+	case RegionQuote:
+		return "quote"
+	case RegionSignature:
+		return "signature"
+	default:
+		return "unknown"
+	}
+}
+
+// RegionAction is what happens to the text of a matched region.
+type RegionAction int
+
+const (
+	// RegionDrop removes the region's text from the output.
+	RegionDrop RegionAction = iota
+	// RegionTag keeps the text but marks it using plain text conventions:
+	// quote lines are prefixed with "> " and signatures are preceded by the
+	// "-- " delimiter line, so the elider can recognise them.
+	RegionTag
+)
+
+// Region marks the elements matched by Selector as containing Kind. With
+// ToEnd set, the region continues past the element to the end of the
+// document; this suits clients that put only a header block around the
+// start of the quoted message.
+type Region struct {
+	Selector Selector
+	Kind     RegionKind
+	ToEnd    bool
+}
+
+// DefaultRegions are the quote and signature markers used by common mail
+// clients.
+var DefaultRegions = []Region{
+	// Gmail
+	{Selector: MustParseSelector("div.gmail_quote"), Kind: RegionQuote},
+	{Selector: MustParseSelector("blockquote.gmail_quote"), Kind: RegionQuote},
+	{Selector: MustParseSelector("div.gmail_signature"), Kind: RegionSignature},
+	{Selector: MustParseSelector("div[data-smartmail=gmail_signature]"), Kind: RegionSignature},
+	// Outlook
+	{Selector: MustParseSelector("#divRplyFwdMsg"), Kind: RegionQuote, ToEnd: true},
+	{Selector: MustParseSelector("#appendonsend"), Kind: RegionQuote, ToEnd: true},
+	{Selector: MustParseSelector("#Signature"), Kind: RegionSignature},
+	// Apple Mail and Thunderbird
+	{Selector: MustParseSelector("blockquote[type=cite]"), Kind: RegionQuote},
+	{Selector: MustParseSelector("div.moz-cite-prefix"), Kind: RegionQuote},
+	{Selector: MustParseSelector("#AppleMailSignature"), Kind: RegionSignature},
+	{Selector: MustParseSelector("div.moz-signature"), Kind: RegionSignature},
+	// Yahoo and Proton
+	{Selector: MustParseSelector("div.yahoo_quoted"), Kind: RegionQuote},
+	{Selector: MustParseSelector("div.protonmail_quote"), Kind: RegionQuote},
+	{Selector: MustParseSelector("div.protonmail_signature_block"), Kind: RegionSignature},
+}
+
+// Selector matches an element by tag name, id, classes and attributes. It
+// supports a single compound CSS selector such as "div.gmail_quote",
+// "#divRplyFwdMsg" or "blockquote[type=cite]"; combinators are not
+// supported.
+type Selector struct {
+	text    string
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrMatch
+}
+
+type attrMatch struct {
+	name  string
+	value string
+	exact bool // false matches presence only
+}
+
+// ParseSelector parses a compound selector made of an optional tag name
+// followed by any number of "#id", ".class", "[attr]" and "[attr=value]"
+// parts. Attribute values may be quoted.
+func ParseSelector(s string) (Selector, error) {
+	sel := Selector{text: s}
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return Selector{}, ErrInvalidSelector{Selector: s, Reason: "empty"}
+	}
+
+	name, rest := selectorName(rest)
+	sel.tag = strings.ToLower(name)
+	for rest != "" {
+		switch rest[0] {
+		case '#', '.':
+			kind := rest[0]
+			name, rest = selectorName(rest[1:])
+			if name == "" {
+				return Selector{}, ErrInvalidSelector{Selector: s, Reason: "missing name after " + string(kind)}
+			}
+			if kind == '#' {
+				sel.id = name
+			} else {
+				sel.classes = append(sel.classes, name)
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return Selector{}, ErrInvalidSelector{Selector: s, Reason: "unterminated attribute"}
+			}
+			m, ok := parseAttrMatch(rest[1:end])
+			if !ok {
+				return Selector{}, ErrInvalidSelector{Selector: s, Reason: "bad attribute " + rest[:end+1]}
+			}
+			sel.attrs = append(sel.attrs, m)
+			rest = rest[end+1:]
+		default:
+			return Selector{}, ErrInvalidSelector{Selector: s, Reason: "unexpected " + string(rest[0])}
+		}
+	}
+	return sel, nil
+}
+
+// MustParseSelector is ParseSelector for selectors known to be valid. It
+// panics on error.
+func MustParseSelector(s string) Selector {
+	sel, err := ParseSelector(s)
+	if err != nil {
+		panic(err)
+	}
+	return sel
+}
+
+// String returns the selector as it was written.
+func (s Selector) String() string {
+	return s.text
+}
+
+// Match reports whether an element with the given tag and attributes
+// matches the selector.
+func (s Selector) Match(tag string, attrs map[string]string) bool {
+	if s.tag != "" && s.tag != "*" && s.tag != tag {
+		return false
+	}
+	if s.id != "" && attrs["id"] != s.id {
+		return false
+	}
+	if len(s.classes) > 0 {
+		have := strings.Fields(attrs["class"])
+		for _, c := range s.classes {
+			if !containsString(have, c) {
+				return false
+			}
+		}
+	}
+	for _, m := range s.attrs {
+		v, ok := attrs[m.name]
+		if !ok || (m.exact && v != m.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorName splits a leading name (tag, id or class) from s.
+func selectorName(s string) (name, rest string) {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c == '#' || c == '.' || c == '[' || c == ' ' {
+			break
+		}
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// parseAttrMatch parses the inside of "[name]" or "[name=value]".
+func parseAttrMatch(s string) (attrMatch, bool) {
+	name, value, exact := strings.Cut(s, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return attrMatch{}, false
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return attrMatch{name: name, value: value, exact: exact}, true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRegion returns the first configured region matching an element.
+func (p *Processor) matchRegion(tag string, attrs map[string]string) (Region, bool) {
+	if voidElements[tag] {
+		return Region{}, false
+	}
+	for _, r := range p.regions {
+		if r.Selector.Match(tag, attrs) {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
@@ -0,0 +1,134 @@
+package htmlstrip
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
+)
+
+// readAll converts input and returns all output.
+func readAll(t *testing.T, input string, opts ...Option) string {
+	t.Helper()
+	p := NewProcessor(textproc.NewBytesToStringAdapter(reader.New(strings.NewReader(input))), opts...)
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+const gmailReply = `<div dir="ltr">Sounds good to me.</div>` +
+	`<div class="gmail_signature">Alice<br>Example Corp</div>` +
+	`<div class="gmail_quote"><div class="gmail_attr">On Mon, Bob wrote:</div>` +
+	`<blockquote class="gmail_quote"><div>Shall we meet?</div></blockquote></div>`
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		sel   string
+		tag   string
+		attrs map[string]string
+		want  bool
+	}{
+		{"div.gmail_quote", "div", map[string]string{"class": "x gmail_quote"}, true},
+		{"div.gmail_quote", "span", map[string]string{"class": "gmail_quote"}, false},
+		{"#divRplyFwdMsg", "div", map[string]string{"id": "divRplyFwdMsg"}, true},
+		{"blockquote[type=cite]", "blockquote", map[string]string{"type": "cite"}, true},
+		{"blockquote[type='cite']", "blockquote", map[string]string{"type": "other"}, false},
+		{"[data-x]", "p", map[string]string{"data-x": ""}, true},
+		{"DIV.a.b", "div", map[string]string{"class": "b a"}, true},
+		{"div.a.b", "div", map[string]string{"class": "a"}, false},
+	}
+	for _, tc := range tests {
+		sel, err := ParseSelector(tc.sel)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.sel, err)
+			continue
+		}
+		if got := sel.Match(tc.tag, tc.attrs); got != tc.want {
+			t.Errorf("%q on %s %v: expected %v", tc.sel, tc.tag, tc.attrs, tc.want)
+		}
+	}
+}
+
+func TestParseSelectorInvalid(t *testing.T) {
+	for _, sel := range []string{"", "div .quote", "div[type=cite", "div.", "a > b", "[=x]"} {
+		_, err := ParseSelector(sel)
+		if !errors.As(err, new(ErrInvalidSelector)) {
+			t.Errorf("%q: expected ErrInvalidSelector, got %v", sel, err)
+		}
+	}
+}
+
+func TestRegionsDrop(t *testing.T) {
+	got := readAll(t, gmailReply, WithRegions(RegionDrop))
+	if got != "Sounds good to me." {
+		t.Errorf("expected only the reply, got %q", got)
+	}
+}
+
+func TestRegionsTag(t *testing.T) {
+	got := readAll(t, gmailReply, WithRegions(RegionTag))
+	for _, want := range []string{"Sounds good to me.", "\n-- \nAlice", "\n> On Mon, Bob wrote:", "\n> Shall we meet?"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestRegionsReadableDrop(t *testing.T) {
+	got := readAll(t, gmailReply, WithReadableText(), WithRegions(RegionDrop))
+	if got != "Sounds good to me." {
+		t.Errorf("expected only the reply, got %q", got)
+	}
+}
+
+func TestRegionsReadableTag(t *testing.T) {
+	got := readAll(t, gmailReply, WithReadableText(), WithRegions(RegionTag))
+	want := "Sounds good to me.\n\n-- \nAlice\nExample Corp\n\n> On Mon, Bob wrote:\n\n>> Shall we meet?"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRegionsOutlookToEnd(t *testing.T) {
+	input := `<p>Thanks!</p><hr><div id="divRplyFwdMsg"><b>From:</b> Bob</div><div>Original body</div>`
+	if got := readAll(t, input, WithRegions(RegionDrop)); got != "Thanks!" {
+		t.Errorf("default mode: expected 'Thanks!', got %q", got)
+	}
+	if got := readAll(t, input, WithReadableText(), WithRegions(RegionDrop)); got != "Thanks!\n\n---" {
+		t.Errorf("readable mode: expected 'Thanks!' and rule, got %q", got)
+	}
+}
+
+func TestRegionsNestedSameTag(t *testing.T) {
+	input := `<div class="gmail_quote"><div>inner</div>still quoted</div><div>after</div>`
+	got := readAll(t, input, WithRegions(RegionDrop))
+	if got != "after" {
+		t.Errorf("expected 'after', got %q", got)
+	}
+}
+
+func TestRegionsCustom(t *testing.T) {
+	input := `<p>keep</p><div class="old">drop</div>`
+	got := readAll(t, input, WithRegions(RegionDrop, Region{Selector: MustParseSelector(".old"), Kind: RegionQuote}))
+	if got != "keep" {
+		t.Errorf("expected 'keep', got %q", got)
+	}
+}
+
+func TestRegionsOffByDefault(t *testing.T) {
+	got := readAll(t, gmailReply)
+	if !strings.Contains(got, "Shall we meet?") {
+		t.Errorf("expected quote kept without WithRegions, got %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package textproc
+
+import (
+	"io"
+	"iter"
+)
+
+// All returns an iterator over the chunk slices from c, for use with
+// range-over-func. It ends at io.EOF. Any other error is yielded once, with
+// a nil slice, and ends the iteration. Ranging over it again continues
+// where the last range stopped.
+func All(c ChunkCombiner) iter.Seq2[ChunkSlice, error] {
+	return all(c.Next)
+}
+
+// AllChunks returns an iterator over the chunks from p, as All does.
+func AllChunks(p ChunkProcessor) iter.Seq2[Chunk, error] {
+	return all(p.Next)
+}
+
+// AllStrings returns an iterator over the blocks from p, as All does.
+func AllStrings(p StringProcessor) iter.Seq2[string, error] {
+	return all(p.Next)
+}
+
+// AllBytes returns an iterator over the blocks from p, as All does.
+func AllBytes(p BytesProcessor) iter.Seq2[[]byte, error] {
+	return all(p.Next)
+}
+
+// all turns a pull-style Next method into an iterator.
+func all[T any](next func() (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			v, err := next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SeqProcessor is a processor reading from an iterator, so a sequence
+// can feed a pipeline stage. A SeqProcessor[[]byte] is a BytesProcessor, a
+// SeqProcessor[string] a StringProcessor, a SeqProcessor[Chunk] a
+// ChunkProcessor and a SeqProcessor[ChunkSlice] a ChunkCombiner.
+//
+// Call Stop if the processor is abandoned before Next returns io.EOF, to
+// release the iterator.
+type SeqProcessor[T any] struct {
+	next func() (T, error, bool)
+	stop func()
+}
+
+// FromSeq creates a SeqProcessor returning the values of seq.
+func FromSeq[T any](seq iter.Seq[T]) *SeqProcessor[T] {
+	return FromSeq2(func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	})
+}
+
+// FromSeq2 creates a SeqProcessor returning the values of seq. A value
+// paired with an error is dropped, and Next returns the error instead.
+func FromSeq2[T any](seq iter.Seq2[T, error]) *SeqProcessor[T] {
+	next, stop := iter.Pull2(seq)
+	return &SeqProcessor[T]{next: next, stop: stop}
+}
+
+// Next returns the next value from the iterator.
+// Returns io.EOF when the iterator is exhausted.
+func (p *SeqProcessor[T]) Next() (T, error) {
+	v, err, ok := p.next()
+	var zero T
+	if !ok {
+		return zero, io.EOF
+	}
+	if err != nil {
+		return zero, err
+	}
+	return v, nil
+}
+
+// Stop releases the iterator. Next returns io.EOF afterwards.
+func (p *SeqProcessor[T]) Stop() {
+	p.stop()
+}
+
+// CollectAll gathers the values of seq into a slice, stopping with the
+// first error. When max is positive and seq has more than max values, it
+// stops with ErrTooMany rather than reading on, and returns the first max.
+func CollectAll[T any](seq iter.Seq2[T, error], max int) ([]T, error) {
+	var values []T
+	for v, err := range seq {
+		if err != nil {
+			return values, err
+		}
+		if max > 0 && len(values) == max {
+			return values, ErrTooMany{Max: max}
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Ensure SeqProcessor implements the processor interfaces
+var (
+	_ BytesProcessor  = (*SeqProcessor[[]byte])(nil)
+	_ StringProcessor = (*SeqProcessor[string])(nil)
+	_ ChunkProcessor  = (*SeqProcessor[Chunk])(nil)
+	_ ChunkCombiner   = (*SeqProcessor[ChunkSlice])(nil)
+)
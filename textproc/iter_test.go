@@ -0,0 +1,151 @@
+package textproc
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+// failingStringProcessor returns its blocks, then err.
+type failingStringProcessor struct {
+	data []string
+	err  error
+}
+
+func (p *failingStringProcessor) Next() (string, error) {
+	if len(p.data) == 0 {
+		return "", p.err
+	}
+	s := p.data[0]
+	p.data = p.data[1:]
+	return s, nil
+}
+
+func TestAll(t *testing.T) {
+	c := newTestChunkCombiner([]ChunkSlice{{"a", "b"}, {"c"}})
+	var got []ChunkSlice
+	for slice, err := range All(c) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, slice)
+	}
+	if len(got) != 2 || !slices.Equal(got[0], ChunkSlice{"a", "b"}) || !slices.Equal(got[1], ChunkSlice{"c"}) {
+		t.Errorf("expected [[a b] [c]], got %v", got)
+	}
+}
+
+func TestAllChunksAndBytes(t *testing.T) {
+	chunks, err := CollectAll(AllChunks(newTestChunkProcessor([]Chunk{"a", "b"})), 0)
+	if err != nil || !slices.Equal(chunks, []Chunk{"a", "b"}) {
+		t.Errorf("expected [a b], got %v, %v", chunks, err)
+	}
+	blocks, err := CollectAll(AllBytes(newTestBytesProcessor([][]byte{[]byte("x")})), 0)
+	if err != nil || len(blocks) != 1 || string(blocks[0]) != "x" {
+		t.Errorf("expected [x], got %q, %v", blocks, err)
+	}
+}
+
+func TestAllYieldsError(t *testing.T) {
+	boom := errors.New("boom")
+	p := &failingStringProcessor{data: []string{"a"}, err: boom}
+	var got []string
+	var errs []error
+	for s, err := range AllStrings(p) {
+		got = append(got, s)
+		errs = append(errs, err)
+	}
+	if !slices.Equal(got, []string{"a", ""}) || errs[0] != nil || !errors.Is(errs[1], boom) {
+		t.Errorf("expected a then the error, got %q, %v", got, errs)
+	}
+}
+
+func TestAllBreakResumes(t *testing.T) {
+	p := newTestStringProcessor([]string{"a", "b", "c"})
+	for s := range AllStrings(p) {
+		if s == "a" {
+			break
+		}
+	}
+	rest, err := CollectAll(AllStrings(p), 0)
+	if err != nil || !slices.Equal(rest, []string{"b", "c"}) {
+		t.Errorf("expected [b c], got %v, %v", rest, err)
+	}
+}
+
+func TestFromSeq(t *testing.T) {
+	p := FromSeq(slices.Values([]string{"a", "b"}))
+	var sp StringProcessor = p
+	for _, want := range []string{"a", "b"} {
+		got, err := sp.Next()
+		if err != nil || got != want {
+			t.Errorf("expected %q, got %q, %v", want, got, err)
+		}
+	}
+	if _, err := sp.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	if _, err := sp.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF again, got %v", err)
+	}
+}
+
+func TestFromSeq2Error(t *testing.T) {
+	boom := errors.New("boom")
+	p := FromSeq2(AllStrings(&failingStringProcessor{data: []string{"a"}, err: boom}))
+	if s, err := p.Next(); err != nil || s != "a" {
+		t.Errorf("expected a, got %q, %v", s, err)
+	}
+	if _, err := p.Next(); !errors.Is(err, boom) {
+		t.Errorf("expected boom, got %v", err)
+	}
+	if _, err := p.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the error, got %v", err)
+	}
+}
+
+func TestFromSeqStop(t *testing.T) {
+	stopped := false
+	p := FromSeq(func(yield func(Chunk) bool) {
+		defer func() { stopped = true }()
+		for {
+			if !yield("x") {
+				return
+			}
+		}
+	})
+	if _, err := p.Next(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Stop()
+	if !stopped {
+		t.Error("expected Stop to end the iterator")
+	}
+	if _, err := p.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after Stop, got %v", err)
+	}
+}
+
+func TestCollectAllMax(t *testing.T) {
+	p := newTestStringProcessor([]string{"a", "b", "c"})
+	got, err := CollectAll(AllStrings(p), 2)
+	var tooMany ErrTooMany
+	if !errors.As(err, &tooMany) || tooMany.Max != 2 {
+		t.Fatalf("expected ErrTooMany{2}, got %v", err)
+	}
+	if !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("expected [a b], got %v", got)
+	}
+	if got, err := CollectAll(AllStrings(newTestStringProcessor([]string{"a", "b"})), 2); err != nil || len(got) != 2 {
+		t.Errorf("expected exactly max values to pass, got %v, %v", got, err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := []Chunk{"one", "two", "three"}
+	got, err := CollectAll(AllChunks(FromSeq(slices.Values(want))), 0)
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v, %v", want, got, err)
+	}
+}
@@ -0,0 +1,75 @@
+package langid
+
+import (
+	"context"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// Annotator reads ChunkSlices and identifies the language of each, and of
+// the document as a whole. It is a textproc.ChunkCombiner, so it can wrap
+// a chain.Chain or any other combiner.
+type Annotator struct {
+	src  textproc.ChunkCombiner
+	d    *Detector
+	doc  tally // Evidence from every slice so far, for Document
+	last Result
+}
+
+// NewAnnotator creates a new Annotator reading slices from src, with a
+// Detector configured by opts.
+func NewAnnotator(src textproc.ChunkCombiner, opts ...Option) *Annotator {
+	return &Annotator{src: src, d: NewDetector(opts...)}
+}
+
+// Next returns the next ChunkSlice; Language returns its language.
+// Returns io.EOF when all data has been consumed.
+func (a *Annotator) Next() (textproc.ChunkSlice, error) {
+	slice, _, err := a.NextWithLanguage()
+	return slice, err
+}
+
+// NextContext is Next, passing ctx on to the source; see
+// textproc.NextChunkSliceContext.
+func (a *Annotator) NextContext(ctx context.Context) (textproc.ChunkSlice, error) {
+	slice, _, err := a.NextWithLanguageContext(ctx)
+	return slice, err
+}
+
+// NextWithLanguage returns the next ChunkSlice and its language.
+// Returns io.EOF when all data has been consumed.
+func (a *Annotator) NextWithLanguage() (textproc.ChunkSlice, Result, error) {
+	return a.NextWithLanguageContext(context.Background())
+}
+
+// NextWithLanguageContext is NextWithLanguage, passing ctx on to the
+// source.
+func (a *Annotator) NextWithLanguageContext(ctx context.Context) (textproc.ChunkSlice, Result, error) {
+	slice, err := textproc.NextChunkSliceContext(ctx, a.src)
+	if err != nil {
+		return nil, Result{}, err
+	}
+	var t tally
+	for _, chunk := range slice {
+		a.d.add(&t, string(chunk))
+		a.d.add(&a.doc, string(chunk))
+	}
+	a.last = a.d.result(&t)
+	return slice, a.last, nil
+}
+
+// Language returns the language of the ChunkSlice last returned.
+func (a *Annotator) Language() Result {
+	return a.last
+}
+
+// Document returns the language of all the slices read so far, looking at
+// no more of them than the Detector's sample size. It is the language of
+// the whole document once Next has returned io.EOF, or once the sample is
+// full.
+func (a *Annotator) Document() Result {
+	return a.d.result(&a.doc)
+}
+
+// Ensure Annotator implements ContextChunkCombiner
+var _ textproc.ContextChunkCombiner = (*Annotator)(nil)
@@ -0,0 +1,95 @@
+package langid
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"golang.org/x/text/language"
+)
+
+// mockCombiner implements textproc.ChunkCombiner for testing.
+type mockCombiner struct {
+	slices []textproc.ChunkSlice
+	err    error
+}
+
+func (m *mockCombiner) Next() (textproc.ChunkSlice, error) {
+	if len(m.slices) == 0 {
+		if m.err != nil {
+			return nil, m.err
+		}
+		return nil, io.EOF
+	}
+	slice := m.slices[0]
+	m.slices = m.slices[1:]
+	return slice, nil
+}
+
+func TestAnnotator(t *testing.T) {
+	src := &mockCombiner{slices: []textproc.ChunkSlice{
+		{textproc.Chunk(heldOut["en"]), textproc.Chunk(heldOut["en"])},
+		{textproc.Chunk(heldOut["de"])},
+		{"Thanks"},
+	}}
+	a := NewAnnotator(src)
+
+	want := []language.Tag{language.English, language.German, language.Und}
+	for i, tag := range want {
+		slice, got, err := a.NextWithLanguage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(slice) == 0 {
+			t.Fatalf("slice %d is empty", i)
+		}
+		if got.Tag != tag || a.Language() != got {
+			t.Errorf("slice %d: got %s, want %s", i, got.Tag, tag)
+		}
+	}
+	if _, _, err := a.NextWithLanguage(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	// The document is mostly English
+	if got := a.Document(); got.Tag != language.English {
+		t.Errorf("document: got %s, want en", got.Tag)
+	}
+}
+
+func TestAnnotatorNext(t *testing.T) {
+	src := &mockCombiner{slices: []textproc.ChunkSlice{{textproc.Chunk(heldOut["fr"])}}}
+	a := NewAnnotator(src, WithLanguages(language.French, language.English))
+	slice, err := a.Next()
+	if err != nil || len(slice) != 1 {
+		t.Fatalf("got %q, %v", slice, err)
+	}
+	if got := a.Language(); got.Tag != language.French {
+		t.Errorf("got %s, want fr", got.Tag)
+	}
+}
+
+func TestAnnotatorDocumentBeforeNext(t *testing.T) {
+	a := NewAnnotator(&mockCombiner{})
+	if got := a.Document(); got.Tag != language.Und {
+		t.Errorf("got %s, want und", got.Tag)
+	}
+}
+
+func TestAnnotatorError(t *testing.T) {
+	srcErr := errors.New("read failed")
+	a := NewAnnotator(&mockCombiner{err: srcErr})
+	if _, _, err := a.NextWithLanguage(); !errors.Is(err, srcErr) {
+		t.Errorf("expected %v, got %v", srcErr, err)
+	}
+}
+
+func TestAnnotatorNextContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a := NewAnnotator(&mockCombiner{slices: []textproc.ChunkSlice{{"x"}}})
+	if _, err := a.NextContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
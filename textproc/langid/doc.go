@@ -0,0 +1,35 @@
+// Package langid identifies the language of text offline.
+//
+// Search needs a language per document to choose analysers, and sentence
+// rules depend on the script. Detect returns a BCP 47 tag, the dominant
+// ISO 15924 script and a confidence between 0 and 1:
+//
+//	r := langid.Detect("Der Drucker hat schon wieder kein Papier.")
+//	// r.Tag is de, r.Script is Latn
+//
+// Languages with a script of their own (Greek, Hebrew, Korean, Thai,
+// Georgian, Armenian and the scripts of India and South-East Asia) are
+// identified by script. Japanese is Han with kana, and Chinese Han
+// without. Languages sharing the Latin, Cyrillic or Arabic script are told
+// apart by character n-grams of up to three letters, scored against
+// profiles built from training text embedded in the package, so nothing
+// is fetched at run time. Devanagari is taken to be Hindi. The confidence
+// of an n-gram result grows with the sample and with the lead over the
+// next most likely language, so a short sentence in one of a close pair,
+// such as Danish and Norwegian or Russian and Ukrainian, is far from sure.
+//
+// Text with fewer than DefaultMinLetters letters is language.Und. Only the
+// first DefaultMaxSample bytes are looked at. WithLanguages limits the
+// candidates, for example to the languages a search index has analysers
+// for.
+//
+// Annotator wraps a textproc.ChunkCombiner, such as a chain.Chain, and
+// identifies the language of each ChunkSlice and of the document:
+//
+//	a := langid.NewAnnotator(c)
+//	for {
+//	    slice, lang, err := a.NextWithLanguage()
+//	    ...
+//	}
+//	doc := a.Document()
+package langid
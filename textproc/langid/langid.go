@@ -0,0 +1,225 @@
+package langid
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultMinLetters is the fewest letters a text needs for its language to
+// be identified.
+const DefaultMinLetters = 10
+
+// DefaultMaxSample is the most text, in bytes, looked at to identify a
+// language. Identification is reliable long before this.
+const DefaultMaxSample = 16 * 1024
+
+// temperature divides the log likelihoods before they become confidences:
+// each letter is counted in up to maxOrder n-grams of each order, so their
+// evidence is far from independent, and the small training text makes
+// every difference look surer than it is.
+const temperature = 3 * maxOrder
+
+// halfLetters is the number of letters at which the sample size halves a
+// confidence from n-grams: a few words are weak evidence however unlike
+// the other languages they look.
+const halfLetters = 5
+
+// Result is an identified language.
+type Result struct {
+	// Tag is the BCP 47 tag of the language, or language.Und if it could
+	// not be identified.
+	Tag language.Tag
+	// Script is the ISO 15924 script most of the text's letters are in,
+	// such as Latn or Cyrl, or Jpan for Han mixed with kana. It is the
+	// zero Script if the text has no letters in a recognised script.
+	Script language.Script
+	// Confidence is between 0 and 1. It is lower for short text, for text
+	// in several scripts, and between similar languages.
+	Confidence float64
+}
+
+// Detector identifies the language of text.
+type Detector struct {
+	tags       []language.Tag // Candidate languages, or nil for all
+	minLetters int
+	maxSample  int
+}
+
+// Option configures a Detector.
+type Option func(*Detector)
+
+// WithLanguages limits the candidate languages to tags. Text in none of
+// them is identified as language.Und, or as the most likely of them if
+// they share its script.
+func WithLanguages(tags ...language.Tag) Option {
+	return func(d *Detector) {
+		d.tags = append(d.tags, tags...)
+	}
+}
+
+// WithMinLetters sets the fewest letters a text needs for its language to
+// be identified. The default is DefaultMinLetters.
+func WithMinLetters(n int) Option {
+	return func(d *Detector) {
+		d.minLetters = n
+	}
+}
+
+// WithMaxSample sets the most text, in bytes, looked at to identify a
+// language. The default is DefaultMaxSample.
+func WithMaxSample(n int) Option {
+	return func(d *Detector) {
+		d.maxSample = n
+	}
+}
+
+// NewDetector creates a new Detector.
+func NewDetector(opts ...Option) *Detector {
+	d := &Detector{minLetters: DefaultMinLetters, maxSample: DefaultMaxSample}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+var defaultDetector = NewDetector()
+
+// Detect identifies the language of text with the default Detector.
+func Detect(text string) Result {
+	return defaultDetector.Detect(text)
+}
+
+// Detect identifies the language of text.
+func (d *Detector) Detect(text string) Result {
+	var t tally
+	d.add(&t, text)
+	return d.result(&t)
+}
+
+// Languages returns the languages the Detector can identify.
+func (d *Detector) Languages() []language.Tag {
+	var tags []language.Tag
+	for _, m := range loadModels() {
+		tags = append(tags, m.tags...)
+	}
+	for _, tag := range scriptLanguages {
+		tags = append(tags, tag)
+	}
+	tags = append(tags, language.Japanese, language.Chinese)
+	tags = slices.DeleteFunc(tags, func(tag language.Tag) bool { return !d.allowed(tag) })
+	slices.SortFunc(tags, func(a, b language.Tag) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return tags
+}
+
+// allowed reports whether tag is a candidate language.
+func (d *Detector) allowed(tag language.Tag) bool {
+	return d.tags == nil || slices.Contains(d.tags, tag)
+}
+
+// tally is the evidence gathered about a text's language.
+type tally struct {
+	scripts scriptCounts
+	scores  map[language.Script][]float64 // Log likelihoods by model language
+	sampled int                           // Bytes looked at
+}
+
+// add gathers evidence from text, until the Detector's sample is full.
+func (d *Detector) add(t *tally, text string) {
+	if t.sampled >= d.maxSample {
+		return
+	}
+	if room := d.maxSample - t.sampled; len(text) > room {
+		// Cut at a character boundary
+		for room > 0 && !utf8.RuneStart(text[room]) {
+			room--
+		}
+		text = text[:room]
+	}
+	t.sampled += len(text)
+
+	models := loadModels()
+	words(text, &t.scripts, func(script language.Script, word []rune) {
+		m := models[script]
+		if m == nil {
+			return
+		}
+		if t.scores == nil {
+			t.scores = map[language.Script][]float64{}
+		}
+		scores := t.scores[script]
+		if scores == nil {
+			scores = make([]float64, len(m.tags))
+			t.scores[script] = scores
+		}
+		grams(word, func(g string) {
+			if probs, ok := m.logProbs[g]; ok {
+				for i, p := range probs {
+					scores[i] += p
+				}
+			}
+		})
+	})
+}
+
+// result identifies the language from the evidence in t.
+func (d *Detector) result(t *tally) Result {
+	script, share, ok := t.scripts.dominant()
+	if !ok {
+		return Result{Tag: language.Und}
+	}
+	if t.scripts.total < d.minLetters {
+		return Result{Tag: language.Und, Script: script}
+	}
+
+	var tag language.Tag
+	switch script {
+	case scriptJapanese:
+		tag = language.Japanese
+	case scriptHan:
+		tag = language.Chinese
+	default:
+		if tag, ok = scriptLanguages[script]; !ok {
+			return d.best(t, script, share)
+		}
+	}
+	if !d.allowed(tag) {
+		return Result{Tag: language.Und, Script: script}
+	}
+	return Result{Tag: tag, Script: script, Confidence: share}
+}
+
+// best returns the most likely of the candidate languages modelled for
+// script, with a confidence from its likelihood relative to the others,
+// reduced for a small sample.
+func (d *Detector) best(t *tally, script language.Script, share float64) Result {
+	m := loadModels()[script]
+	scores := t.scores[script]
+	if m == nil || scores == nil {
+		return Result{Tag: language.Und, Script: script}
+	}
+
+	bestIdx := -1
+	for i, tag := range m.tags {
+		if d.allowed(tag) && (bestIdx < 0 || scores[i] > scores[bestIdx]) {
+			bestIdx = i
+		}
+	}
+	if bestIdx < 0 {
+		return Result{Tag: language.Und, Script: script}
+	}
+	sum := 0.0
+	for i, tag := range m.tags {
+		if d.allowed(tag) {
+			sum += math.Exp((scores[i] - scores[bestIdx]) / temperature)
+		}
+	}
+	letters := float64(t.scripts.counts[script])
+	sample := letters / (letters + halfLetters)
+	return Result{Tag: m.tags[bestIdx], Script: script, Confidence: share * sample / sum}
+}
@@ -0,0 +1,172 @@
+package langid
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+// heldOut are sentences in each language that are not in the training text.
+var heldOut = map[string]string{
+	"en": "The printer on the second floor is out of paper again, and nobody seems to know where the spare boxes are kept.",
+	"de": "Der Drucker im zweiten Stock hat schon wieder kein Papier, und niemand scheint zu wissen, wo die Ersatzkartons aufbewahrt werden.",
+	"fr": "L'imprimante du deuxième étage n'a encore plus de papier, et personne ne semble savoir où sont rangés les cartons de réserve.",
+	"es": "La impresora de la segunda planta se ha vuelto a quedar sin papel y nadie parece saber dónde se guardan las cajas de repuesto.",
+	"it": "La stampante al secondo piano è di nuovo senza carta e nessuno sembra sapere dove vengono tenute le scatole di scorta.",
+	"pt": "A impressora do segundo andar ficou outra vez sem papel e ninguém parece saber onde são guardadas as caixas de reserva.",
+	"nl": "De printer op de tweede verdieping heeft alweer geen papier en niemand lijkt te weten waar de reservedozen worden bewaard.",
+	"sv": "Skrivaren på andra våningen har slut på papper igen, och ingen verkar veta var reservkartongerna förvaras.",
+	"da": "Printeren på anden sal er løbet tør for papir igen, og ingen lader til at vide, hvor de ekstra kasser bliver opbevaret.",
+	"nb": "Skriveren i andre etasje er tom for papir igjen, og ingen ser ut til å vite hvor ekstraeskene blir oppbevart.",
+	"fi": "Toisen kerroksen tulostimesta on taas paperi loppu, eikä kukaan tunnu tietävän, missä varalaatikoita säilytetään.",
+	"pl": "W drukarce na drugim piętrze znowu skończył się papier i nikt nie wie, gdzie trzymane są zapasowe kartony.",
+	"cs": "V tiskárně ve druhém patře zase došel papír a nikdo zřejmě neví, kde se uchovávají náhradní krabice.",
+	"hu": "A második emeleti nyomtatóból megint kifogyott a papír, és úgy tűnik, senki sem tudja, hol tartják a tartalék dobozokat.",
+	"ro": "Imprimanta de la etajul doi a rămas din nou fără hârtie și nimeni nu pare să știe unde sunt păstrate cutiile de rezervă.",
+	"tr": "İkinci kattaki yazıcının kâğıdı yine bitti ve yedek kutuların nerede saklandığını kimse bilmiyor gibi görünüyor.",
+	"id": "Printer di lantai dua kehabisan kertas lagi, dan sepertinya tidak ada yang tahu di mana kotak cadangan disimpan.",
+	"vi": "Máy in ở tầng hai lại hết giấy, và dường như không ai biết các thùng giấy dự phòng được cất ở đâu.",
+	"ca": "La impressora del segon pis s'ha tornat a quedar sense paper i sembla que ningú no sap on es guarden les capses de recanvi.",
+	"ru": "В принтере на втором этаже опять закончилась бумага, и, кажется, никто не знает, где хранятся запасные коробки.",
+	"uk": "У принтері на другому поверсі знову закінчився папір, і, здається, ніхто не знає, де зберігаються запасні коробки.",
+	"bg": "Принтерът на втория етаж отново остана без хартия и изглежда никой не знае къде се съхраняват резервните кашони.",
+	"sr": "Штампачу на другом спрату поново је нестало папира и изгледа да нико не зна где се чувају резервне кутије.",
+	"ar": "نفد الورق مرة أخرى من الطابعة في الطابق الثاني، ويبدو أن لا أحد يعرف أين تحفظ الصناديق الاحتياطية.",
+	"fa": "کاغذ چاپگر طبقه دوم دوباره تمام شده و ظاهراً هیچ‌کس نمی‌داند جعبه‌های یدکی کجا نگهداری می‌شوند.",
+	"ur": "دوسری منزل والے پرنٹر میں پھر سے کاغذ ختم ہو گیا ہے اور لگتا ہے کسی کو نہیں معلوم کہ فالتو ڈبے کہاں رکھے جاتے ہیں۔",
+	"el": "Ο εκτυπωτής στον δεύτερο όροφο έμεινε πάλι χωρίς χαρτί και κανείς δεν φαίνεται να ξέρει πού φυλάσσονται τα εφεδρικά κουτιά.",
+	"he": "במדפסת בקומה השנייה שוב נגמר הנייר, ונראה שאף אחד לא יודע איפה שומרים את הקרטונים הרזרביים.",
+	"ja": "二階のプリンターがまた紙切れになっていて、予備の箱がどこに保管されているのか誰も知らないようです。",
+	"zh": "二楼的打印机又没纸了，好像没有人知道备用的纸箱放在哪里。",
+	"ko": "2층 프린터에 또 종이가 떨어졌는데, 여분의 상자를 어디에 보관하는지 아무도 모르는 것 같습니다.",
+	"th": "เครื่องพิมพ์ที่ชั้นสองกระดาษหมดอีกแล้ว และดูเหมือนไม่มีใครรู้ว่าเก็บกล่องสำรองไว้ที่ไหน",
+	"hi": "दूसरी मंज़िल के प्रिंटर में फिर से कागज़ खत्म हो गया है, और लगता है किसी को नहीं पता कि अतिरिक्त डिब्बे कहाँ रखे जाते हैं।",
+}
+
+func TestDetectHeldOut(t *testing.T) {
+	for want, text := range heldOut {
+		got := Detect(text)
+		if got.Tag != language.MustParse(want) {
+			t.Errorf("%s: detected %s (%.2f) for %q", want, got.Tag, got.Confidence, text)
+			continue
+		}
+		if got.Confidence < 0.5 {
+			t.Errorf("%s: confidence %.2f, want at least 0.5", want, got.Confidence)
+		}
+	}
+}
+
+func TestDetectTooShort(t *testing.T) {
+	for _, text := range []string{"", "   ", "12345 67890", "Thanks!", "OK"} {
+		if got := Detect(text); got.Tag != language.Und || got.Confidence != 0 {
+			t.Errorf("Detect(%q) = %+v, want und", text, got)
+		}
+	}
+	if got := Detect("Thanks!"); got.Script.String() != "Latn" {
+		t.Errorf("expected the script of short text, got %s", got.Script)
+	}
+
+	d := NewDetector(WithMinLetters(3))
+	if got := d.Detect("Ευχαριστώ"); got.Tag != language.Greek {
+		t.Errorf("expected el with a lower minimum, got %s", got.Tag)
+	}
+}
+
+func TestDetectMixedScripts(t *testing.T) {
+	pure := Detect(heldOut["en"])
+	mixed := Detect(heldOut["en"] + " " + heldOut["ru"][:60])
+	if mixed.Tag != language.English {
+		t.Fatalf("expected en, got %s", mixed.Tag)
+	}
+	if mixed.Confidence >= pure.Confidence {
+		t.Errorf("expected lower confidence for mixed scripts: %.2f, %.2f", mixed.Confidence, pure.Confidence)
+	}
+}
+
+func TestDetectSimilarLanguagesLessConfident(t *testing.T) {
+	// Danish and Norwegian share most words
+	got := Detect("Tak for hjælpen.")
+	if got.Confidence > Detect(heldOut["da"]).Confidence {
+		t.Errorf("expected a short Danish text to be less certain, got %.3f", got.Confidence)
+	}
+}
+
+func TestDetectShortConfusableText(t *testing.T) {
+	tests := []struct {
+		lang, text string
+	}{
+		{"nb", "Vi har mottatt bestillingen din og den blir sendt i morgen."},
+		{"da", "Tak for hjælpen."},
+		{"nb", "Takk for hjelpen."},
+		{"ru", "Спасибо за помощь."},
+		{"bg", "Благодаря за помощта."},
+		{"sr", "Хвала на помоћи."},
+	}
+	for _, tt := range tests {
+		got := Detect(tt.text)
+		if got.Tag != language.MustParse(tt.lang) {
+			t.Errorf("%s: detected %s for %q", tt.lang, got.Tag, tt.text)
+			continue
+		}
+		if got.Confidence > 0.7 {
+			t.Errorf("%s: confidence %.2f for %q, want at most 0.7", tt.lang, got.Confidence, tt.text)
+		}
+		if long := Detect(heldOut[tt.lang]); got.Confidence >= long.Confidence {
+			t.Errorf("%s: confidence %.2f, want less than %.2f for a longer text", tt.lang, got.Confidence, long.Confidence)
+		}
+	}
+}
+
+func TestWithLanguages(t *testing.T) {
+	d := NewDetector(WithLanguages(language.English, language.German, language.Japanese))
+	if got := d.Detect(heldOut["nl"]); got.Tag != language.German && got.Tag != language.English {
+		t.Errorf("expected Dutch to be identified as a candidate, got %s", got.Tag)
+	}
+	if got := d.Detect(heldOut["ru"]); got.Tag != language.Und || got.Script.String() != "Cyrl" {
+		t.Errorf("expected und for Russian, got %+v", got)
+	}
+	if got := d.Detect(heldOut["ko"]); got.Tag != language.Und {
+		t.Errorf("expected und for Korean, got %s", got.Tag)
+	}
+	if got := d.Detect(heldOut["ja"]); got.Tag != language.Japanese {
+		t.Errorf("expected ja, got %s", got.Tag)
+	}
+
+	want := []language.Tag{language.German, language.English, language.Japanese}
+	if got := d.Languages(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("Languages() = %v, want %v", got, want)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	tags := NewDetector().Languages()
+	for want := range heldOut {
+		tag := language.MustParse(want)
+		found := false
+		for _, got := range tags {
+			found = found || got == tag
+		}
+		if !found {
+			t.Errorf("Languages() is missing %s", tag)
+		}
+	}
+}
+
+func TestWithMaxSample(t *testing.T) {
+	// Only the English prefix is looked at
+	text := heldOut["en"] + " " + strings.Repeat(heldOut["fi"]+" ", 20)
+	d := NewDetector(WithMaxSample(len(heldOut["en"])))
+	if got := d.Detect(text); got.Tag != language.English {
+		t.Errorf("expected en from the sample, got %s", got.Tag)
+	}
+	if got := Detect(text); got.Tag != language.Finnish {
+		t.Errorf("expected fi from the whole text, got %s", got.Tag)
+	}
+
+	// The sample is cut at a character boundary
+	d = NewDetector(WithMaxSample(15))
+	if got := d.Detect("Привет, как дела у тебя сегодня?"); got.Script.String() != "Cyrl" {
+		t.Errorf("expected Cyrl, got %+v", got)
+	}
+}
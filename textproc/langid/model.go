@@ -0,0 +1,207 @@
+package langid
+
+import (
+	"embed"
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// The training text for the languages told apart by n-grams: the same
+// everyday and business prose in each, so their profiles differ by
+// language rather than subject.
+//
+//go:embed corpus/*.txt
+var corpus embed.FS
+
+// maxOrder is the longest n-gram used, in characters.
+const maxOrder = 3
+
+// smoothing is added to every n-gram count, so an n-gram a language's
+// training text lacks makes it less likely rather than impossible.
+const smoothing = 0.5
+
+// model tells apart the languages written in one script by character
+// n-grams.
+type model struct {
+	tags []language.Tag
+	// logProbs are the log probabilities of each n-gram seen in training,
+	// by language. N-grams no language has are ignored.
+	logProbs map[string][]float64
+}
+
+var (
+	loadOnce sync.Once
+	models   map[language.Script]*model
+)
+
+// loadModels returns the n-gram models, by script, building them from the
+// embedded training text on first use.
+func loadModels() map[language.Script]*model {
+	loadOnce.Do(func() {
+		models = buildModels()
+	})
+	return models
+}
+
+// buildModels builds a model for each script in the training text.
+func buildModels() map[language.Script]*model {
+	files, err := corpus.ReadDir("corpus")
+	if err != nil {
+		panic(err)
+	}
+
+	// langCounts counts the n-grams in one language's text, with their
+	// totals by order
+	type langCounts struct {
+		tag    language.Tag
+		grams  map[string]int
+		totals [maxOrder + 1]int
+	}
+	byScript := map[language.Script][]*langCounts{}
+	for _, f := range files {
+		data, err := corpus.ReadFile(path.Join("corpus", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		text := string(data)
+		lc := &langCounts{
+			tag:   language.MustParse(strings.TrimSuffix(f.Name(), ".txt")),
+			grams: map[string]int{},
+		}
+		var scripts scriptCounts
+		words(text, &scripts, func(_ language.Script, word []rune) {
+			grams(word, func(g string) {
+				lc.grams[g]++
+				lc.totals[len([]rune(g))]++
+			})
+		})
+		script, _, _ := scripts.dominant()
+		byScript[script] = append(byScript[script], lc)
+	}
+
+	built := map[language.Script]*model{}
+	for script, langs := range byScript {
+		m := &model{logProbs: map[string][]float64{}}
+		var vocab [maxOrder + 1]int
+		for _, lc := range langs {
+			m.tags = append(m.tags, lc.tag)
+			for g := range lc.grams {
+				if m.logProbs[g] == nil {
+					m.logProbs[g] = make([]float64, len(langs))
+					vocab[len([]rune(g))]++
+				}
+			}
+		}
+		for g, probs := range m.logProbs {
+			order := len([]rune(g))
+			for i, lc := range langs {
+				p := (float64(lc.grams[g]) + smoothing) / (float64(lc.totals[order]) + smoothing*float64(vocab[order]))
+				probs[i] = math.Log(p)
+			}
+		}
+		built[script] = m
+	}
+	return built
+}
+
+// words calls fn with each word of text, lower-cased, and counts the
+// script of each letter. A word is a run of letters and combining marks;
+// its script is that of its first letter.
+func words(text string, scripts *scriptCounts, fn func(script language.Script, word []rune)) {
+	var word []rune
+	var script language.Script
+	known := false
+	flush := func() {
+		if len(word) > 0 && known {
+			fn(script, word)
+		}
+		word = word[:0]
+		known = false
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r):
+			s, ok := scriptOf(r)
+			if ok {
+				scripts.add(s)
+			}
+			if len(word) == 0 {
+				script, known = s, ok
+			}
+			word = append(word, unicode.ToLower(r))
+		case len(word) > 0 && unicode.In(r, unicode.Mn, unicode.Mc):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+}
+
+// grams calls fn with every n-gram of word, padded with a space at each
+// end, up to maxOrder characters long.
+func grams(word []rune, fn func(gram string)) {
+	padded := make([]rune, 0, len(word)+2)
+	padded = append(padded, ' ')
+	padded = append(padded, word...)
+	padded = append(padded, ' ')
+	for n := 1; n <= maxOrder; n++ {
+		for i := 0; i+n <= len(padded); i++ {
+			if n == 1 && padded[i] == ' ' {
+				continue
+			}
+			fn(string(padded[i : i+n]))
+		}
+	}
+}
+
+// scriptCounts counts letters by script.
+type scriptCounts struct {
+	counts map[language.Script]int
+	total  int
+}
+
+// add counts a letter in script s.
+func (c *scriptCounts) add(s language.Script) {
+	if c.counts == nil {
+		c.counts = map[language.Script]int{}
+	}
+	c.counts[s]++
+	c.total++
+}
+
+// dominant returns the script with the most letters, counting Han and kana
+// together, and the share of letters in it. Han with enough kana is
+// reported as Japanese.
+func (c *scriptCounts) dominant() (language.Script, float64, bool) {
+	if c.total == 0 {
+		return language.Script{}, 0, false
+	}
+	cjk, kana := 0, 0
+	var best language.Script
+	bestCount := 0
+	for s, n := range c.counts {
+		if isCJK(s) {
+			cjk += n
+			if s != scriptHan {
+				kana += n
+			}
+			continue
+		}
+		if n > bestCount || (n == bestCount && s.String() < best.String()) {
+			best, bestCount = s, n
+		}
+	}
+	if cjk > bestCount {
+		best, bestCount = scriptHan, cjk
+		if kana*10 >= cjk {
+			best = scriptJapanese
+		}
+	}
+	return best, float64(bestCount) / float64(c.total), true
+}
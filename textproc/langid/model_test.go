@@ -0,0 +1,67 @@
+package langid
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestModelsCoverCorpus(t *testing.T) {
+	files, err := corpus.ReadDir("corpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := loadModels()
+	for _, f := range files {
+		tag := language.MustParse(strings.TrimSuffix(f.Name(), ".txt"))
+		found := false
+		for _, m := range models {
+			found = found || slices.Contains(m.tags, tag)
+		}
+		if !found {
+			t.Errorf("no model has %s", tag)
+		}
+	}
+	for _, script := range []string{"Latn", "Cyrl", "Arab"} {
+		if models[language.MustParseScript(script)] == nil {
+			t.Errorf("no model for %s", script)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	var got []string
+	var c scriptCounts
+	words("Don't stop, Straße-Bahn 42 naïve", &c, func(_ language.Script, word []rune) {
+		got = append(got, string(word))
+	})
+	want := []string{"don", "t", "stop", "straße", "bahn", "naïve"}
+	if !slices.Equal(got, want) {
+		t.Errorf("words = %q, want %q", got, want)
+	}
+	if c.total != 23 {
+		t.Errorf("counted %d letters, want 23", c.total)
+	}
+}
+
+func TestWordsKeepCombiningMarks(t *testing.T) {
+	var got []string
+	var c scriptCounts
+	words("हिन्दी café", &c, func(_ language.Script, word []rune) {
+		got = append(got, string(word))
+	})
+	if want := []string{"हिन्दी", "café"}; !slices.Equal(got, want) {
+		t.Errorf("words = %q, want %q", got, want)
+	}
+}
+
+func TestGrams(t *testing.T) {
+	var got []string
+	grams([]rune("ab"), func(g string) { got = append(got, g) })
+	want := []string{"a", "b", " a", "ab", "b ", " ab", "ab "}
+	if !slices.Equal(got, want) {
+		t.Errorf("grams = %q, want %q", got, want)
+	}
+}
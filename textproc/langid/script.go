@@ -0,0 +1,96 @@
+package langid
+
+import (
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// scriptTable is a writing system and the characters in it.
+type scriptTable struct {
+	script language.Script
+	table  *unicode.RangeTable
+}
+
+// scriptTables are the scripts recognised, most common first.
+var scriptTables = []scriptTable{
+	{language.MustParseScript("Latn"), unicode.Latin},
+	{language.MustParseScript("Cyrl"), unicode.Cyrillic},
+	{language.MustParseScript("Arab"), unicode.Arabic},
+	{language.MustParseScript("Hani"), unicode.Han},
+	{language.MustParseScript("Hira"), unicode.Hiragana},
+	{language.MustParseScript("Kana"), unicode.Katakana},
+	{language.MustParseScript("Hang"), unicode.Hangul},
+	{language.MustParseScript("Grek"), unicode.Greek},
+	{language.MustParseScript("Hebr"), unicode.Hebrew},
+	{language.MustParseScript("Thai"), unicode.Thai},
+	{language.MustParseScript("Deva"), unicode.Devanagari},
+	{language.MustParseScript("Beng"), unicode.Bengali},
+	{language.MustParseScript("Taml"), unicode.Tamil},
+	{language.MustParseScript("Telu"), unicode.Telugu},
+	{language.MustParseScript("Gujr"), unicode.Gujarati},
+	{language.MustParseScript("Knda"), unicode.Kannada},
+	{language.MustParseScript("Mlym"), unicode.Malayalam},
+	{language.MustParseScript("Guru"), unicode.Gurmukhi},
+	{language.MustParseScript("Geor"), unicode.Georgian},
+	{language.MustParseScript("Armn"), unicode.Armenian},
+	{language.MustParseScript("Ethi"), unicode.Ethiopic},
+	{language.MustParseScript("Khmr"), unicode.Khmer},
+	{language.MustParseScript("Laoo"), unicode.Lao},
+	{language.MustParseScript("Mymr"), unicode.Myanmar},
+	{language.MustParseScript("Sinh"), unicode.Sinhala},
+}
+
+var (
+	scriptLatin    = scriptTables[0].script
+	scriptHan      = language.MustParseScript("Hani")
+	scriptHiragana = language.MustParseScript("Hira")
+	scriptKatakana = language.MustParseScript("Kana")
+	scriptHangul   = language.MustParseScript("Hang")
+	// scriptJapanese is Han with Hiragana and Katakana.
+	scriptJapanese = language.MustParseScript("Jpan")
+)
+
+// scriptLanguages are the languages identified by their script alone.
+// Japanese and Chinese, which share Han, are told apart by kana.
+var scriptLanguages = map[language.Script]language.Tag{
+	scriptHangul:                     language.Korean,
+	language.MustParseScript("Grek"): language.Greek,
+	language.MustParseScript("Hebr"): language.Hebrew,
+	language.MustParseScript("Thai"): language.Thai,
+	language.MustParseScript("Deva"): language.Hindi,
+	language.MustParseScript("Beng"): language.Bengali,
+	language.MustParseScript("Taml"): language.Tamil,
+	language.MustParseScript("Telu"): language.Telugu,
+	language.MustParseScript("Gujr"): language.Gujarati,
+	language.MustParseScript("Knda"): language.Kannada,
+	language.MustParseScript("Mlym"): language.Malayalam,
+	language.MustParseScript("Guru"): language.Punjabi,
+	language.MustParseScript("Geor"): language.Georgian,
+	language.MustParseScript("Armn"): language.Armenian,
+	language.MustParseScript("Ethi"): language.Amharic,
+	language.MustParseScript("Khmr"): language.Khmer,
+	language.MustParseScript("Laoo"): language.Lao,
+	language.MustParseScript("Mymr"): language.Burmese,
+	language.MustParseScript("Sinh"): language.Sinhala,
+}
+
+// scriptOf returns the script of the letter r, or false if it is not in a
+// recognised script.
+func scriptOf(r rune) (language.Script, bool) {
+	if r < 0x80 {
+		return scriptLatin, ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+	}
+	for _, s := range scriptTables {
+		if unicode.Is(s.table, r) {
+			return s.script, true
+		}
+	}
+	return language.Script{}, false
+}
+
+// isCJK reports whether s is one of the scripts written by Chinese and
+// Japanese.
+func isCJK(s language.Script) bool {
+	return s == scriptHan || s == scriptHiragana || s == scriptKatakana
+}
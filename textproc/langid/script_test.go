@@ -0,0 +1,63 @@
+package langid
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestScriptOf(t *testing.T) {
+	tests := map[rune]string{
+		'a': "Latn",
+		'Z': "Latn",
+		'é': "Latn",
+		'ж': "Cyrl",
+		'ب': "Arab",
+		'漢': "Hani",
+		'ひ': "Hira",
+		'カ': "Kana",
+		'한': "Hang",
+		'λ': "Grek",
+		'ש': "Hebr",
+		'ก': "Thai",
+		'क': "Deva",
+	}
+	for r, want := range tests {
+		got, ok := scriptOf(r)
+		if !ok || got.String() != want {
+			t.Errorf("scriptOf(%q) = %s, %v; want %s", r, got, ok, want)
+		}
+	}
+	for _, r := range []rune{'1', '-', ' ', '€'} {
+		if _, ok := scriptOf(r); ok {
+			t.Errorf("scriptOf(%q) should not be a script", r)
+		}
+	}
+}
+
+func TestDominantScript(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  string
+		share float64
+	}{
+		{"hello мир", "Latn", 5.0 / 8},
+		{"东京是日本的首都", "Hani", 1},
+		{"東京は日本の首都です", "Jpan", 1},
+		{"すべてひらがな", "Jpan", 1},
+		{"Hello 東京", "Latn", 5.0 / 7},
+	}
+	for _, tt := range tests {
+		var c scriptCounts
+		words(tt.text, &c, func(language.Script, []rune) {})
+		got, share, ok := c.dominant()
+		if !ok || got.String() != tt.want || share != tt.share {
+			t.Errorf("dominant(%q) = %s, %.2f; want %s, %.2f", tt.text, got, share, tt.want, tt.share)
+		}
+	}
+
+	var c scriptCounts
+	if _, _, ok := c.dominant(); ok {
+		t.Error("expected no dominant script without letters")
+	}
+}
@@ -0,0 +1,28 @@
+// Package pool runs the text chain over many documents concurrently.
+//
+// A Pool reads Documents from an iterator, builds a chain for each from its
+// reader, charset, transfer encoding and content type, and processes up to
+// WithWorkers of them at once. Each Result holds one document's
+// ChunkSlices in order, or the error that stopped it; an error in one
+// document, including a panic in a stage, does not stop the others. A
+// panic in the iterator itself ends the input, and is reported as a final
+// Result with ErrDocsPanic.
+//
+//	p := pool.New(pool.WithWorkers(8), pool.WithMaxSlices(1000),
+//	    pool.WithBuilder(func(b *chain.Builder, doc pool.Document) {
+//	        b.WithLimits(chain.DefaultLimits)
+//	    }))
+//	for res := range p.Run(ctx, docs) {
+//	    if res.Err != nil {
+//	        log.Printf("%v: %v", res.Document.Metadata, res.Err)
+//	        continue
+//	    }
+//	    index(res.Document.Metadata, res.Slices)
+//	}
+//
+// Results arrive as documents finish, not in input order; Result.Index
+// gives each one's position in the input. Documents are read from the
+// iterator only as workers become free, and workers wait for their result
+// to be taken, so memory is bounded by the number of workers and the
+// output of each document, which WithMaxSlices and the chain's limits cap.
+package pool
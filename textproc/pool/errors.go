@@ -0,0 +1,28 @@
+package pool
+
+// Error types for pool.
+
+import (
+	"fmt"
+)
+
+// ErrPanic indicates a document whose processing panicked. The pool
+// recovers, so the other documents are unaffected.
+type ErrPanic struct {
+	Value any
+}
+
+func (e ErrPanic) Error() string {
+	return fmt.Sprintf("panic processing document: %v", e.Value)
+}
+
+// ErrDocsPanic indicates that the docs iterator passed to Run panicked.
+// It is the Err of a Result with no Document, whose Index is the position
+// the next document would have had; no more documents are read.
+type ErrDocsPanic struct {
+	Value any
+}
+
+func (e ErrDocsPanic) Error() string {
+	return fmt.Sprintf("panic reading documents: %v", e.Value)
+}
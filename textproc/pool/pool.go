@@ -0,0 +1,197 @@
+package pool
+
+import (
+	"context"
+	"io"
+	"iter"
+	"runtime"
+	"sync"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chain"
+)
+
+// Document is one input to a Pool.
+type Document struct {
+	Reader           io.Reader
+	Charset          string // Empty means UTF-8
+	TransferEncoding string // Content-Transfer-Encoding, empty for none
+	ContentType      string // Picks the chain's front-end; empty leaves the default
+	Metadata         any    // Passed through to the Result untouched
+}
+
+// Result is the output of one Document.
+type Result struct {
+	Document Document
+	Index    int                   // Position of the document in the input
+	Slices   []textproc.ChunkSlice // In the order the chain returned them
+	Err      error                 // Error that stopped the document, or nil
+}
+
+// Pool processes documents concurrently with the text chain.
+type Pool struct {
+	workers   int
+	maxSlices int
+	configure []func(*chain.Builder, Document)
+}
+
+// Option configures a Pool.
+type Option func(*Pool)
+
+// WithWorkers sets how many documents are processed at once. The default
+// is runtime.GOMAXPROCS(0).
+func WithWorkers(n int) Option {
+	return func(p *Pool) {
+		if n > 0 {
+			p.workers = n
+		}
+	}
+}
+
+// WithMaxSlices stops a document with textproc.ErrTooMany once it has more
+// than n ChunkSlices, keeping the first n. Zero, the default, means no
+// limit.
+func WithMaxSlices(n int) Option {
+	return func(p *Pool) {
+		p.maxSlices = n
+	}
+}
+
+// WithBuilder calls fn to configure the chain for each document, after the
+// document's charset, transfer encoding and content type are applied.
+func WithBuilder(fn func(b *chain.Builder, doc Document)) Option {
+	return func(p *Pool) {
+		p.configure = append(p.configure, fn)
+	}
+}
+
+// New creates a Pool.
+func New(opts ...Option) *Pool {
+	p := &Pool{
+		workers: runtime.GOMAXPROCS(0),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// job is a document waiting for a worker.
+type job struct {
+	index int
+	doc   Document
+	err   error // Reported as the result instead of processing doc
+}
+
+// Run processes docs and returns an iterator over their results, in the
+// order they finish. Documents are read from docs as workers become free.
+// When ctx is done, documents in progress finish with its error and no
+// more are started. Breaking out of the range stops the pool, and Run's
+// iterator returns once every worker has.
+func (p *Pool) Run(ctx context.Context, docs iter.Seq[Document]) iter.Seq[Result] {
+	return func(yield func(Result) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		jobs := make(chan job)
+		fed := make(chan struct{})
+		go func() {
+			defer close(fed)
+			defer close(jobs)
+			i := 0
+			defer func() {
+				if v := recover(); v != nil {
+					// Report the panic in place of the document it stopped
+					select {
+					case jobs <- job{index: i, err: ErrDocsPanic{Value: v}}:
+					case <-ctx.Done():
+					}
+				}
+			}()
+			for doc := range docs {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case jobs <- job{index: i, doc: doc}:
+				case <-ctx.Done():
+					return
+				}
+				i++
+			}
+		}()
+
+		results := make(chan Result)
+		var wg sync.WaitGroup
+		for range p.workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					results <- p.process(ctx, j)
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for res := range results {
+			if !yield(res) {
+				cancel()
+				break
+			}
+		}
+		// Let the workers finish and the feeder stop before returning
+		for range results {
+		}
+		<-fed
+	}
+}
+
+// process runs the chain over one document.
+func (p *Pool) process(ctx context.Context, j job) (res Result) {
+	res = Result{Document: j.doc, Index: j.index}
+	if j.err != nil {
+		res.Err = j.err
+		return res
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			res.Slices = nil
+			res.Err = ErrPanic{Value: v}
+		}
+	}()
+
+	b := chain.NewBuilder(j.doc.Reader).
+		WithCharset(j.doc.Charset).
+		WithTransferEncoding(j.doc.TransferEncoding)
+	if j.doc.ContentType != "" {
+		b.WithContentType(j.doc.ContentType)
+	}
+	for _, fn := range p.configure {
+		fn(b, j.doc)
+	}
+	c, err := b.Build()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	for {
+		slice, err := c.NextContext(ctx)
+		if err == io.EOF {
+			return res
+		}
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		if p.maxSlices > 0 && len(res.Slices) == p.maxSlices {
+			res.Err = textproc.ErrTooMany{Max: p.maxSlices}
+			return res
+		}
+		res.Slices = append(res.Slices, slice)
+	}
+}
@@ -0,0 +1,192 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chain"
+)
+
+// slowReader reads its text slowly, recording how many are read at once.
+type slowReader struct {
+	r      io.Reader
+	active *atomic.Int32
+	peak   *atomic.Int32
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	n := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return s.r.Read(p)
+}
+
+// documents returns n plain text documents numbered in their text and
+// metadata.
+func documents(n int) []Document {
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{
+			Reader:      strings.NewReader(fmt.Sprintf("Document %d first.\n\nDocument %d second.", i, i)),
+			ContentType: "text/plain",
+			Metadata:    i,
+		}
+	}
+	return docs
+}
+
+// text joins the chunks of res.
+func text(res Result) string {
+	var parts []string
+	for _, slice := range res.Slices {
+		for _, chunk := range slice {
+			parts = append(parts, string(chunk))
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestRun(t *testing.T) {
+	docs := documents(20)
+	seen := map[int]bool{}
+	for res := range New(WithWorkers(4)).Run(context.Background(), slices.Values(docs)) {
+		if res.Err != nil {
+			t.Fatalf("document %d: unexpected error: %v", res.Index, res.Err)
+		}
+		if res.Document.Metadata != res.Index {
+			t.Errorf("expected metadata %d, got %v", res.Index, res.Document.Metadata)
+		}
+		want := fmt.Sprintf("Document %d first.|Document %d second.", res.Index, res.Index)
+		if got := text(res); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		seen[res.Index] = true
+	}
+	if len(seen) != len(docs) {
+		t.Errorf("expected %d results, got %d", len(docs), len(seen))
+	}
+}
+
+func TestRunBoundsWorkers(t *testing.T) {
+	var active, peak atomic.Int32
+	docs := documents(12)
+	for i := range docs {
+		docs[i].Reader = &slowReader{r: docs[i].Reader, active: &active, peak: &peak}
+	}
+	for range New(WithWorkers(3)).Run(context.Background(), slices.Values(docs)) {
+	}
+	if got := peak.Load(); got > 3 {
+		t.Errorf("expected at most 3 documents at once, got %d", got)
+	}
+}
+
+func TestRunReportsErrorsPerDocument(t *testing.T) {
+	docs := documents(3)
+	docs[1].Charset = "no-such-charset"
+	errs := map[int]error{}
+	for res := range New(WithWorkers(2)).Run(context.Background(), slices.Values(docs)) {
+		errs[res.Index] = res.Err
+	}
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("expected other documents to succeed, got %v and %v", errs[0], errs[2])
+	}
+	if errs[1] == nil {
+		t.Error("expected an error for the bad charset")
+	}
+}
+
+func TestRunRecoversPanic(t *testing.T) {
+	p := New(WithBuilder(func(b *chain.Builder, doc Document) {
+		if doc.Metadata != 1 {
+			return
+		}
+		b.Append(chain.ChunkStage("panic", func(src textproc.ChunkProcessor) textproc.ChunkProcessor {
+			panic("boom")
+		}))
+	}))
+	errs := map[int]error{}
+	for res := range p.Run(context.Background(), slices.Values(documents(3))) {
+		errs[res.Index] = res.Err
+	}
+	var panicErr ErrPanic
+	if !errors.As(errs[1], &panicErr) || panicErr.Value != "boom" {
+		t.Errorf("expected ErrPanic{boom}, got %v", errs[1])
+	}
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("expected other documents to succeed, got %v and %v", errs[0], errs[2])
+	}
+}
+
+func TestRunRecoversDocsPanic(t *testing.T) {
+	docs := func(yield func(Document) bool) {
+		for _, doc := range documents(2) {
+			if !yield(doc) {
+				return
+			}
+		}
+		panic("bad source")
+	}
+	errs := map[int]error{}
+	for res := range New(WithWorkers(2)).Run(context.Background(), docs) {
+		errs[res.Index] = res.Err
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("expected two documents and the panic, got %v", errs)
+	}
+	var panicErr ErrDocsPanic
+	if !errors.As(errs[2], &panicErr) || panicErr.Value != "bad source" {
+		t.Errorf("expected ErrDocsPanic{bad source}, got %v", errs[2])
+	}
+}
+
+func TestRunMaxSlices(t *testing.T) {
+	p := New(WithMaxSlices(1), WithBuilder(func(b *chain.Builder, doc Document) {
+		b.WithCharLimit(10).WithOverlap(0)
+	}))
+	for res := range p.Run(context.Background(), slices.Values(documents(1))) {
+		var tooMany textproc.ErrTooMany
+		if !errors.As(res.Err, &tooMany) || len(res.Slices) != 1 {
+			t.Errorf("expected ErrTooMany with one slice, got %v with %d", res.Err, len(res.Slices))
+		}
+	}
+}
+
+func TestRunBreakStopsFeeding(t *testing.T) {
+	var fed atomic.Int32
+	docs := func(yield func(Document) bool) {
+		for _, doc := range documents(100) {
+			fed.Add(1)
+			if !yield(doc) {
+				return
+			}
+		}
+	}
+	for range New(WithWorkers(2)).Run(context.Background(), docs) {
+		break
+	}
+	if got := fed.Load(); got >= 100 {
+		t.Errorf("expected feeding to stop early, fed %d", got)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for res := range New().Run(ctx, slices.Values(documents(5))) {
+		t.Errorf("expected no documents to start once cancelled, got %d", res.Index)
+	}
+}
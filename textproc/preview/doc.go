@@ -0,0 +1,25 @@
+// Package preview makes the plain text previews and highlighted search
+// snippets JMAP servers return for Emails (RFC 8621).
+//
+// Builder makes an Email.preview. It reads a body through
+// chain.Builder.BuildText, so it has the chunking chain's stages up to the
+// chunker (reader, utf8clean, a front-end chosen by WithContentType, and
+// the elider, which drops quoted text and signatures) and its Limits. It
+// collapses whitespace to single spaces and cuts the result to 256
+// characters at a grapheme cluster boundary, so no accent or emoji
+// sequence is split. It reads only as much of the body as it needs:
+//
+//	p, err := preview.NewBuilder(body).
+//	    WithContentType("text/html; charset=utf-8").
+//	    WithCharset("utf-8").
+//	    Build()
+//
+// Matcher makes the subject and preview of a SearchSnippet. Highlight
+// escapes &, < and > and wraps each match for the filter's terms in
+// <mark></mark>; Snippet does the same for the part of a body around the
+// first match, within the 255 octets RFC 8621 allows:
+//
+//	m := preview.NewMatcher("budget", "quarterly report")
+//	subject, ok := m.Highlight(email.Subject)
+//	excerpt, ok := m.Snippet(bodyText)
+package preview
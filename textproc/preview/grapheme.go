@@ -0,0 +1,109 @@
+package preview
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file finds extended grapheme cluster boundaries, following the rules
+// of Unicode Standard Annex #29 (https://www.unicode.org/reports/tr29/) that
+// matter when cutting text short: combining marks, joiners, emoji
+// sequences, regional indicator pairs and Hangul syllables stay whole.
+// Character properties are approximated from the unicode package's tables.
+
+const zwj = '\u200D'
+
+// graphemeClass is the part of the Grapheme_Cluster_Break property the
+// boundary rules use.
+type graphemeClass uint8
+
+const (
+	gbOther graphemeClass = iota
+	gbCR
+	gbLF
+	gbExtend // Extend, SpacingMark and ZWJ
+	gbRegional
+	gbL
+	gbV
+	gbT
+	gbLV
+	gbLVT
+)
+
+// classifyGrapheme returns the grapheme class of r.
+func classifyGrapheme(r rune) graphemeClass {
+	switch {
+	case r == '\r':
+		return gbCR
+	case r == '\n':
+		return gbLF
+	case r == zwj || unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Other_Grapheme_Extend),
+		r >= 0x1F3FB && r <= 0x1F3FF: // Emoji modifiers
+		return gbExtend
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gbRegional
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gbL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gbV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gbT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gbLV
+		}
+		return gbLVT
+	}
+	return gbOther
+}
+
+// nextGrapheme returns the length in bytes of the grapheme cluster at the
+// start of s.
+func nextGrapheme(s string) int {
+	if s == "" {
+		return 0
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	prev, prevRune := classifyGrapheme(r), r
+	regional := 0
+	if prev == gbRegional {
+		regional = 1
+	}
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		next := classifyGrapheme(r)
+		if !joined(prev, prevRune, next, r, regional) {
+			break
+		}
+		if next == gbRegional {
+			regional++
+		}
+		prev, prevRune = next, r
+		n += size
+	}
+	return n
+}
+
+// joined reports whether there is no boundary between two characters.
+// regional counts the regional indicators in the cluster so far.
+func joined(prev graphemeClass, prevRune rune, next graphemeClass, nextRune rune, regional int) bool {
+	switch {
+	case prev == gbCR:
+		return next == gbLF // GB3, GB4
+	case next == gbCR || next == gbLF:
+		return false // GB5
+	case next == gbExtend:
+		return true // GB9, GB9a
+	case prevRune == zwj:
+		return unicode.Is(unicode.So, nextRune) // GB11, approximating Extended_Pictographic
+	case prev == gbRegional && next == gbRegional:
+		return regional%2 == 1 // GB12, GB13
+	case prev == gbL:
+		return next == gbL || next == gbV || next == gbLV || next == gbLVT // GB6
+	case prev == gbLV || prev == gbV:
+		return next == gbV || next == gbT // GB7
+	case prev == gbLVT || prev == gbT:
+		return next == gbT // GB8
+	}
+	return false // GB999
+}
@@ -0,0 +1,26 @@
+package preview
+
+import "testing"
+
+func TestNextGrapheme(t *testing.T) {
+	tests := []struct {
+		name, s, want string
+	}{
+		{"empty", "", ""},
+		{"ASCII", "abc", "a"},
+		{"combining accent", "éx", "é"},
+		{"CR LF", "\r\nx", "\r\n"},
+		{"skin tone", "\U0001F44D\U0001F3FDx", "\U0001F44D\U0001F3FD"},
+		{"joined emoji", "\U0001F468‍\U0001F4BBx", "\U0001F468‍\U0001F4BB"},
+		{"two flags", "\U0001F1F3\U0001F1FF\U0001F1E6\U0001F1FA", "\U0001F1F3\U0001F1FF"},
+		{"Hangul jamo", "각x", "각"},
+		{"Hangul LV and T", "각x", "각"},
+		{"Devanagari vowel sign", "किक", "कि"},
+		{"joiner before a letter", "a‍b", "a‍"},
+	}
+	for _, tt := range tests {
+		if got := tt.s[:nextGrapheme(tt.s)]; got != tt.want {
+			t.Errorf("%s: nextGrapheme(%+q) = %+q, want %+q", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package preview
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chain"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/elider"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/htmlstrip"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/reader"
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/utf8clean"
+)
+
+// DefaultMaxChars is the longest preview in characters, the limit RFC 8621
+// sets for Email.preview.
+const DefaultMaxChars = 256
+
+// Builder configures and makes a preview: the start of a message body as
+// plain text on one line.
+type Builder struct {
+	chain    *chain.Builder // Makes the text, as the chunking chain does
+	maxChars int
+}
+
+// NewBuilder creates a Builder reading a body from r. Without
+// WithContentType the body is treated as HTML.
+func NewBuilder(r io.Reader) *Builder {
+	return &Builder{chain: chain.NewBuilder(r), maxChars: DefaultMaxChars}
+}
+
+// WithMaxChars sets the longest preview in characters (Unicode code
+// points). The preview is cut at a grapheme cluster boundary, so it can be
+// a few characters shorter.
+func (b *Builder) WithMaxChars(n int) *Builder {
+	b.maxChars = n
+	return b
+}
+
+// WithCharset sets the charset of the input. Empty means UTF-8.
+func (b *Builder) WithCharset(charset string) *Builder {
+	b.chain.WithCharset(charset)
+	return b
+}
+
+// WithTransferEncoding sets the Content-Transfer-Encoding of the input.
+func (b *Builder) WithTransferEncoding(encoding string) *Builder {
+	b.chain.WithTransferEncoding(encoding)
+	return b
+}
+
+// WithContentType chooses how the input is turned into plain text from its
+// Content-Type header value, with chain.Builder.WithContentType.
+func (b *Builder) WithContentType(contentType string) *Builder {
+	b.chain.WithContentType(contentType)
+	return b
+}
+
+// WithLimits bounds the resources the body may use, as
+// chain.Builder.WithLimits does.
+func (b *Builder) WithLimits(l chain.Limits) *Builder {
+	b.chain.WithLimits(l)
+	return b
+}
+
+// WithReaderOptions adds options for the reader stage.
+func (b *Builder) WithReaderOptions(opts ...reader.Option) *Builder {
+	b.chain.WithReaderOptions(opts...)
+	return b
+}
+
+// WithUTF8CleanOptions adds options for the utf8clean stage.
+func (b *Builder) WithUTF8CleanOptions(opts ...utf8clean.Option) *Builder {
+	b.chain.WithUTF8CleanOptions(opts...)
+	return b
+}
+
+// WithHTMLStripOptions adds options for the htmlstrip stage.
+func (b *Builder) WithHTMLStripOptions(opts ...htmlstrip.Option) *Builder {
+	b.chain.WithHTMLStripOptions(opts...)
+	return b
+}
+
+// WithEliderOptions adds options for the elider stage.
+func (b *Builder) WithEliderOptions(opts ...elider.Option) *Builder {
+	b.chain.WithEliderOptions(opts...)
+	return b
+}
+
+// Build reads the body and returns its preview: the text from
+// chain.Builder.BuildText, with each run of whitespace collapsed to a
+// single space, trimmed and cut to at most WithMaxChars characters. It reads only as much of the body as the preview needs.
+func (b *Builder) Build() (string, error) {
+	src, err := b.chain.BuildText()
+	if err != nil {
+		return "", err
+	}
+
+	// Collect one character more than the limit, so the cut can tell
+	// whether it splits a grapheme cluster
+	var text strings.Builder
+	chars := 0
+	space := false
+	for chars <= b.maxChars {
+		block, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, r := range block {
+			if chars > b.maxChars {
+				break
+			}
+			if unicode.IsSpace(r) {
+				space = true
+				continue
+			}
+			if space && chars > 0 {
+				text.WriteByte(' ')
+				chars++
+			}
+			space = false
+			text.WriteRune(r)
+			chars++
+		}
+	}
+	return truncate(text.String(), b.maxChars), nil
+}
+
+// truncate returns the longest prefix of s of at most max characters that
+// ends at a grapheme cluster boundary, without trailing space.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	end, chars := 0, 0
+	for end < len(s) {
+		n := nextGrapheme(s[end:])
+		chars += utf8.RuneCountInString(s[end : end+n])
+		if chars > max {
+			break
+		}
+		end += n
+	}
+	return strings.TrimRight(s[:end], " ")
+}
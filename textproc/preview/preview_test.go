@@ -0,0 +1,162 @@
+package preview
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc/chain"
+)
+
+// build makes a preview of input with the Builder configured by configure.
+func build(t *testing.T, input string, configure func(*Builder) *Builder) string {
+	t.Helper()
+	b := NewBuilder(strings.NewReader(input))
+	if configure != nil {
+		b = configure(b)
+	}
+	p, err := b.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p
+}
+
+func TestPreviewHTML(t *testing.T) {
+	input := "<html><head><style>p { color: red }</style></head><body>\n" +
+		"<p>Hi   Bob,</p>\n<p>The <b>quarterly</b> numbers &amp; charts are attached.</p></body></html>"
+	got := build(t, input, nil)
+	if want := "Hi Bob, The quarterly numbers & charts are attached."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPreviewContentTypes(t *testing.T) {
+	tests := []struct {
+		contentType string
+		input       string
+		want        string
+	}{
+		{"text/plain", "Hi <Bob>,\n\n\tSee you   soon.\n", "Hi <Bob>, See you soon."},
+		{"text/plain; format=flowed", "This line is \nflowed.\n", "This line is flowed."},
+		{"text/enriched", "<bold>Hello</bold> there", "Hello there"},
+		{"text/html; charset=utf-8", "<p>One</p><p>Two</p>", "One Two"},
+	}
+	for _, tt := range tests {
+		got := build(t, tt.input, func(b *Builder) *Builder { return b.WithContentType(tt.contentType) })
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewInvalidContentType(t *testing.T) {
+	_, err := NewBuilder(strings.NewReader("x")).WithContentType(";;").Build()
+	if err == nil {
+		t.Error("expected an error for an invalid content type")
+	}
+}
+
+func TestPreviewElidesQuotesAndSignatures(t *testing.T) {
+	input := "Sounds good.\n\nOn Mon, Alice wrote:\n> Shall we meet?\n> Tuesday?\n\n-- \nBob\n"
+	got := build(t, input, func(b *Builder) *Builder { return b.WithContentType("text/plain") })
+	if strings.Contains(got, "Shall we meet") || strings.Contains(got, "Bob") {
+		t.Errorf("expected quote and signature elided, got %q", got)
+	}
+	if !strings.HasPrefix(got, "Sounds good.") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPreviewDecodes(t *testing.T) {
+	got := build(t, "Caf=E9 au lait", func(b *Builder) *Builder {
+		return b.WithContentType("text/plain").WithCharset("ISO-8859-1").WithTransferEncoding("quoted-printable")
+	})
+	if want := "Café au lait"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPreviewTruncates(t *testing.T) {
+	input := strings.Repeat("word ", 100)
+	got := build(t, input, func(b *Builder) *Builder { return b.WithContentType("text/plain") })
+	if n := utf8.RuneCountInString(got); n > DefaultMaxChars || n < DefaultMaxChars-5 {
+		t.Errorf("expected about %d characters, got %d", DefaultMaxChars, n)
+	}
+	if strings.HasSuffix(got, " ") {
+		t.Errorf("expected no trailing space, got %q", got)
+	}
+
+	got = build(t, "Short  text ", func(b *Builder) *Builder { return b.WithContentType("text/plain").WithMaxChars(10) })
+	if want := "Short text"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPreviewTruncatesAtGraphemeBoundary(t *testing.T) {
+	// Each flag is two characters, so a limit of 5 fits two flags
+	flags := strings.Repeat("\U0001F1F3\U0001F1FF", 4)
+	got := build(t, flags, func(b *Builder) *Builder { return b.WithContentType("text/plain").WithMaxChars(5) })
+	if want := strings.Repeat("\U0001F1F3\U0001F1FF", 2); got != want {
+		t.Errorf("got %+q, want %+q", got, want)
+	}
+
+	// The accent after the fifth character stays with it, so both go
+	got = build(t, "abcde\u0301f", func(b *Builder) *Builder { return b.WithContentType("text/plain").WithMaxChars(5) })
+	if want := "abcd"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if len(p) > 512 {
+		p = p[:512]
+	}
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestPreviewReadsOnlyWhatItNeeds(t *testing.T) {
+	r := &countingReader{r: strings.NewReader(strings.Repeat("A line of text.\n\n", 100000))}
+	p, err := NewBuilder(r).WithContentType("text/plain").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utf8.RuneCountInString(p) > DefaultMaxChars {
+		t.Errorf("preview too long: %d characters", utf8.RuneCountInString(p))
+	}
+	if r.n > 64*1024 {
+		t.Errorf("read %d bytes for a %d character preview", r.n, DefaultMaxChars)
+	}
+}
+
+// errReader fails every read.
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+func TestPreviewReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	if _, err := NewBuilder(errReader{readErr}).Build(); !errors.Is(err, readErr) {
+		t.Errorf("expected %v, got %v", readErr, err)
+	}
+}
+
+func TestPreviewLimits(t *testing.T) {
+	_, err := NewBuilder(strings.NewReader(strings.Repeat("<div>", 1000) + "text")).
+		WithLimits(chain.Limits{MaxHTMLDepth: 100}).
+		Build()
+	var limitErr chain.LimitExceededError
+	if !errors.As(err, &limitErr) || limitErr.Limit != chain.LimitHTMLDepth {
+		t.Errorf("expected the HTML depth limit, got %v", err)
+	}
+}
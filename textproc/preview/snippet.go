@@ -0,0 +1,186 @@
+package preview
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxSnippetBytes is the largest snippet in octets, the limit RFC 8621
+// sets for SearchSnippet preview.
+const MaxSnippetBytes = 255
+
+// snippetContext is how many bytes of text before the first match a
+// snippet tries to include.
+const snippetContext = 60
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// escaper replaces the characters RFC 8621 requires to be escaped in
+// SearchSnippet text.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Matcher finds search terms in text and highlights them, for JMAP
+// SearchSnippet/get. A term matches case-insensitively at the start of a
+// word, so "run" highlights the start of "Running"; a term of several
+// words matches them separated by any whitespace. In scripts written
+// without spaces between words, such as Chinese, Japanese and Thai, a
+// term matches anywhere. Create one with
+// NewMatcher and use it for every Email matching the same filter.
+type Matcher struct {
+	pattern *regexp.Regexp // Nil if there are no terms
+}
+
+// NewMatcher creates a Matcher for terms. Empty terms are ignored, and
+// where terms overlap the longest match is highlighted.
+func NewMatcher(terms ...string) *Matcher {
+	var alts []string
+	for _, term := range terms {
+		words := strings.Fields(term)
+		if len(words) == 0 {
+			continue
+		}
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		alts = append(alts, strings.Join(words, `\s+`))
+	}
+	if len(alts) == 0 {
+		return &Matcher{}
+	}
+	// Longer alternatives first, so the longest match wins
+	slices.SortStableFunc(alts, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return &Matcher{pattern: regexp.MustCompile(`(?i)` + strings.Join(alts, "|"))}
+}
+
+// matches returns the byte ranges of the matches in s.
+func (m *Matcher) matches(s string) [][]int {
+	if m.pattern == nil {
+		return nil
+	}
+	var found [][]int
+	for _, loc := range m.pattern.FindAllStringIndex(s, -1) {
+		if loc[0] < loc[1] && wordStart(s, loc[0]) {
+			found = append(found, loc)
+		}
+	}
+	return found
+}
+
+// unspacedScripts are written without spaces between words, so a word can
+// start at any character in them.
+var unspacedScripts = []*unicode.RangeTable{
+	unicode.Han, unicode.Hiragana, unicode.Katakana,
+	unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+}
+
+// wordStart reports whether a word can start at s[i]: it is not preceded
+// by a letter, digit or mark, or it or the character before it is in a
+// script written without spaces.
+func wordStart(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	if unicode.In(prev, unspacedScripts...) || unicode.In(r, unspacedScripts...) {
+		return true
+	}
+	return !unicode.In(prev, unicode.L, unicode.N, unicode.M)
+}
+
+// Highlight returns text with its HTML special characters escaped and each
+// match wrapped in <mark></mark>, as RFC 8621 requires for the subject of
+// a SearchSnippet. The result is false, and the text empty, if no term
+// matches.
+func (m *Matcher) Highlight(text string) (string, bool) {
+	found := m.matches(text)
+	if len(found) == 0 {
+		return "", false
+	}
+	var out strings.Builder
+	last := 0
+	for _, loc := range found {
+		out.WriteString(escaper.Replace(text[last:loc[0]]))
+		out.WriteString(markOpen)
+		out.WriteString(escaper.Replace(text[loc[0]:loc[1]]))
+		out.WriteString(markClose)
+		last = loc[1]
+	}
+	out.WriteString(escaper.Replace(text[last:]))
+	return out.String(), true
+}
+
+// Snippet returns the part of text around the first match, highlighted as
+// Highlight does, for the preview of a SearchSnippet. Whitespace is
+// collapsed and the snippet starts at a word shortly before the match. It
+// is at most MaxSnippetBytes octets, escaping and markup included, and is
+// cut at a grapheme cluster boundary, never inside an entity or a mark.
+// The result is false, and the snippet empty, if no term matches.
+func (m *Matcher) Snippet(text string) (string, bool) {
+	text = strings.Join(strings.Fields(text), " ")
+	found := m.matches(text)
+	if len(found) == 0 {
+		return "", false
+	}
+
+	start := 0
+	if first := found[0][0]; first > snippetContext {
+		start = first
+		if i := strings.IndexByte(text[first-snippetContext:first], ' '); i >= 0 {
+			start = first - snippetContext + i + 1
+		}
+	}
+
+	return strings.TrimRight(writeSnippet(text[start:], found, start), " "), true
+}
+
+// writeSnippet escapes and highlights text, which starts at offset start
+// of the text the matches in found are from, until MaxSnippetBytes is
+// reached.
+func writeSnippet(text string, found [][]int, start int) string {
+	var out strings.Builder
+	pos := 0
+	for _, loc := range found {
+		mStart, mEnd := loc[0]-start, loc[1]-start
+		if !writeFitting(&out, text[pos:mStart], 0) {
+			return out.String()
+		}
+		// A mark needs room for its first character and closing tag
+		first := escaper.Replace(text[mStart : mStart+nextGrapheme(text[mStart:])])
+		if out.Len()+len(markOpen)+len(first)+len(markClose) > MaxSnippetBytes {
+			return out.String()
+		}
+		out.WriteString(markOpen)
+		ok := writeFitting(&out, text[mStart:mEnd], len(markClose))
+		out.WriteString(markClose)
+		if !ok {
+			return out.String()
+		}
+		pos = mEnd
+	}
+	writeFitting(&out, text[pos:], 0)
+	return out.String()
+}
+
+// writeFitting escapes and writes to out as many grapheme clusters of s as
+// fit in MaxSnippetBytes while leaving reserve bytes free, and reports
+// whether all of s fitted.
+func writeFitting(out *strings.Builder, s string, reserve int) bool {
+	for s != "" {
+		n := nextGrapheme(s)
+		escaped := escaper.Replace(s[:n])
+		if out.Len()+len(escaped)+reserve > MaxSnippetBytes {
+			return false
+		}
+		out.WriteString(escaped)
+		s = s[n:]
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package preview
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		terms []string
+		text  string
+		want  string
+	}{
+		{[]string{"budget"}, "Q3 Budget review", "Q3 <mark>Budget</mark> review"},
+		{[]string{"run"}, "Running late, run!", "<mark>Run</mark>ning late, <mark>run</mark>!"},
+		{[]string{"report"}, "Misreported <b> & more report", "Misreported &lt;b&gt; &amp; more <mark>report</mark>"},
+		{[]string{"quarterly report"}, "The quarterly\n report is in", "The <mark>quarterly\n report</mark> is in"},
+		{[]string{"a", "a&b"}, "see a&b", "see <mark>a&amp;b</mark>"},
+		{[]string{"straße"}, "STRASSE or Straße", "STRASSE or <mark>Straße</mark>"},
+		{[]string{"ÉTÉ"}, "un été chaud", "un <mark>été</mark> chaud"},
+		{[]string{"東京"}, "来週東京で会議があります", "来週<mark>東京</mark>で会議があります"},
+		{[]string{"会议"}, "明天的会议改到下午", "明天的<mark>会议</mark>改到下午"},
+		{[]string{"ประชุม"}, "พรุ่งนี้มีประชุมที่สำนักงาน", "พรุ่งนี้มี<mark>ประชุม</mark>ที่สำนักงาน"},
+		{[]string{"Tokyo"}, "東京Tokyoオフィス", "東京<mark>Tokyo</mark>オフィス"},
+	}
+	for _, tt := range tests {
+		got, ok := NewMatcher(tt.terms...).Highlight(tt.text)
+		if !ok || got != tt.want {
+			t.Errorf("Highlight(%q) with %q = %q, %v; want %q", tt.text, tt.terms, got, ok, tt.want)
+		}
+	}
+}
+
+func TestHighlightNoMatch(t *testing.T) {
+	for _, m := range []*Matcher{NewMatcher("budget"), NewMatcher(), NewMatcher("", "  ")} {
+		if got, ok := m.Highlight("Misbudgeted <plans>"); ok || got != "" {
+			t.Errorf("expected no match, got %q, %v", got, ok)
+		}
+	}
+}
+
+func TestHighlightQuotesTerms(t *testing.T) {
+	got, ok := NewMatcher("c++", "(x)").Highlight("c++ and (x) but not cpp")
+	if want := "<mark>c++</mark> and <mark>(x)</mark> but not cpp"; !ok || got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSnippetUnspacedScripts(t *testing.T) {
+	tests := []struct {
+		term, text, want string
+	}{
+		{"東京", "私は東京に住んでいます", "私は<mark>東京</mark>に住んでいます"},
+		{"ประชุม", "พรุ่งนี้มีประชุมที่สำนักงาน", "พรุ่งนี้มี<mark>ประชุม</mark>ที่สำนักงาน"},
+	}
+	for _, tt := range tests {
+		got, ok := NewMatcher(tt.term).Snippet(tt.text)
+		if !ok || got != tt.want {
+			t.Errorf("Snippet(%q) with %q = %q, %v; want %q", tt.text, tt.term, got, ok, tt.want)
+		}
+	}
+}
+
+func TestSnippetShortText(t *testing.T) {
+	got, ok := NewMatcher("lunch").Snippet("Are we still on for\n\nlunch <today>?")
+	if want := "Are we still on for <mark>lunch</mark> &lt;today&gt;?"; !ok || got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, ok := NewMatcher("dinner").Snippet("Lunch today?"); ok || got != "" {
+		t.Errorf("expected no match, got %q, %v", got, ok)
+	}
+}
+
+func TestSnippetStartsNearMatch(t *testing.T) {
+	text := strings.Repeat("filler words here ", 50) + "the invoice is attached " + strings.Repeat("more text ", 50)
+	got, ok := NewMatcher("invoice").Snippet(text)
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if len(got) > MaxSnippetBytes {
+		t.Errorf("snippet is %d bytes, more than %d", len(got), MaxSnippetBytes)
+	}
+	i := strings.Index(got, "<mark>invoice</mark>")
+	if i < 0 || i > snippetContext {
+		t.Errorf("expected the match near the start, got %q", got)
+	}
+	if strings.HasPrefix(got, " ") || strings.HasPrefix(got, "ords") || strings.HasSuffix(got, " ") {
+		t.Errorf("expected the snippet to start at a word and be trimmed, got %q", got)
+	}
+}
+
+func TestSnippetLimitCountsEscapes(t *testing.T) {
+	text := "tag " + strings.Repeat("<&> ", 200)
+	got, ok := NewMatcher("tag").Snippet(text)
+	if !ok || len(got) > MaxSnippetBytes {
+		t.Fatalf("got %d bytes, %v", len(got), ok)
+	}
+	// Never cut inside an entity
+	trimmed := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(got, "&lt;"), "&amp;"), "&gt;")
+	if i := strings.LastIndexByte(trimmed, '&'); i >= 0 && !strings.Contains(trimmed[i:], ";") {
+		t.Errorf("snippet ends inside an entity: %q", got[len(got)-10:])
+	}
+}
+
+func TestSnippetNeverSplitsMarks(t *testing.T) {
+	text := strings.Repeat("x ", 100) + strings.Repeat("spam ", 100)
+	got, ok := NewMatcher("spam").Snippet(text)
+	if !ok || len(got) > MaxSnippetBytes {
+		t.Fatalf("got %d bytes, %v", len(got), ok)
+	}
+	if strings.Count(got, "<mark>") != strings.Count(got, "</mark>") {
+		t.Errorf("unbalanced marks in %q", got)
+	}
+
+	// A match longer than the limit is cut inside its mark
+	long := strings.Repeat("a", 400)
+	got, ok = NewMatcher(long).Snippet(long)
+	if !ok || len(got) > MaxSnippetBytes || !strings.HasPrefix(got, "<mark>a") || !strings.HasSuffix(got, "a</mark>") {
+		t.Errorf("got %d bytes: %q", len(got), got)
+	}
+}
+
+func TestSnippetGraphemes(t *testing.T) {
+	// 4-byte flags that do not divide the limit evenly
+	text := "flags " + strings.Repeat("\U0001F1F3\U0001F1FF", 100)
+	got, ok := NewMatcher("flags").Snippet(text)
+	if !ok || len(got) > MaxSnippetBytes {
+		t.Fatalf("got %d bytes, %v", len(got), ok)
+	}
+	rest := strings.TrimPrefix(got, "<mark>flags</mark> ")
+	if len(rest)%8 != 0 {
+		t.Errorf("snippet splits a flag: %d bytes of flags", len(rest))
+	}
+}
@@ -9,7 +9,7 @@ type BytesProcessor interface {
 
 // StringProcessor reads validated UTF-8 text and writes processed text.
 // Input: string (valid UTF-8), Output: string blocks via Next()
-// Implemented by: htmlstrip, elider, chunker, splitter
+// Implemented by: htmlstrip, flowed, enriched, elider
 type StringProcessor interface {
 	Next() (string, error)
 }
@@ -0,0 +1,270 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kind names a kind of personal data. The built-in kinds are the constants
+// below; WithPattern adds others.
+type Kind string
+
+// Built-in kinds, all detected by default.
+const (
+	// KindEmail is an email address.
+	KindEmail Kind = "email"
+	// KindCard is a payment card number passing the Luhn check, with the
+	// prefix and length of a major card network (Visa, Mastercard,
+	// American Express or Discover), optionally grouped with spaces or
+	// dashes.
+	KindCard Kind = "card"
+	// KindIBAN is an International Bank Account Number with a valid
+	// checksum, optionally grouped with spaces.
+	KindIBAN Kind = "iban"
+	// KindPhone is an E.164 phone number: "+", a country code and up to 15
+	// digits in all, optionally grouped with spaces, dots, dashes or
+	// parentheses, as in "(+44) 20 7946 0958".
+	KindPhone Kind = "phone"
+	// KindSSN is a US Social Security number written as 123-45-6789.
+	KindSSN Kind = "ssn"
+	// KindNINO is a UK National Insurance number such as AB 12 34 56 C.
+	KindNINO Kind = "nino"
+)
+
+// BuiltinKinds lists the built-in kinds in the order they are detected.
+// Where matches overlap, the one starting first is redacted, and of those
+// the longest.
+var BuiltinKinds = []Kind{KindEmail, KindIBAN, KindCard, KindPhone, KindSSN, KindNINO}
+
+// detector finds one kind of personal data.
+type detector struct {
+	kind    Kind
+	pattern *regexp.Regexp
+	check   func(s string, start, end int) (int, int, bool) // Validates a match in s, or nil
+}
+
+// builtinDetectors are the detectors for the built-in kinds.
+var builtinDetectors = map[Kind]detector{
+	KindEmail: {
+		kind:    KindEmail,
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	KindIBAN: {
+		kind:    KindIBAN,
+		pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		check:   trimGroups(validIBAN),
+	},
+	KindCard: {
+		kind:    KindCard,
+		pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		check:   anyGroups(validCard),
+	},
+	KindPhone: {
+		kind:    KindPhone,
+		pattern: regexp.MustCompile(`\(?\+[1-9](?:[ .()-]{0,2}\d){6,14}`),
+		check:   validPhone,
+	},
+	KindSSN: {
+		kind:    KindSSN,
+		pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		check:   whole(validSSN),
+	},
+	KindNINO: {
+		kind:    KindNINO,
+		pattern: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+		check:   whole(validNINO),
+	},
+}
+
+// whole checks the whole of a match with valid.
+func whole(valid func(string) bool) func(s string, start, end int) (int, int, bool) {
+	return func(s string, start, end int) (int, int, bool) {
+		return start, end, valid(s[start:end])
+	}
+}
+
+// trimGroups checks a grouped number with valid, dropping groups from the
+// end until it passes: the patterns are greedy, so a match can run on into
+// a following number or capitalised word.
+func trimGroups(valid func(string) bool) func(s string, start, end int) (int, int, bool) {
+	return func(s string, start, end int) (int, int, bool) {
+		if valid(s[start:end]) {
+			return start, end, true
+		}
+		for i := end - 1; i > start; i-- {
+			if (s[i] == ' ' || s[i] == '-') && valid(s[start:i]) {
+				return start, i, true
+			}
+		}
+		return 0, 0, false
+	}
+}
+
+// anyGroups checks a grouped number like trimGroups, also dropping groups
+// from the start: a match can begin with a number before the one wanted,
+// as in "ref 12 4111 1111 1111 1111".
+func anyGroups(valid func(string) bool) func(s string, start, end int) (int, int, bool) {
+	trim := trimGroups(valid)
+	return func(s string, start, end int) (int, int, bool) {
+		for i := start; i < end; i++ {
+			if i > start && s[i-1] != ' ' && s[i-1] != '-' {
+				continue
+			}
+			if from, to, ok := trim(s, i, end); ok {
+				return from, to, true
+			}
+		}
+		return 0, 0, false
+	}
+}
+
+// digitsOf returns the ASCII digits of s.
+func digitsOf(s string) []byte {
+	var digits []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			digits = append(digits, s[i])
+		}
+	}
+	return digits
+}
+
+// validCard reports whether s is a card number: it passes the Luhn check
+// and has the prefix and length of a major card network.
+func validCard(s string) bool {
+	return luhn(s) && cardNetwork(digitsOf(s))
+}
+
+// cardNetwork reports whether digits start with the issuer identification
+// number of Visa, Mastercard, American Express or Discover, and have a
+// length that network issues.
+func cardNetwork(digits []byte) bool {
+	n := len(digits)
+	if n < 13 {
+		return false
+	}
+	prefix := func(k int) int {
+		v := 0
+		for _, d := range digits[:k] {
+			v = v*10 + int(d-'0')
+		}
+		return v
+	}
+	switch {
+	case digits[0] == '4':
+		return n == 13 || n == 16 || n == 19 // Visa
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return n == 16 // Mastercard
+	case prefix(2) == 34 || prefix(2) == 37:
+		return n == 15 // American Express
+	case prefix(4) == 6011 || prefix(2) == 65:
+		return n >= 16 && n <= 19 // Discover
+	}
+	return false
+}
+
+// luhn reports whether the digits of s pass the Luhn check.
+func luhn(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validIBAN reports whether s, without spaces, has a valid IBAN checksum:
+// moving the first four characters to the end and reading letters as 10
+// to 35 gives a number that is 1 modulo 97.
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	s = s[4:] + s[:4]
+	rem := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			rem = (rem*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			rem = (rem*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return rem == 1
+}
+
+// validPhone checks a phone number match: 8 to 15 digits, balanced
+// parentheses, and not part of a longer word or number. An opening
+// parenthesis before the "+" is dropped if nothing in the match closes it.
+func validPhone(s string, start, end int) (int, int, bool) {
+	if s[start] == '(' && strings.Count(s[start:end], "(") != strings.Count(s[start:end], ")") {
+		start++
+	}
+	if start > 0 && isWordByte(s[start-1]) {
+		return 0, 0, false
+	}
+	if end < len(s) && (isWordByte(s[end]) || s[end] == '+') {
+		return 0, 0, false
+	}
+	match := s[start:end]
+	if strings.Count(match, "(") != strings.Count(match, ")") {
+		return 0, 0, false
+	}
+	n := len(digitsOf(match))
+	return start, end, n >= 8 && n <= 15
+}
+
+// validSSN rejects numbers the Social Security Administration never
+// issues: area 000, 666 or 900-999, group 00 and serial 0000.
+func validSSN(s string) bool {
+	area, group, serial := s[0:3], s[4:6], s[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// validNINO rejects the prefixes never issued for National Insurance
+// numbers.
+func validNINO(s string) bool {
+	switch s[:2] {
+	case "BG", "GB", "KN", "NK", "NT", "TN", "ZZ":
+		return false
+	}
+	return s[1] != 'O'
+}
+
+// isWordByte reports whether b is an ASCII letter, digit or underscore.
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// normalize returns the form of a match used to give equal values the same
+// pseudonym: emails ignore case, and the built-in numbers ignore grouping.
+// Matches of added patterns are used as they are.
+func normalize(kind Kind, s string) string {
+	switch kind {
+	case KindEmail:
+		return strings.ToLower(s)
+	case KindCard, KindIBAN, KindPhone, KindSSN, KindNINO:
+	default:
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" .()-", r) {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(s))
+}
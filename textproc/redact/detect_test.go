@@ -0,0 +1,111 @@
+package redact
+
+import "testing"
+
+func TestLuhn(t *testing.T) {
+	tests := map[string]bool{
+		"4111111111111111":    true,
+		"4111 1111 1111 1111": true,
+		"5500-0000-0000-0004": true,
+		"378282246310005":     true,
+		"4111111111111112":    false,
+		"411111111111":        false, // Too short
+		"0000000000000":       true,
+	}
+	for s, want := range tests {
+		if got := luhn(s); got != want {
+			t.Errorf("luhn(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestValidCard(t *testing.T) {
+	tests := map[string]bool{
+		"4111111111111111":    true,  // Visa
+		"4222222222222":       true,  // Visa, 13 digits
+		"5500 0000 0000 0004": true,  // Mastercard
+		"2223000048400011":    true,  // Mastercard 2-series
+		"378282246310005":     true,  // American Express
+		"6011111111111117":    true,  // Discover
+		"0000000000000":       false, // Luhn-valid, no network
+		"1234567890123452":    false, // Luhn-valid, no network
+		"411111111111116":     false, // Visa prefix, wrong length
+		"3782822463100050":    false, // Amex prefix, wrong length
+		"4111111111111112":    false, // Fails Luhn
+	}
+	for s, want := range tests {
+		if got := validCard(s); got != want {
+			t.Errorf("validCard(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	tests := map[string]bool{
+		"GB82WEST12345698765432":       true,
+		"GB82 WEST 1234 5698 7654 32":  true,
+		"DE89370400440532013000":       true,
+		"FR1420041010050500013M02606":  true,
+		"GB83WEST12345698765432":       false,
+		"GB82WEST1234569876543":        false,
+		"GB82":                         false,
+		"gb82west12345698765432":       false,
+		"DE89 3704 0044 0532 0130 00X": false,
+	}
+	for s, want := range tests {
+		if got := validIBAN(s); got != want {
+			t.Errorf("validIBAN(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestValidSSN(t *testing.T) {
+	tests := map[string]bool{
+		"123-45-6789": true,
+		"000-12-3456": false,
+		"666-12-3456": false,
+		"912-12-3456": false,
+		"123-00-4567": false,
+		"123-45-0000": false,
+	}
+	for s, want := range tests {
+		if got := validSSN(s); got != want {
+			t.Errorf("validSSN(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestValidNINO(t *testing.T) {
+	tests := map[string]bool{
+		"AB123456C":     true,
+		"AB 12 34 56 C": true,
+		"GB123456A":     false,
+		"ZZ123456A":     false,
+		"AO123456A":     false,
+	}
+	for s, want := range tests {
+		if got := validNINO(s); got != want {
+			t.Errorf("validNINO(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		a, b string
+	}{
+		{KindEmail, "Alice@Example.COM", "alice@example.com"},
+		{KindCard, "4111 1111 1111 1111", "4111-1111-1111-1111"},
+		{KindPhone, "+1 (415) 555-2671", "+1.415.555.2671"},
+		{KindIBAN, "GB82 WEST 1234 5698 7654 32", "GB82WEST12345698765432"},
+	}
+	for _, tt := range tests {
+		if normalize(tt.kind, tt.a) != normalize(tt.kind, tt.b) {
+			t.Errorf("normalize(%s) differs for %q and %q", tt.kind, tt.a, tt.b)
+		}
+	}
+	if normalize("ticket", "AB-1") == normalize("ticket", "ab1") {
+		t.Error("added kinds should not be normalized")
+	}
+}
@@ -0,0 +1,32 @@
+// Package redact replaces personal data in text before it is stored or
+// indexed.
+//
+// Chunks of a message often carry card numbers, bank accounts, phone
+// numbers, email addresses and government IDs that should not reach an
+// index or a model. The Processor finds them with detectors and replaces
+// each match with a token such as "[EMAIL]".
+//
+// The built-in detectors validate what they find, to keep false positives
+// down: card numbers must pass the Luhn check and have a card network's
+// prefix and length, IBANs their mod-97 checksum, phone numbers must be
+// E.164 ("+" and 8 to 15 digits), and US Social Security and UK National
+// Insurance numbers must not use ranges that are never issued. WithKinds
+// and WithoutKinds choose the detectors and WithPattern adds regular
+// expressions for other kinds.
+//
+// WithPseudonyms numbers the tokens instead, so the same value gets the
+// same token throughout a document ("[EMAIL_1]" wherever alice@example.com
+// appears) and text stays readable without revealing it. Pseudonyms are
+// kept per Processor, so they are not linkable across documents.
+//
+// Report gives the count and input spans of the matches of each kind; the
+// values themselves are never kept. Offsets maps the redacted text back to
+// the input.
+//
+// Text is redacted a line at a time; matches never span lines.
+//
+// Position in the pipeline:
+//
+//	After: elider
+//	Before: chunker
+package redact
@@ -0,0 +1,277 @@
+package redact
+
+import (
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// maxPending is the most input, in bytes, held back waiting for the end of
+// a line. A longer line is redacted up to its last space, so a match can
+// only be split if it is more than this long or has no space before it.
+const maxPending = 64 * 1024
+
+// KindReport describes what was redacted of one kind. Count is the number
+// of matches and Spans are their input bytes. The matched values are never
+// kept.
+type KindReport struct {
+	Count int
+	Spans []textproc.Span
+}
+
+// Report describes what was redacted, by kind. Kinds that never matched
+// are absent.
+type Report map[Kind]KindReport
+
+// Processor reads text and returns it with personal data replaced.
+type Processor struct {
+	src        textproc.StringProcessor
+	detectors  []detector
+	disabled   map[Kind]bool
+	tokens     map[Kind]string
+	pseudonyms bool
+
+	input string // Unprocessed input, the end of a line
+	done  bool
+
+	names  map[string]string // Pseudonyms by kind and normalized value
+	counts map[Kind]int      // Pseudonyms given out, by kind
+	report map[Kind]*KindReport
+
+	offsets textproc.OffsetMap
+	inPos   int64 // Input offset of input[0]
+	outBase int64 // Output offset of the next block
+}
+
+// Option configures a Processor.
+type Option func(*Processor)
+
+// WithKinds enables only the given built-in kinds. Kinds added with
+// WithPattern are not affected.
+func WithKinds(kinds ...Kind) Option {
+	return func(p *Processor) {
+		p.disabled = map[Kind]bool{}
+		for _, k := range BuiltinKinds {
+			p.disabled[k] = !slices.Contains(kinds, k)
+		}
+	}
+}
+
+// WithoutKinds disables the given kinds, built-in or added.
+func WithoutKinds(kinds ...Kind) Option {
+	return func(p *Processor) {
+		if p.disabled == nil {
+			p.disabled = map[Kind]bool{}
+		}
+		for _, k := range kinds {
+			p.disabled[k] = true
+		}
+	}
+}
+
+// WithPattern adds a detector redacting every match for pattern as kind.
+// Added detectors run after the built-in ones. Patterns should not match
+// across lines.
+func WithPattern(kind Kind, pattern *regexp.Regexp) Option {
+	return func(p *Processor) {
+		p.detectors = append(p.detectors, detector{kind: kind, pattern: pattern})
+	}
+}
+
+// WithToken sets the text replacing matches of kind. The default is the
+// kind in upper case and brackets, such as "[EMAIL]".
+func WithToken(kind Kind, token string) Option {
+	return func(p *Processor) {
+		if p.tokens == nil {
+			p.tokens = map[Kind]string{}
+		}
+		p.tokens[kind] = token
+	}
+}
+
+// WithPseudonyms numbers the replacements, so each distinct value gets its
+// own token, used wherever it appears in the document: "[EMAIL_1]",
+// "[EMAIL_2]". Values that differ only in case (for emails) or grouping
+// (for numbers) count as the same. The numbering belongs to the Processor,
+// so pseudonyms are consistent within a document but not across them.
+func WithPseudonyms() Option {
+	return func(p *Processor) {
+		p.pseudonyms = true
+	}
+}
+
+// NewProcessor creates a new Processor with the given StringProcessor source.
+func NewProcessor(src textproc.StringProcessor, opts ...Option) *Processor {
+	p := &Processor{src: src}
+	for _, k := range BuiltinKinds {
+		p.detectors = append(p.detectors, builtinDetectors[k])
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Next returns the next block of text with personal data redacted.
+// Returns io.EOF when all data has been consumed.
+func (p *Processor) Next() (string, error) {
+	for !p.done {
+		block, err := p.src.Next()
+		if err == io.EOF {
+			p.done = true
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		p.input += block
+		n := strings.LastIndexByte(p.input, '\n') + 1
+		if n == 0 && len(p.input) > maxPending {
+			n = strings.LastIndexAny(p.input, " \t") + 1
+			if n == 0 {
+				n = len(p.input)
+			}
+		}
+		if n > 0 {
+			out := p.redact(p.input[:n])
+			p.inPos += int64(n)
+			p.input = p.input[n:]
+			if out != "" {
+				return out, nil
+			}
+		}
+	}
+
+	if p.input != "" {
+		out := p.redact(p.input)
+		p.inPos += int64(len(p.input))
+		p.input = ""
+		return out, nil
+	}
+	return "", io.EOF
+}
+
+// match is a match for a detector, as offsets into the text being redacted.
+type match struct {
+	kind       Kind
+	start, end int
+}
+
+// redact returns s, which starts at input offset p.inPos, with the matches
+// of every enabled detector replaced.
+func (p *Processor) redact(s string) string {
+	var matches []match
+	for _, d := range p.detectors {
+		if p.disabled[d.kind] {
+			continue
+		}
+		for _, loc := range d.pattern.FindAllStringIndex(s, -1) {
+			start, end, ok := loc[0], loc[1], true
+			if d.check != nil {
+				start, end, ok = d.check(s, start, end)
+			}
+			if ok && start < end {
+				matches = append(matches, match{kind: d.kind, start: start, end: end})
+			}
+		}
+	}
+	// Where matches overlap, the first wins, then the longest
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return b.end - a.end
+	})
+
+	var out strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m.start < last {
+			continue
+		}
+		p.offsets.Add(p.outBase+int64(out.Len()), p.inPos+int64(last))
+		out.WriteString(s[last:m.start])
+		// The replacement's first and last bytes map to the match's, so a
+		// span covering the replacement covers the whole match
+		repl := p.replacement(m.kind, s[m.start:m.end])
+		p.offsets.Add(p.outBase+int64(out.Len()), p.inPos+int64(m.start))
+		if repl != "" {
+			p.offsets.Add(p.outBase+int64(out.Len()+len(repl)-1), p.inPos+int64(m.end-1))
+		}
+		out.WriteString(repl)
+		p.record(m.kind, p.inPos+int64(m.start), p.inPos+int64(m.end))
+		last = m.end
+	}
+	p.offsets.Add(p.outBase+int64(out.Len()), p.inPos+int64(last))
+	out.WriteString(s[last:])
+	p.outBase += int64(out.Len())
+	return out.String()
+}
+
+// replacement returns the text replacing value, a match of kind.
+func (p *Processor) replacement(kind Kind, value string) string {
+	token, ok := p.tokens[kind]
+	if !ok {
+		token = "[" + strings.ToUpper(string(kind)) + "]"
+	}
+	if !p.pseudonyms {
+		return token
+	}
+
+	key := string(kind) + "\x00" + normalize(kind, value)
+	if name, ok := p.names[key]; ok {
+		return name
+	}
+	if p.names == nil {
+		p.names = map[string]string{}
+		p.counts = map[Kind]int{}
+	}
+	p.counts[kind]++
+	n := "_" + strconv.Itoa(p.counts[kind])
+	name := token + n
+	if base, ok := strings.CutSuffix(token, "]"); ok {
+		name = base + n + "]"
+	}
+	p.names[key] = name
+	return name
+}
+
+// record notes that a match of kind covered the input from start to end.
+func (p *Processor) record(kind Kind, start, end int64) {
+	if p.report == nil {
+		p.report = map[Kind]*KindReport{}
+	}
+	r := p.report[kind]
+	if r == nil {
+		r = &KindReport{}
+		p.report[kind] = r
+	}
+	r.Count++
+	r.Spans = append(r.Spans, textproc.Span{Start: start, End: end})
+}
+
+// Report describes what has been redacted so far. It is complete once Next
+// has returned io.EOF.
+func (p *Processor) Report() Report {
+	report := make(Report, len(p.report))
+	for kind, r := range p.report {
+		report[kind] = KindReport{Count: r.Count, Spans: append([]textproc.Span(nil), r.Spans...)}
+	}
+	return report
+}
+
+// Offsets maps offsets in the output back to the input.
+func (p *Processor) Offsets() *textproc.OffsetMap {
+	return &p.offsets
+}
+
+// Ensure Processor implements StringProcessor and OffsetMapper
+var (
+	_ textproc.StringProcessor = (*Processor)(nil)
+	_ textproc.OffsetMapper    = (*Processor)(nil)
+)
@@ -0,0 +1,260 @@
+package redact
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// mockStringSource implements textproc.StringProcessor for testing.
+type mockStringSource struct {
+	blocks []string
+	err    error
+}
+
+func (m *mockStringSource) Next() (string, error) {
+	if len(m.blocks) == 0 {
+		if m.err != nil {
+			return "", m.err
+		}
+		return "", io.EOF
+	}
+	block := m.blocks[0]
+	m.blocks = m.blocks[1:]
+	return block, nil
+}
+
+// readAll reads all output from a Processor.
+func readAll(t *testing.T, p *Processor) string {
+	t.Helper()
+	var out strings.Builder
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.WriteString(s)
+	}
+}
+
+// redact runs a Processor with opts over input.
+func redact(t *testing.T, input string, opts ...Option) string {
+	t.Helper()
+	return readAll(t, NewProcessor(&mockStringSource{blocks: []string{input}}, opts...))
+}
+
+func TestRedactsBuiltinKinds(t *testing.T) {
+	tests := map[string]string{
+		"Mail alice.smith+news@mail.example.co.uk today": "Mail [EMAIL] today",
+		"Card 4111 1111 1111 1111, exp 12/29":            "Card [CARD], exp 12/29",
+		"Card 5500-0000-0000-0004.":                      "Card [CARD].",
+		"Pay GB82 WEST 1234 5698 7654 32 now":            "Pay [IBAN] now",
+		"Pay DE89370400440532013000.":                    "Pay [IBAN].",
+		"Call +44 20 7946 0958 or +1 (415) 555-2671.":    "Call [PHONE] or [PHONE].",
+		"Call (+44) 20 7946 0958.":                       "Call [PHONE].",
+		"Office (+1 415 555 2671), ask for Bob":          "Office ([PHONE]), ask for Bob",
+		"SSN 123-45-6789":                                "SSN [SSN]",
+		"NI number AB 12 34 56 C.":                       "NI number [NINO].",
+	}
+	for input, want := range tests {
+		if got := redact(t, input); got != want {
+			t.Errorf("redact(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestLeavesInvalidNumbers(t *testing.T) {
+	for _, input := range []string{
+		"Order 4111 1111 1111 1112 shipped",  // Fails Luhn
+		"Ref GB83 WEST 1234 5698 7654 32",    // Bad IBAN checksum
+		"Version 1.2.3, build 20240101",      // Not E.164
+		"Extension +12 345",                  // Too few digits
+		"Part 666-12-3456",                   // Never issued
+		"Serial 912-34-5678",                 // Never issued
+		"ID abc+4420794609581",               // Part of a word
+		"Code GB 12 34 56 A",                 // Never issued
+		"Total 1234567890123 items, not PII", // Fails Luhn
+	} {
+		if got := redact(t, input); got != input {
+			t.Errorf("redact(%q) = %q, want unchanged", input, got)
+		}
+	}
+}
+
+func TestLeavesNumbersThatAreNotCards(t *testing.T) {
+	// All pass the Luhn check
+	for _, input := range []string{
+		"Tracking 1234567890123452",   // No card network starts with 1
+		"Account 9000 0000 0000 0001", // Nor with 9
+		"Meter 8600123456789129",      // Nor with 8
+		"Invoice 0000000000000",       // Nor with 0
+		"Order 411111111111116",       // Visa, but 15 digits
+	} {
+		if got := redact(t, input); got != input {
+			t.Errorf("redact(%q) = %q, want unchanged", input, got)
+		}
+	}
+}
+
+func TestTrimsGreedyMatches(t *testing.T) {
+	tests := map[string]string{
+		"GB82 WEST 1234 5698 7654 32 OK":  "[IBAN] OK",
+		"4111 1111 1111 1111 12 pcs":      "[CARD] 12 pcs",
+		"4111-1111-1111-1111-99":          "[CARD]-99",
+		"DE89 3704 0044 0532 0130 00 EUR": "[IBAN] EUR",
+		"ref 12 4111 1111 1111 1111":      "ref 12 [CARD]",
+		"ref 12-4111-1111-1111-1111 ok":   "ref 12-[CARD] ok",
+	}
+	for input, want := range tests {
+		if got := redact(t, input); got != want {
+			t.Errorf("redact(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestOverlappingMatches(t *testing.T) {
+	// The phone-like part of the address is part of the email
+	got := redact(t, "Write to support+4420794609581@example.com")
+	if want := "Write to [EMAIL]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWithKinds(t *testing.T) {
+	input := "alice@example.com +44 20 7946 0958"
+	if got, want := redact(t, input, WithKinds(KindEmail)), "[EMAIL] +44 20 7946 0958"; got != want {
+		t.Errorf("WithKinds = %q, want %q", got, want)
+	}
+	if got, want := redact(t, input, WithoutKinds(KindEmail)), "alice@example.com [PHONE]"; got != want {
+		t.Errorf("WithoutKinds = %q, want %q", got, want)
+	}
+}
+
+func TestWithPattern(t *testing.T) {
+	pattern := regexp.MustCompile(`\bEMP-\d{6}\b`)
+	input := "Employee EMP-123456 and EMP-654321"
+	got := redact(t, input, WithPattern("employee", pattern))
+	if want := "Employee [EMPLOYEE] and [EMPLOYEE]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got = redact(t, input, WithPattern("employee", pattern), WithoutKinds("employee"))
+	if got != input {
+		t.Errorf("disabled pattern: got %q, want unchanged", got)
+	}
+}
+
+func TestWithToken(t *testing.T) {
+	got := redact(t, "alice@example.com", WithToken(KindEmail, "<redacted>"))
+	if want := "<redacted>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWithPseudonyms(t *testing.T) {
+	input := "From alice@example.com to bob@example.com, cc Alice@Example.com.\n" +
+		"Call +44 20 7946 0958, or +44.20.7946.0958, not +1 415 555 2671.\n"
+	want := "From [EMAIL_1] to [EMAIL_2], cc [EMAIL_1].\n" +
+		"Call [PHONE_1], or [PHONE_1], not [PHONE_2].\n"
+	if got := redact(t, input, WithPseudonyms()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got := redact(t, "a@example.com b@example.com a@example.com",
+		WithPseudonyms(), WithToken(KindEmail, "<person>"))
+	if want := "<person>_1 <person>_2 <person>_1"; got != want {
+		t.Errorf("custom token: got %q, want %q", got, want)
+	}
+}
+
+func TestPseudonymsArePerProcessor(t *testing.T) {
+	first := redact(t, "bob@example.com alice@example.com", WithPseudonyms())
+	second := redact(t, "alice@example.com", WithPseudonyms())
+	if first != "[EMAIL_1] [EMAIL_2]" || second != "[EMAIL_1]" {
+		t.Errorf("got %q and %q", first, second)
+	}
+}
+
+func TestMatchesSplitAcrossBlocks(t *testing.T) {
+	src := &mockStringSource{blocks: []string{"Card 4111 11", "11 1111 1111 and mail al", "ice@example.com\nend"}}
+	got := readAll(t, NewProcessor(src))
+	if want := "Card [CARD] and mail [EMAIL]\nend"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLongLineWithoutNewlines(t *testing.T) {
+	word := "alice@example.com "
+	input := strings.Repeat(word, 2*maxPending/len(word))
+	var blocks []string
+	for i := 0; i < len(input); i += 1000 {
+		blocks = append(blocks, input[i:min(i+1000, len(input))])
+	}
+	p := NewProcessor(&mockStringSource{blocks: blocks})
+	got := readAll(t, p)
+	if want := strings.Repeat("[EMAIL] ", 2*maxPending/len(word)); got != want {
+		t.Errorf("long line redacted incorrectly")
+	}
+	if n := p.Report()[KindEmail].Count; n != 2*maxPending/len(word) {
+		t.Errorf("Count = %d, want %d", n, 2*maxPending/len(word))
+	}
+}
+
+func TestReport(t *testing.T) {
+	input := "Hi alice@example.com,\ncard 4111111111111111 and bob@example.com\n"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}})
+	readAll(t, p)
+	report := p.Report()
+
+	email := report[KindEmail]
+	if email.Count != 2 || len(email.Spans) != 2 {
+		t.Fatalf("email report = %+v", email)
+	}
+	for _, s := range email.Spans {
+		if got := input[s.Start:s.End]; !strings.HasSuffix(got, "@example.com") {
+			t.Errorf("email span covers %q", got)
+		}
+	}
+	card := report[KindCard]
+	if card.Count != 1 || input[card.Spans[0].Start:card.Spans[0].End] != "4111111111111111" {
+		t.Errorf("card report = %+v", card)
+	}
+	if _, ok := report[KindPhone]; ok {
+		t.Error("unmatched kinds should be absent")
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	input := "Mail alice@example.com or call +44 20 7946 0958 today\n"
+	p := NewProcessor(&mockStringSource{blocks: []string{input}})
+	got := readAll(t, p)
+
+	for _, word := range []string{"Mail", "or call", "today"} {
+		out := int64(strings.Index(got, word))
+		in := p.Offsets().Map(out)
+		if !strings.HasPrefix(input[in:], word) {
+			t.Errorf("%q maps to %d, want %d", word, in, strings.Index(input, word))
+		}
+	}
+	span := p.Offsets().MapSpan(textproc.Span{Start: int64(strings.Index(got, "[EMAIL]")), End: int64(strings.Index(got, "[EMAIL]") + len("[EMAIL]"))})
+	if got := input[span.Start:span.End]; got != "alice@example.com" {
+		t.Errorf("[EMAIL] maps to %q", got)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	srcErr := errors.New("read failed")
+	p := NewProcessor(&mockStringSource{blocks: []string{"line\n"}, err: srcErr})
+	if _, err := p.Next(); err != nil {
+		t.Fatalf("first Next: %v", err)
+	}
+	if _, err := p.Next(); !errors.Is(err, srcErr) {
+		t.Errorf("err = %v, want %v", err, srcErr)
+	}
+}
@@ -0,0 +1,85 @@
+package textproc
+
+import (
+	"sort"
+)
+
+// Span is a range of byte offsets in a stream, from the start of the
+// stream. End is exclusive. The zero Span means the offsets are unknown.
+type Span struct {
+	Start int64
+	End   int64
+}
+
+// IsZero reports whether the span is unknown.
+func (s Span) IsZero() bool {
+	return s.Start == 0 && s.End == 0
+}
+
+// OffsetMap maps byte offsets in a processor's output back to offsets in
+// its input. It is a list of breakpoints: output offset Out corresponds to
+// input offset In, and offsets between breakpoints advance together. Text
+// the processor inserted maps to the input just before it.
+type OffsetMap struct {
+	out []int64
+	in  []int64
+}
+
+// Add records that output offset out corresponds to input offset in.
+// Breakpoints must be added in output order; any at or after out are
+// replaced, so a processor can correct a mapping after trimming its output.
+func (m *OffsetMap) Add(out, in int64) {
+	n := len(m.out)
+	for n > 0 && m.out[n-1] >= out {
+		n--
+	}
+	m.out, m.in = m.out[:n], m.in[:n]
+	if n > 0 && out-m.out[n-1] == in-m.in[n-1] {
+		// Already implied by the previous breakpoint
+		return
+	}
+	m.out = append(m.out, out)
+	m.in = append(m.in, in)
+}
+
+// Map returns the input offset of the output byte at out.
+func (m *OffsetMap) Map(out int64) int64 {
+	i := sort.Search(len(m.out), func(i int) bool { return m.out[i] > out }) - 1
+	if i < 0 {
+		return out
+	}
+	return m.in[i] + out - m.out[i]
+}
+
+// MapSpan returns the input span that produced the output span s. A zero
+// span maps to the zero span.
+func (m *OffsetMap) MapSpan(s Span) Span {
+	if s.IsZero() || s.End <= s.Start {
+		return s
+	}
+	return Span{Start: m.Map(s.Start), End: m.Map(s.End-1) + 1}
+}
+
+// OffsetMapper is implemented by processors that can map offsets in their
+// output back to their input. Offsets returns nil when the mapping is not
+// available, for example when a transformation does not preserve positions.
+// The map covers all output so far and is updated by each Next.
+type OffsetMapper interface {
+	Offsets() *OffsetMap
+}
+
+// SpanChunkProcessor is a ChunkProcessor that can also report the span of
+// its input that each chunk came from. The span is zero when unknown.
+// Implemented by: chunker, splitter
+type SpanChunkProcessor interface {
+	ChunkProcessor
+	NextWithSpan() (Chunk, Span, error)
+}
+
+// SpanChunkCombiner is a ChunkCombiner that can also report the span of
+// each chunk in a slice, as given by its SpanChunkProcessor source.
+// Implemented by: combiner
+type SpanChunkCombiner interface {
+	ChunkCombiner
+	NextWithSpans() (ChunkSlice, []Span, error)
+}
@@ -0,0 +1,54 @@
+package textproc
+
+import (
+	"testing"
+)
+
+func TestOffsetMapIdentity(t *testing.T) {
+	var m OffsetMap
+	if got := m.Map(42); got != 42 {
+		t.Errorf("expected empty map to be identity, got %d", got)
+	}
+}
+
+func TestOffsetMapDeletion(t *testing.T) {
+	// Input "ab<x>cd" becomes output "abcd"
+	var m OffsetMap
+	m.Add(0, 0)
+	m.Add(2, 5)
+	tests := []struct{ out, in int64 }{{0, 0}, {1, 1}, {2, 5}, {3, 6}}
+	for _, tc := range tests {
+		if got := m.Map(tc.out); got != tc.in {
+			t.Errorf("Map(%d): expected %d, got %d", tc.out, tc.in, got)
+		}
+	}
+	if got := m.MapSpan(Span{1, 4}); got != (Span{1, 7}) {
+		t.Errorf("expected span {1 7}, got %+v", got)
+	}
+}
+
+func TestOffsetMapAddReplacesLater(t *testing.T) {
+	var m OffsetMap
+	m.Add(0, 0)
+	m.Add(10, 20)
+	m.Add(5, 7)
+	if got := m.Map(10); got != 12 {
+		t.Errorf("expected later breakpoint replaced, got %d", got)
+	}
+}
+
+func TestOffsetMapSkipsImpliedBreakpoints(t *testing.T) {
+	var m OffsetMap
+	for i := int64(0); i < 100; i++ {
+		m.Add(i, i+3)
+	}
+	if len(m.out) != 1 {
+		t.Errorf("expected 1 breakpoint, got %d", len(m.out))
+	}
+}
+
+func TestSpanIsZero(t *testing.T) {
+	if !(Span{}).IsZero() || (Span{0, 1}).IsZero() {
+		t.Error("unexpected IsZero result")
+	}
+}
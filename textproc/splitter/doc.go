@@ -5,8 +5,13 @@
 // strategies in order of preference:
 //
 // Supported split types:
-//   - Sentence boundaries: [.!?。] followed by whitespace
-//   - Word boundaries: whitespace characters (space, tab, newline)
+//   - Sentence boundaries: Unicode Standard Annex #29 sentence breaks, so
+//     full stops in decimals, initialisms and lower-case continuations do not
+//     split, and terminators such as 。, ！ and । need no following space.
+//     A full stop after an abbreviation in DefaultAbbreviations (or the list
+//     given to WithAbbreviations) does not end a sentence either.
+//   - Word boundaries: whitespace characters (space, tab, newline), or failing
+//     that any Annex #29 word break, such as between ideographs
 //   - Character boundaries: UTF-8 safe byte positions
 //
 // Chunks are trimmed of leading and trailing whitespace.
@@ -16,6 +21,8 @@
 //   - If a single content unit (no sentence/word boundaries) is within 2x the byte limit,
 //     it is emitted as-is to avoid creating tiny fragments
 //   - Otherwise, character splitting is forced at UTF-8 safe boundaries
+//   - WithStrictLimit never emits a chunk over the limit, and WithByteLimit
+//     does so with the limit in UTF-8 bytes, for storage budgets
 //
 // The Processor implements textproc.ChunkProcessor, making it suitable for
 // pull-based lazy evaluation chains.
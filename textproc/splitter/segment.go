@@ -0,0 +1,479 @@
+package splitter
+
+import (
+	"iter"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file implements the sentence and word boundary rules of Unicode
+// Standard Annex #29 (https://www.unicode.org/reports/tr29/). Character
+// properties are derived from the unicode package's tables, which is close
+// to, but not exactly, the annex's property data.
+
+// unit is a character together with any Extend and Format characters that
+// follow it, which the annex's rules ignore (SB5, WB4).
+type unit[C comparable] struct {
+	class C
+	start int
+	end   int
+	zwj   bool // Ends with a zero width joiner
+}
+
+// units decodes s into units lazily, so that a caller that stops early
+// only classifies the text it looked at.
+type units[C comparable] struct {
+	s        string
+	pos      int
+	decoded  []unit[C]
+	classify func(rune) C
+	ignored  func(C) bool // Extend and Format classes
+	absorbs  func(C) bool // Classes that ignored characters may follow
+}
+
+// at returns the i'th unit, or false past the end of s.
+func (u *units[C]) at(i int) (unit[C], bool) {
+	for len(u.decoded) <= i {
+		if u.pos >= len(u.s) {
+			return unit[C]{}, false
+		}
+		r, size := utf8.DecodeRuneInString(u.s[u.pos:])
+		next := unit[C]{class: u.classify(r), start: u.pos, end: u.pos + size, zwj: r == zwj}
+		if u.absorbs(next.class) {
+			for next.end < len(u.s) {
+				r, size := utf8.DecodeRuneInString(u.s[next.end:])
+				if !u.ignored(u.classify(r)) {
+					break
+				}
+				next.end += size
+				next.zwj = r == zwj
+			}
+		}
+		u.pos = next.end
+		u.decoded = append(u.decoded, next)
+	}
+	return u.decoded[i], true
+}
+
+// class returns the class of the i'th unit, or zero outside s.
+func (u *units[C]) class(i int) C {
+	if i < 0 {
+		var zero C
+		return zero
+	}
+	next, _ := u.at(i)
+	return next.class
+}
+
+const zwj = '\u200D'
+
+// isExtend reports whether r is a Grapheme_Extend or spacing mark.
+func isExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Other_Grapheme_Extend) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) // Emoji modifiers
+}
+
+// isFormat reports whether r is a format character other than the zero
+// width space and joiners.
+func isFormat(r rune) bool {
+	return unicode.Is(unicode.Cf, r) && r != '\u200B' && r != '\u200C' && r != zwj
+}
+
+// isAlphabetic reports whether r has the Alphabetic property.
+func isAlphabetic(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_Alphabetic)
+}
+
+// sentenceClass is a Sentence_Break property value.
+type sentenceClass uint8
+
+const (
+	sbOther sentenceClass = iota
+	sbCR
+	sbLF
+	sbSep
+	sbExtend
+	sbFormat
+	sbSp
+	sbLower
+	sbUpper
+	sbOLetter
+	sbNumeric
+	sbATerm
+	sbSTerm
+	sbSContinue
+	sbClose
+)
+
+// classifySentence returns the Sentence_Break property of r.
+func classifySentence(r rune) sentenceClass {
+	switch r {
+	case '\r':
+		return sbCR
+	case '\n':
+		return sbLF
+	case '\u0085', '\u2028', '\u2029':
+		return sbSep
+	case '.', '\u2024', '\uFE52', '\uFF0E':
+		return sbATerm
+	case ',', '-', ':', ';', '\u055D', '\u060C', '\u060D', '\u07F8', '\u1802', '\u1808',
+		'\u2013', '\u2014', '\u3001', '\uFE10', '\uFE11', '\uFE13', '\uFE31', '\uFE32',
+		'\uFE50', '\uFE51', '\uFE55', '\uFE58', '\uFE63', '\uFF0C', '\uFF0D', '\uFF1A',
+		'\uFF1B', '\uFF64':
+		return sbSContinue
+	}
+	switch {
+	case isExtend(r) || r == zwj:
+		return sbExtend
+	case isFormat(r):
+		return sbFormat
+	case unicode.Is(unicode.White_Space, r):
+		return sbSp
+	case unicode.Is(unicode.Sentence_Terminal, r):
+		return sbSTerm
+	case unicode.IsLower(r) || unicode.Is(unicode.Other_Lowercase, r):
+		return sbLower
+	case unicode.IsUpper(r) || unicode.IsTitle(r) || unicode.Is(unicode.Other_Uppercase, r):
+		return sbUpper
+	case isAlphabetic(r):
+		return sbOLetter
+	case unicode.Is(unicode.Nd, r):
+		return sbNumeric
+	case unicode.In(r, unicode.Ps, unicode.Pe, unicode.Pi, unicode.Pf, unicode.Quotation_Mark):
+		return sbClose
+	}
+	return sbOther
+}
+
+// isParaSep reports whether c ends a paragraph.
+func isParaSep(c sentenceClass) bool {
+	return c == sbSep || c == sbCR || c == sbLF
+}
+
+// isSATerm reports whether c ends a sentence.
+func isSATerm(c sentenceClass) bool {
+	return c == sbATerm || c == sbSTerm
+}
+
+// DefaultAbbreviations are the words that a full stop does not end a
+// sentence after, compared case-insensitively without their final stop.
+var DefaultAbbreviations = []string{
+	"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "mt", "rev", "hon",
+	"gen", "col", "capt", "lt", "sgt", "vs", "e.g", "i.e", "cf", "ca",
+	"approx", "dept", "fig", "no", "vol", "pp", "al", "z.b", "d.h", "u.a",
+	"p.ex", "s.v.p",
+}
+
+// maxAbbreviation is the longest abbreviation looked for, in bytes.
+const maxAbbreviation = 32
+
+// sentenceBreaks returns the byte offsets inside s at which a new sentence
+// starts, in order. A full stop after one of abbreviations does not end a
+// sentence.
+func sentenceBreaks(s string, abbreviations map[string]bool) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		u := &units[sentenceClass]{
+			s:        s,
+			classify: classifySentence,
+			ignored:  func(c sentenceClass) bool { return c == sbExtend || c == sbFormat },
+			absorbs:  func(c sentenceClass) bool { return !isParaSep(c) },
+		}
+		for i := 0; ; i++ {
+			cur, ok := u.at(i)
+			if !ok {
+				return
+			}
+			end := i
+			switch {
+			case cur.class == sbCR && u.class(i+1) == sbLF:
+				end = i + 1 // SB3
+			case isParaSep(cur.class):
+				// SB4
+			case isSATerm(cur.class):
+				var broke bool
+				end, broke = sentenceEnd(u, i, abbreviations)
+				if !broke {
+					continue
+				}
+			default:
+				continue
+			}
+			next, ok := u.at(end + 1)
+			if !ok {
+				return
+			}
+			if !yield(next.start) {
+				return
+			}
+			i = end
+		}
+	}
+}
+
+// sentenceEnd applies SB6 to SB11 to the terminator at unit i. It returns
+// the last unit of the sentence, and whether the sentence ends there.
+func sentenceEnd(u *units[sentenceClass], i int, abbreviations map[string]bool) (int, bool) {
+	aterm := u.class(i) == sbATerm
+	next := u.class(i + 1)
+
+	// SB6: ATerm × Numeric
+	if aterm && next == sbNumeric {
+		return i, false
+	}
+	// SB7: (Upper | Lower) ATerm × Upper
+	if aterm && next == sbUpper {
+		if prev := u.class(i - 1); i > 0 && (prev == sbUpper || prev == sbLower) {
+			return i, false
+		}
+	}
+
+	// SB9, SB10: SATerm Close* Sp* stays with the sentence
+	j := i + 1
+	for u.class(j) == sbClose {
+		j++
+	}
+	for u.class(j) == sbSp {
+		j++
+	}
+	if _, ok := u.at(j); !ok {
+		return j - 1, false // End of text
+	}
+
+	// SB8a: SATerm Close* Sp* × (SContinue | SATerm)
+	if c := u.class(j); c == sbSContinue || isSATerm(c) {
+		return i, false
+	}
+	if aterm {
+		// SB8: ATerm Close* Sp* × (¬(OLetter | Upper | Lower | ParaSep | SATerm))* Lower
+		for k := j; ; k++ {
+			c := u.class(k)
+			if c == sbLower {
+				return i, false
+			}
+			if _, ok := u.at(k); !ok || c == sbOLetter || c == sbUpper || isParaSep(c) || isSATerm(c) {
+				break
+			}
+		}
+		if isAbbreviation(u, i, abbreviations) {
+			return i, false
+		}
+	}
+
+	// SB11: the sentence ends after one paragraph separator, if any
+	switch c := u.class(j); {
+	case c == sbCR && u.class(j+1) == sbLF:
+		return j + 1, true
+	case isParaSep(c):
+		return j, true
+	}
+	return j - 1, true
+}
+
+// isAbbreviation reports whether the full stop at unit i follows one of
+// abbreviations.
+func isAbbreviation(u *units[sentenceClass], i int, abbreviations map[string]bool) bool {
+	if len(abbreviations) == 0 {
+		return false
+	}
+	stop, _ := u.at(i)
+	start := stop.start
+	for start > 0 && stop.start-start <= maxAbbreviation {
+		r, size := utf8.DecodeLastRuneInString(u.s[:start])
+		if r != '.' && !unicode.IsLetter(r) {
+			break
+		}
+		start -= size
+	}
+	return abbreviations[strings.ToLower(u.s[start:stop.start])]
+}
+
+// wordClass is a Word_Break property value.
+type wordClass uint8
+
+const (
+	wbOther wordClass = iota
+	wbCR
+	wbLF
+	wbNewline
+	wbExtend
+	wbZWJ
+	wbFormat
+	wbRegionalIndicator
+	wbKatakana
+	wbHebrewLetter
+	wbALetter
+	wbSingleQuote
+	wbDoubleQuote
+	wbMidNumLet
+	wbMidLetter
+	wbMidNum
+	wbNumeric
+	wbExtendNumLet
+	wbWSegSpace
+)
+
+// complexContext are scripts written without spaces between words, which
+// need a dictionary to segment. Their letters are not ALetter.
+var complexContext = []*unicode.RangeTable{
+	unicode.Thai, unicode.Lao, unicode.Myanmar, unicode.Khmer,
+	unicode.Tai_Le, unicode.New_Tai_Lue, unicode.Tai_Tham, unicode.Tai_Viet,
+}
+
+// classifyWord returns the Word_Break property of r.
+func classifyWord(r rune) wordClass {
+	switch r {
+	case '\r':
+		return wbCR
+	case '\n':
+		return wbLF
+	case '\u000B', '\u000C', '\u0085', '\u2028', '\u2029':
+		return wbNewline
+	case zwj:
+		return wbZWJ
+	case '\'':
+		return wbSingleQuote
+	case '"':
+		return wbDoubleQuote
+	case '.', '\u2018', '\u2019', '\u2024', '\uFE52', '\uFF07', '\uFF0E':
+		return wbMidNumLet
+	case ':', '\u00B7', '\u0387', '\u055F', '\u05F4', '\u2027', '\uFE13', '\uFE55', '\uFF1A':
+		return wbMidLetter
+	case ',', ';', '\u037E', '\u0589', '\u060C', '\u060D', '\u066C', '\u07F8', '\u2044',
+		'\uFE10', '\uFE14', '\uFE50', '\uFE54', '\uFF0C', '\uFF1B':
+		return wbMidNum
+	case '\u202F':
+		return wbExtendNumLet
+	case '\u3031', '\u3032', '\u3033', '\u3034', '\u3035', '\u309B', '\u309C', '\u30A0',
+		'\u30FC', '\uFF70':
+		return wbKatakana
+	case '\u00A0', '\u2007':
+		return wbOther // No-break spaces are not WSegSpace
+	}
+	switch {
+	case isExtend(r):
+		return wbExtend
+	case isFormat(r):
+		return wbFormat
+	case unicode.Is(unicode.Regional_Indicator, r):
+		return wbRegionalIndicator
+	case unicode.Is(unicode.Katakana, r):
+		return wbKatakana
+	case unicode.Is(unicode.Hebrew, r) && unicode.IsLetter(r):
+		return wbHebrewLetter
+	case isAlphabetic(r) && !unicode.In(r, unicode.Ideographic, unicode.Hiragana) &&
+		!unicode.In(r, complexContext...):
+		return wbALetter
+	case unicode.Is(unicode.Nd, r):
+		return wbNumeric
+	case unicode.Is(unicode.Pc, r):
+		return wbExtendNumLet
+	case unicode.Is(unicode.Zs, r):
+		return wbWSegSpace
+	}
+	return wbOther
+}
+
+// isAHLetter reports whether c is a letter of an alphabetic script.
+func isAHLetter(c wordClass) bool {
+	return c == wbALetter || c == wbHebrewLetter
+}
+
+// isMidLetterish reports whether c may join two letters (WB6, WB7).
+func isMidLetterish(c wordClass) bool {
+	return c == wbMidLetter || c == wbMidNumLet || c == wbSingleQuote
+}
+
+// isMidNumish reports whether c may join two numbers (WB11, WB12).
+func isMidNumish(c wordClass) bool {
+	return c == wbMidNum || c == wbMidNumLet || c == wbSingleQuote
+}
+
+// isNewline reports whether c is a line break.
+func isNewline(c wordClass) bool {
+	return c == wbCR || c == wbLF || c == wbNewline
+}
+
+// wordBreaks returns the byte offsets inside s at which a word boundary
+// falls, in order. Spaces and punctuation form words of their own, and
+// ideographs, kana and scripts written without spaces break between
+// characters.
+func wordBreaks(s string) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		u := &units[wordClass]{
+			s:        s,
+			classify: classifyWord,
+			ignored:  func(c wordClass) bool { return c == wbExtend || c == wbFormat || c == wbZWJ },
+			absorbs:  func(c wordClass) bool { return !isNewline(c) },
+		}
+		regional := 0 // Regional indicators in a row, for WB15 and WB16
+		for i := 0; ; i++ {
+			cur, ok := u.at(i)
+			if !ok {
+				return
+			}
+			next, ok := u.at(i + 1)
+			if !ok {
+				return
+			}
+			if cur.class == wbRegionalIndicator {
+				regional++
+			} else {
+				regional = 0
+			}
+			if !wordJoined(u, i, cur, next, regional) && !yield(next.start) {
+				return
+			}
+		}
+	}
+}
+
+// wordJoined reports whether no word boundary falls between units i and
+// i+1, by WB3 to WB16.
+func wordJoined(u *units[wordClass], i int, cur, next unit[wordClass], regional int) bool {
+	a, b := cur.class, next.class
+	switch {
+	case a == wbCR && b == wbLF: // WB3
+		return true
+	case isNewline(a) || isNewline(b): // WB3a, WB3b
+		return false
+	case cur.zwj && isPictographic(u.s[next.start:]): // WB3c
+		return true
+	case a == wbWSegSpace && b == wbWSegSpace: // WB3d
+		return true
+	case isAHLetter(a) && isAHLetter(b): // WB5
+		return true
+	case isAHLetter(a) && isMidLetterish(b) && isAHLetter(u.class(i+2)): // WB6
+		return true
+	case isMidLetterish(a) && isAHLetter(b) && i > 0 && isAHLetter(u.class(i-1)): // WB7
+		return true
+	case a == wbHebrewLetter && b == wbSingleQuote: // WB7a
+		return true
+	case a == wbHebrewLetter && b == wbDoubleQuote && u.class(i+2) == wbHebrewLetter: // WB7b
+		return true
+	case a == wbDoubleQuote && b == wbHebrewLetter && i > 0 && u.class(i-1) == wbHebrewLetter: // WB7c
+		return true
+	case (a == wbNumeric || isAHLetter(a)) && (b == wbNumeric || isAHLetter(b)): // WB8, WB9, WB10
+		return true
+	case isMidNumish(a) && b == wbNumeric && i > 0 && u.class(i-1) == wbNumeric: // WB11
+		return true
+	case a == wbNumeric && isMidNumish(b) && u.class(i+2) == wbNumeric: // WB12
+		return true
+	case a == wbKatakana && b == wbKatakana: // WB13
+		return true
+	case b == wbExtendNumLet && (isAHLetter(a) || a == wbNumeric || a == wbKatakana || a == wbExtendNumLet): // WB13a
+		return true
+	case a == wbExtendNumLet && (isAHLetter(b) || b == wbNumeric || b == wbKatakana): // WB13b
+		return true
+	case a == wbRegionalIndicator && b == wbRegionalIndicator: // WB15, WB16
+		return regional%2 == 1
+	}
+	return false // WB999
+}
+
+// isPictographic approximates Extended_Pictographic for the character at
+// the start of s, by its general category.
+func isPictographic(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.Is(unicode.So, r)
+}
@@ -0,0 +1,109 @@
+package splitter
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// sentences splits s at its sentence boundaries.
+func sentences(s string, abbreviations map[string]bool) []string {
+	var out []string
+	start := 0
+	for end := range sentenceBreaks(s, abbreviations) {
+		out = append(out, s[start:end])
+		start = end
+	}
+	return append(out, s[start:])
+}
+
+func TestSentenceBreaks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"simple", "Hello world. This is a test.", []string{"Hello world. ", "This is a test."}},
+		{"question and exclamation", "Really? Yes! Good.", []string{"Really? ", "Yes! ", "Good."}},
+		{"lower case after stop", "See e.g. the docs. Next one.", []string{"See e.g. the docs. ", "Next one."}},
+		{"abbreviation before capital", "Ask Dr. Smith now. Then go.", []string{"Ask Dr. Smith now. ", "Then go."}},
+		{"decimal", "Pi is 3.14 exactly. Yes.", []string{"Pi is 3.14 exactly. ", "Yes."}},
+		{"initialism", "Born in the U.S.A. today.", []string{"Born in the U.S.A. today."}},
+		{"closing quote", `He said "Stop." Then left.`, []string{`He said "Stop." `, "Then left."}},
+		{"continuation", "Oh dear., he said.", []string{"Oh dear., he said."}},
+		{"ideographic", "日本語です。次の文です。", []string{"日本語です。", "次の文です。"}},
+		{"full-width", "你好！再见？好的。", []string{"你好！", "再见？", "好的。"}},
+		{"devanagari", "यह पहला वाक्य है। यह दूसरा है।", []string{"यह पहला वाक्य है। ", "यह दूसरा है।"}},
+		{"paragraph separator", "No stop here\nNew line", []string{"No stop here\n", "New line"}},
+		{"crlf after stop", "One.\r\nTwo.", []string{"One.\r\n", "Two."}},
+		{"trailing spaces", "End.  ", []string{"End.  "}},
+		{"empty", "", []string{""}},
+	}
+
+	abbreviations := abbreviationSet(DefaultAbbreviations)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sentences(tt.input, abbreviations)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sentences(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentenceBreaksWithoutAbbreviations(t *testing.T) {
+	got := sentences("Ask Dr. Smith now.", nil)
+	want := []string{"Ask Dr. ", "Smith now."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestWordBreaks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []int
+	}{
+		{"punctuation", "Hello, world", []int{5, 6, 7}},
+		{"apostrophe", "can't stop", []int{5, 6}},
+		{"numbers", "3.14 and 1,000", []int{4, 5, 8, 9}},
+		{"ideographs", "日本語", []int{3, 6}},
+		{"katakana", "カタカナ漢字", []int{12, 15}},
+		{"combining marks", "e\u0301te\u0301", nil},
+		{"connector", "snake_case", nil},
+		{"regional indicators", "🇬🇧🇫🇷", []int{8}},
+		{"crlf", "a\r\nb", []int{1, 3}},
+		{"spaces", "a  b", []int{1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.Collect(wordBreaks(tt.input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wordBreaks(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzBreaks(f *testing.F) {
+	f.Add("Hello world. Dr. Who? 3.14 is pi.\r\n")
+	f.Add("日本語です。次の文です。")
+	f.Add("🇬🇧🇫🇷 can't é ‍🙂")
+	f.Fuzz(func(t *testing.T, input string) {
+		abbreviations := abbreviationSet(DefaultAbbreviations)
+		for name, breaks := range map[string][]int{
+			"sentence": slices.Collect(sentenceBreaks(input, abbreviations)),
+			"word":     slices.Collect(wordBreaks(input)),
+		} {
+			last := 0
+			for _, b := range breaks {
+				if b <= last || b >= len(input) {
+					t.Fatalf("%s breaks of %q out of order or range: %v", name, input, breaks)
+				}
+				last = b
+			}
+		}
+	})
+}
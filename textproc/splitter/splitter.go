@@ -1,17 +1,30 @@
 package splitter
 
 import (
-	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
 )
 
 // Processor splits chunks that exceed the character limit into smaller chunks.
 // It tries sentence boundaries first, then word boundaries, then character boundaries.
+// Sentence and word boundaries follow Unicode Standard Annex #29.
+// The limit is measured by a textproc.Counter, runes by default.
 type Processor struct {
 	src       textproc.ChunkProcessor
 	charLimit int
+	counter   textproc.Counter
+	strict    bool // Never exceed charLimit, even by up to 2x
 	remaining textproc.Chunk
+
+	// Lower-cased words a full stop does not end a sentence after
+	abbreviations map[string]bool
+
+	// Offsets of remaining in the input, when the source reports spans
+	remainingStart int64
+	spans          bool
 }
 
 // Option configures a Processor.
@@ -24,12 +37,61 @@ func WithCharLimit(n int) Option {
 	}
 }
 
+// WithCounter sets how chunk sizes are measured against the limit, so the
+// limit can be given in bytes, words or model tokens. Default is runes.
+func WithCounter(c textproc.Counter) Option {
+	return func(p *Processor) {
+		p.counter = c
+	}
+}
+
+// WithStrictLimit never emits a chunk over the limit. By default a
+// remainder of up to twice the limit is emitted whole, to avoid tiny
+// fragments.
+func WithStrictLimit() Option {
+	return func(p *Processor) {
+		p.strict = true
+	}
+}
+
+// WithByteLimit sets a strict limit of n UTF-8 bytes, replacing maxChars
+// and the counter. Splits still fall on sentence, word or rune boundaries,
+// so n is raised to utf8.UTFMax if it is smaller, to always fit a rune.
+func WithByteLimit(n int) Option {
+	return func(p *Processor) {
+		p.charLimit = max(n, utf8.UTFMax)
+		p.counter = textproc.ByteCounter{}
+		p.strict = true
+	}
+}
+
+// WithAbbreviations sets the words that a full stop does not end a
+// sentence after, without their final stop, replacing
+// DefaultAbbreviations. They are compared case-insensitively.
+func WithAbbreviations(words ...string) Option {
+	return func(p *Processor) {
+		p.abbreviations = abbreviationSet(words)
+	}
+}
+
+// abbreviationSet returns the lower-cased words as a set.
+func abbreviationSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[strings.ToLower(strings.TrimSuffix(w, "."))] = true
+	}
+	return set
+}
+
 // NewProcessor creates a new Processor with the given ChunkProcessor source.
 // Chunks larger than maxChars will be split into smaller chunks.
 func NewProcessor(src textproc.ChunkProcessor, maxChars int, opts ...Option) *Processor {
 	p := &Processor{
 		src:       src,
 		charLimit: maxChars,
+		counter:   textproc.RuneCounter{},
+
+		abbreviations: abbreviationSet(DefaultAbbreviations),
 	}
 	for _, opt := range opts {
 		opt(p)
@@ -59,66 +121,112 @@ func trimTrailing(chunk textproc.Chunk) textproc.Chunk {
 
 // Next returns the next Chunk, splitting large chunks if necessary.
 func (p *Processor) Next() (textproc.Chunk, error) {
+	chunk, _, err := p.NextWithSpan()
+	return chunk, err
+}
+
+// NextWithSpan returns the next Chunk and its byte offsets in the text the
+// source's chunks came from. The span is zero if the source does not
+// report spans.
+func (p *Processor) NextWithSpan() (textproc.Chunk, textproc.Span, error) {
 	// Return any remaining content first
 	if len(p.remaining) > 0 {
 		result := p.remaining
+		start := p.remainingStart
 		p.remaining = ""
-		result = trimLeading(result)
+		trimmed := trimLeading(result)
+		start += int64(len(result) - len(trimmed))
+		result = trimmed
 		if result == "" {
-			return p.Next() // All whitespace, skip
+			return p.NextWithSpan() // All whitespace, skip
 		}
-		// Use character count for limit check
-		if len([]rune(result)) <= p.charLimit {
-			return result, nil
+		// Use the counter for limit check
+		size := p.count(result)
+		if size <= p.charLimit {
+			return result, p.span(start, result), nil
 		}
 		// If remaining is not much larger than limit, return as-is to avoid over-splitting
 		// Otherwise, split it
-		if len([]rune(result)) <= p.charLimit*2 {
-			return result, nil
+		if !p.strict && size <= p.charLimit*2 {
+			return result, p.span(start, result), nil
 		}
-		return p.splitChunk(result)
+		return p.splitChunk(result, start)
 	}
 
 	// Get next chunk from source
-	chunk, err := p.src.Next()
+	chunk, span, err := p.nextSource()
 	if err != nil {
-		return "", err
+		return "", textproc.Span{}, err
 	}
 
 	// Skip empty chunks
 	if chunk == "" {
-		return p.Next()
+		return p.NextWithSpan()
 	}
 
 	// Trim leading whitespace
-	chunk = trimLeading(chunk)
+	trimmed := trimLeading(chunk)
+	start := span.Start + int64(len(chunk)-len(trimmed))
+	chunk = trimmed
 	if chunk == "" {
-		return p.Next() // All whitespace, skip
+		return p.NextWithSpan() // All whitespace, skip
 	}
 
 	// If chunk fits, return it directly
-	if len([]rune(chunk)) <= p.charLimit {
-		return chunk, nil
+	if p.count(chunk) <= p.charLimit {
+		return chunk, p.span(start, chunk), nil
 	}
 
 	// Chunk is too large, split it
-	return p.splitChunk(chunk)
+	return p.splitChunk(chunk, start)
 }
 
-// splitChunk splits a chunk that exceeds the char limit.
-func (p *Processor) splitChunk(chunk textproc.Chunk) (textproc.Chunk, error) {
+// count returns the size of chunk as measured by the counter.
+func (p *Processor) count(chunk textproc.Chunk) int {
+	return p.counter.Count(string(chunk))
+}
+
+// nextSource reads the next chunk from the source, with its span if the
+// source reports one.
+func (p *Processor) nextSource() (textproc.Chunk, textproc.Span, error) {
+	if src, ok := p.src.(textproc.SpanChunkProcessor); ok {
+		chunk, span, err := src.NextWithSpan()
+		p.spans = !span.IsZero()
+		return chunk, span, err
+	}
+	p.spans = false
+	chunk, err := p.src.Next()
+	return chunk, textproc.Span{}, err
+}
+
+// span returns the span of chunk starting at start, or the zero span if
+// the source does not report spans.
+func (p *Processor) span(start int64, chunk textproc.Chunk) textproc.Span {
+	if !p.spans {
+		return textproc.Span{}
+	}
+	return textproc.Span{Start: start, End: start + int64(len(chunk))}
+}
+
+// splitChunk splits a chunk that exceeds the char limit. start is the
+// chunk's offset in the input.
+func (p *Processor) splitChunk(chunk textproc.Chunk, start int64) (textproc.Chunk, textproc.Span, error) {
 	// Try sentence boundary first
 	splitIdx := p.findSentenceBoundary(chunk)
 	if splitIdx > 0 {
 		p.remaining = chunk[splitIdx:]
-		return trimTrailing(chunk[:splitIdx]), nil
+		p.remainingStart = start + int64(splitIdx)
+		first := trimTrailing(chunk[:splitIdx])
+		return first, p.span(start, first), nil
 	}
 
 	// Try word boundary
 	splitIdx = p.findWordBoundary(chunk)
 	if splitIdx > 0 {
 		p.remaining = chunk[splitIdx:]
-		return trimTrailing(chunk[:splitIdx]), nil
+		p.remainingStart = start + int64(splitIdx)
+		first := trimTrailing(chunk[:splitIdx])
+		return first, p.span(start, first), nil
 	}
 
 	// No sentence/word boundaries found
@@ -126,38 +234,28 @@ func (p *Processor) splitChunk(chunk textproc.Chunk) (textproc.Chunk, error) {
 	splitIdx = p.findCharacterBoundary(chunk)
 	if splitIdx > 0 {
 		p.remaining = chunk[splitIdx:]
-		return chunk[:splitIdx], nil
+		p.remainingStart = start + int64(splitIdx)
+		return chunk[:splitIdx], p.span(start, chunk[:splitIdx]), nil
 	}
 
 	// Worst case: return as-is
-	return chunk, nil
+	return chunk, p.span(start, chunk), nil
 }
 
 // findSentenceBoundary finds the best sentence boundary within charLimit.
 // Returns the index where remaining content starts (after the delimiter).
-// The delimiter ([.!?。] and following whitespace) is NOT included in the first piece.
+// The delimiter's trailing whitespace is NOT included in the first piece.
 func (p *Processor) findSentenceBoundary(chunk textproc.Chunk) int {
-	// Sentence pattern: [.!?。] followed by whitespace
-	sentencePattern := regexp.MustCompile(`[.!?。]\s+`)
-
-	// Find all matches in the string
-	matches := sentencePattern.FindAllStringIndex(string(chunk), -1)
-	if len(matches) == 0 {
-		return 0
-	}
-
-	// Find the match that gives the first reasonable chunk within charLimit
-	for _, match := range matches {
-		// match[0] is position of [.!?], match[1] is end of whitespace
-		// First piece should be up to match[0]+1 (includes [.!?], excludes whitespace)
-		// remaining starts at match[1] (after whitespace)
-		endOfSentence := match[0] + 1 // Include the [.!?] in first piece
-		// Check character count up to this point
-		if len([]rune(chunk[:endOfSentence])) <= p.charLimit && len([]rune(chunk[:endOfSentence])) >= p.charLimit/4 {
-			return endOfSentence // Return position after [.!?], before whitespace
+	// Find the boundary that gives the first reasonable chunk within charLimit
+	for end := range sentenceBreaks(string(chunk), p.abbreviations) {
+		size := p.count(trimTrailing(chunk[:end]))
+		if size > p.charLimit {
+			break
+		}
+		if size >= p.charLimit/4 {
+			return end
 		}
 	}
-
 	return 0
 }
 
@@ -165,32 +263,51 @@ func (p *Processor) findSentenceBoundary(chunk textproc.Chunk) int {
 // Returns the index where remaining content starts (after the space).
 // The space is NOT included in the first piece.
 func (p *Processor) findWordBoundary(chunk textproc.Chunk) int {
-	runes := []rune(chunk)
-	searchEnd := p.charLimit
-	if searchEnd > len(runes) {
-		searchEnd = len(runes)
-	}
-
-	// Find the last space within charLimit
-	for i := searchEnd - 1; i >= 0; i-- {
-		if runes[i] == ' ' || runes[i] == '\t' || runes[i] == '\n' {
-			// First piece is chunk up to this rune, remaining is after this rune
-			// Convert rune index back to byte index
-			return len(string(runes[:i+1]))
+	// Candidate split points, just after each whitespace character
+	var ends []int
+	for i, r := range chunk {
+		if r == ' ' || r == '\t' || r == '\n' {
+			ends = append(ends, i+1)
 		}
 	}
-	return 0
+	if end := p.longestPrefix(chunk, ends); end > 0 {
+		return end
+	}
+
+	// No whitespace fits, as in scripts written without spaces: split at
+	// any word boundary, such as between ideographs
+	ends = ends[:0]
+	for end := range wordBreaks(string(chunk)) {
+		ends = append(ends, end)
+	}
+	return p.longestPrefix(chunk, ends)
 }
 
 // findCharacterBoundary finds a safe UTF-8 character boundary at or before charLimit.
 // Returns the index where remaining content starts.
 func (p *Processor) findCharacterBoundary(chunk textproc.Chunk) int {
-	runes := []rune(chunk)
-	pos := p.charLimit
-	if pos > len(runes) {
-		pos = len(runes)
+	ends := make([]int, 0, len(chunk))
+	for i := 0; i < len(chunk); {
+		_, size := utf8.DecodeRuneInString(string(chunk[i:]))
+		i += size
+		ends = append(ends, i)
 	}
+	return p.longestPrefix(chunk, ends)
+}
 
-	// Return the byte index for the rune position
-	return len(string(runes[:pos]))
+// longestPrefix returns the largest of the ascending byte offsets ends
+// whose prefix of chunk fits within charLimit, or 0 if none does. Sizes
+// grow with prefix length (only very nearly, for tokenizers), so a binary
+// search saves counting each candidate.
+func (p *Processor) longestPrefix(chunk textproc.Chunk, ends []int) int {
+	n := sort.Search(len(ends), func(i int) bool {
+		return p.count(chunk[:ends[i]]) > p.charLimit
+	})
+	if n == 0 {
+		return 0
+	}
+	return ends[n-1]
 }
+
+// Ensure Processor implements SpanChunkProcessor
+var _ textproc.SpanChunkProcessor = (*Processor)(nil)
@@ -3,6 +3,8 @@ package splitter
 import (
 	"errors"
 	"io"
+	"reflect"
+	"strings"
 	"testing"
 	"unicode/utf8"
 
@@ -363,3 +365,223 @@ func TestUTF8Validation(t *testing.T) {
 		})
 	}
 }
+
+// mockSpanChunkProcessor reports chunks with spans, as the chunker does.
+type mockSpanChunkProcessor struct {
+	mockChunkProcessor
+	spans []textproc.Span
+}
+
+func (m *mockSpanChunkProcessor) NextWithSpan() (textproc.Chunk, textproc.Span, error) {
+	i := m.index
+	chunk, err := m.Next()
+	if err != nil {
+		return "", textproc.Span{}, err
+	}
+	return chunk, m.spans[i], nil
+}
+
+func TestNextWithSpan(t *testing.T) {
+	input := "xx One two three. Four five six. Seven"
+	src := &mockSpanChunkProcessor{
+		mockChunkProcessor: mockChunkProcessor{chunks: []textproc.Chunk{textproc.Chunk(input[3:])}},
+		spans:              []textproc.Span{{Start: 3, End: int64(len(input))}},
+	}
+	p := NewProcessor(src, 16)
+	for {
+		chunk, span, err := p.NextWithSpan()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if input[span.Start:span.End] != string(chunk) {
+			t.Errorf("span %+v gives %q, expected %q", span, input[span.Start:span.End], chunk)
+		}
+	}
+}
+
+func TestNextWithSpanUnknownSource(t *testing.T) {
+	p := NewProcessor(&mockChunkProcessor{chunks: []textproc.Chunk{"hello"}}, 100)
+	_, span, err := p.NextWithSpan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !span.IsZero() {
+		t.Errorf("expected zero span from a source without spans, got %+v", span)
+	}
+}
+
+// TestWithCounter verifies split points are chosen by the configured counter.
+func TestWithCounter(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{
+		textproc.Chunk("a b c d e f g h"),
+	}}
+	p := NewProcessor(src, 3, WithCounter(textproc.WordCounter{}))
+
+	result, err := p.Next()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(result) != "a b c" {
+		t.Errorf("expected 'a b c', got '%s'", string(result))
+	}
+
+	result, err = p.Next()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(result) != "d e f g h" {
+		t.Errorf("expected 'd e f g h', got '%s'", string(result))
+	}
+}
+
+// TestWithByteCounter verifies character splits stay within a byte limit
+// without breaking runes.
+func TestWithByteCounter(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{
+		textproc.Chunk("日本語日本語"),
+	}}
+	p := NewProcessor(src, 7, WithCounter(textproc.ByteCounter{}))
+
+	result, err := p.Next()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(result) != "日本" {
+		t.Errorf("expected '日本', got '%s'", string(result))
+	}
+
+	result, err = p.Next()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(result) != "語日本語" {
+		t.Errorf("expected '語日本語', got '%s'", string(result))
+	}
+}
+
+// collect reads all chunks from p.
+func collect(t *testing.T, p *Processor) []string {
+	t.Helper()
+	var got []string
+	for {
+		chunk, err := p.Next()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		got = append(got, string(chunk))
+	}
+}
+
+// TestStrictLimit verifies a remainder under twice the limit is still split.
+func TestStrictLimit(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{
+		textproc.Chunk("This is slightly over the limit"),
+	}}
+	p := NewProcessor(src, 15, WithStrictLimit())
+
+	got := collect(t, p)
+	want := []string{"This is", "slightly over", "the limit"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestIdeographicFullStop verifies a sentence ending in 。 is not split
+// inside the multi-byte delimiter.
+func TestIdeographicFullStop(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{
+		textproc.Chunk("日本語です。 次の文です。"),
+	}}
+	p := NewProcessor(src, 8)
+
+	got := collect(t, p)
+	want := []string{"日本語です。", "次の文です。"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestByteLimit verifies CJK text is split to a byte budget on rune boundaries.
+func TestByteLimit(t *testing.T) {
+	input := "这是一个很长的句子没有任何空格"
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{textproc.Chunk(input)}}
+	p := NewProcessor(src, 1000, WithByteLimit(10))
+
+	got := collect(t, p)
+	if len(got) != 5 {
+		t.Errorf("expected 5 chunks of 3 runes, got %q", got)
+	}
+	for _, chunk := range got {
+		if len(chunk) > 10 {
+			t.Errorf("chunk %q exceeds 10 bytes", chunk)
+		}
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk %q is not valid UTF-8", chunk)
+		}
+	}
+	if strings.Join(got, "") != input {
+		t.Errorf("chunks do not rejoin to the input: %q", got)
+	}
+}
+
+// TestByteLimitMinimum verifies a byte limit below one rune is raised to fit one.
+func TestByteLimitMinimum(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{textproc.Chunk("日本")}}
+	p := NewProcessor(src, 1000, WithByteLimit(1))
+
+	got := collect(t, p)
+	want := []string{"日", "本"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestSentenceSplitAbbreviation verifies a full stop after an abbreviation
+// does not end the first piece.
+func TestSentenceSplitAbbreviation(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{
+		textproc.Chunk("Please ask Dr. Smith about it. He knows the answer."),
+	}}
+	p := NewProcessor(src, 40)
+
+	got := collect(t, p)
+	want := []string{"Please ask Dr. Smith about it.", "He knows the answer."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestWithAbbreviations verifies the abbreviation list can be replaced.
+func TestWithAbbreviations(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{
+		textproc.Chunk("Ask the Cpl. Jones about it. He knows the answer."),
+	}}
+	p := NewProcessor(src, 30, WithAbbreviations("Cpl."))
+
+	got := collect(t, p)
+	want := []string{"Ask the Cpl. Jones about it.", "He knows the answer."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestSentenceSplitWithoutSpaces verifies sentences split after
+// punctuation that is not followed by whitespace.
+func TestSentenceSplitWithoutSpaces(t *testing.T) {
+	src := &mockChunkProcessor{chunks: []textproc.Chunk{
+		textproc.Chunk("これは最初の文です。これは二番目の文です。"),
+	}}
+	p := NewProcessor(src, 12)
+
+	got := collect(t, p)
+	want := []string{"これは最初の文です。", "これは二番目の文です。"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
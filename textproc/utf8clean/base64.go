@@ -0,0 +1,171 @@
+package utf8clean
+
+import (
+	"io"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// base64Invalid marks bytes outside the base64 alphabet in base64Values.
+const base64Invalid = 0xFF
+
+// base64Values maps each byte to its 6-bit base64 value, or base64Invalid.
+var base64Values = func() [256]byte {
+	var t [256]byte
+	for i := range t {
+		t[i] = base64Invalid
+	}
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	for i := 0; i < len(alphabet); i++ {
+		t[alphabet[i]] = byte(i)
+	}
+	return t
+}()
+
+// Base64Decoder decodes a base64 stream (RFC 2045 section 6.8).
+//
+// Line breaks and other whitespace are ignored, incomplete 4-character
+// quanta are carried over to the next Next() call, and missing padding at
+// the end of input is tolerated. In strict mode any other byte outside the
+// base64 alphabet is reported as ErrInvalidBase64; in lenient mode it is
+// skipped and counted by Skipped.
+type Base64Decoder struct {
+	src     textproc.BytesProcessor
+	lenient bool
+	quad    [4]byte // Values of the current incomplete quantum
+	n       int     // Number of values in quad
+	padded  bool    // Padding has been seen since the last data character
+	offset  int64   // Offset in the encoded stream of the next byte
+	skipped int
+	eof     bool
+}
+
+var _ textproc.BytesProcessor = (*Base64Decoder)(nil)
+
+// NewBase64Decoder creates a Base64Decoder reading from src.
+// If lenient is true, invalid bytes are skipped instead of returning an error.
+func NewBase64Decoder(src textproc.BytesProcessor, lenient bool) *Base64Decoder {
+	return &Base64Decoder{src: src, lenient: lenient}
+}
+
+// Skipped returns the number of invalid bytes skipped so far in lenient mode.
+func (d *Base64Decoder) Skipped() int {
+	return d.skipped
+}
+
+// Next returns the next block of decoded bytes.
+// Returns io.EOF when all data has been consumed.
+func (d *Base64Decoder) Next() ([]byte, error) {
+	for !d.eof {
+		block, err := d.src.Next()
+		if err == io.EOF {
+			d.eof = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := d.decode(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > 0 {
+			return out, nil
+		}
+	}
+
+	out, err := d.flush()
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+	return nil, io.EOF
+}
+
+// decode decodes block, keeping any incomplete quantum for the next call.
+func (d *Base64Decoder) decode(block []byte) ([]byte, error) {
+	out := make([]byte, 0, len(block)*3/4+3)
+	for _, c := range block {
+		offset := d.offset
+		d.offset++
+
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '=':
+			switch {
+			case d.n >= 2:
+				out = d.appendPartial(out)
+				d.padded = true
+			case d.n == 0 && d.padded:
+				// Second padding character
+			default:
+				if err := d.invalid(offset, c); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		v := base64Values[c]
+		if v == base64Invalid || (d.padded && !d.lenient) {
+			if err := d.invalid(offset, c); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// In lenient mode data after padding starts a new quantum
+		d.padded = false
+		d.quad[d.n] = v
+		d.n++
+		if d.n == 4 {
+			out = append(out,
+				d.quad[0]<<2|d.quad[1]>>4,
+				d.quad[1]<<4|d.quad[2]>>2,
+				d.quad[2]<<6|d.quad[3])
+			d.n = 0
+		}
+	}
+	return out, nil
+}
+
+// flush decodes the final quantum when it lacks padding.
+func (d *Base64Decoder) flush() ([]byte, error) {
+	switch d.n {
+	case 0:
+		return nil, nil
+	case 1:
+		// A single character cannot encode a whole byte
+		d.n = 0
+		if !d.lenient {
+			return nil, ErrInvalidBase64{Offset: d.offset, Byte: 0}
+		}
+		d.skipped++
+		return nil, nil
+	default:
+		return d.appendPartial(nil), nil
+	}
+}
+
+// appendPartial appends the bytes encoded by an incomplete quantum of two
+// or three characters and resets the quantum.
+func (d *Base64Decoder) appendPartial(out []byte) []byte {
+	out = append(out, d.quad[0]<<2|d.quad[1]>>4)
+	if d.n == 3 {
+		out = append(out, d.quad[1]<<4|d.quad[2]>>2)
+	}
+	d.n = 0
+	return out
+}
+
+// invalid handles a byte that is not valid at this point of the stream.
+func (d *Base64Decoder) invalid(offset int64, c byte) error {
+	if !d.lenient {
+		return ErrInvalidBase64{Offset: offset, Byte: c}
+	}
+	d.skipped++
+	return nil
+}
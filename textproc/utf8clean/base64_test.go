@@ -0,0 +1,152 @@
+package utf8clean
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// readAllBase64 decodes the given blocks and returns the output and any error.
+func readAllBase64(d *Base64Decoder) (string, error) {
+	var out bytes.Buffer
+	for {
+		block, err := d.Next()
+		if err == io.EOF {
+			return out.String(), nil
+		}
+		if err != nil {
+			return out.String(), err
+		}
+		out.Write(block)
+	}
+}
+
+func newBase64Source(blocks ...string) *mockSource {
+	src := &mockSource{}
+	for _, b := range blocks {
+		src.blocks = append(src.blocks, []byte(b))
+	}
+	return src
+}
+
+func TestBase64DecoderWrappedLines(t *testing.T) {
+	// "Hello, World!" wrapped with CRLFs mid-quantum
+	d := NewBase64Decoder(newBase64Source("SGVsbG8s\r\nIFdv\r\ncmxk\r\nIQ==\r\n"), false)
+	got, err := readAllBase64(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "Hello, World!" {
+		t.Errorf("expected 'Hello, World!', got %q", got)
+	}
+}
+
+func TestBase64DecoderQuantumSplitAcrossBlocks(t *testing.T) {
+	d := NewBase64Decoder(newBase64Source("SGV", "sbG8sIF", "dvcmxkIQ", "=="), false)
+	got, err := readAllBase64(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "Hello, World!" {
+		t.Errorf("expected 'Hello, World!', got %q", got)
+	}
+}
+
+func TestBase64DecoderMissingPadding(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SGVsbG8", "Hello"},
+		{"SGVsbA", "Hell"},
+		{"SGVsbG8=", "Hello"},
+	}
+	for _, tc := range tests {
+		got, err := readAllBase64(NewBase64Decoder(newBase64Source(tc.in), false))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestBase64DecoderInvalidByteStrict(t *testing.T) {
+	d := NewBase64Decoder(newBase64Source("SGVs", "b*G8="), false)
+	_, err := readAllBase64(d)
+	var invalid ErrInvalidBase64
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidBase64, got %v", err)
+	}
+	if invalid.Offset != 5 || invalid.Byte != '*' {
+		t.Errorf("expected '*' at offset 5, got %q at %d", invalid.Byte, invalid.Offset)
+	}
+}
+
+func TestBase64DecoderTruncatedQuantumStrict(t *testing.T) {
+	_, err := readAllBase64(NewBase64Decoder(newBase64Source("SGVsb"), false))
+	var invalid ErrInvalidBase64
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidBase64, got %v", err)
+	}
+}
+
+func TestBase64DecoderDataAfterPaddingStrict(t *testing.T) {
+	_, err := readAllBase64(NewBase64Decoder(newBase64Source("SGk=SGk="), false))
+	var invalid ErrInvalidBase64
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidBase64, got %v", err)
+	}
+}
+
+func TestBase64DecoderLenient(t *testing.T) {
+	// Invalid bytes are skipped, concatenated encodings are both decoded
+	d := NewBase64Decoder(newBase64Source("SG*k=!SGk=x"), true)
+	got, err := readAllBase64(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "HiHi" {
+		t.Errorf("expected 'HiHi', got %q", got)
+	}
+	if d.Skipped() != 3 {
+		t.Errorf("expected 3 skipped bytes, got %d", d.Skipped())
+	}
+}
+
+func TestProcessorBase64StrictError(t *testing.T) {
+	p, err := NewProcessor(newBase64Source("not base64!"), WithTransferEncoding("base64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Next(); !errors.As(err, new(ErrInvalidBase64)) {
+		t.Errorf("expected ErrInvalidBase64, got %v", err)
+	}
+}
+
+func TestProcessorBase64Lenient(t *testing.T) {
+	p, err := NewProcessor(newBase64Source("SGVs\r\n", "bG8!"),
+		WithTransferEncoding("base64"), WithLenientBase64())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	for {
+		block, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, block...)
+	}
+	if string(got) != "Hello" {
+		t.Errorf("expected 'Hello', got %q", got)
+	}
+	if p.Base64Skipped() != 1 {
+		t.Errorf("expected 1 skipped byte, got %d", p.Base64Skipped())
+	}
+}
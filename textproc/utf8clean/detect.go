@@ -0,0 +1,402 @@
+package utf8clean
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// DetectionSampleSize is the number of bytes sniffed by WithCharsetDetection.
+const DetectionSampleSize = 4096
+
+// DetectionMethod describes how a charset was chosen.
+type DetectionMethod string
+
+// Detection methods, strongest first.
+const (
+	MethodBOM       DetectionMethod = "bom"       // Byte order mark
+	MethodEscape    DetectionMethod = "escape"    // ISO-2022 escape sequences
+	MethodUTF8      DetectionMethod = "utf8"      // Non-ASCII text that is valid UTF-8
+	MethodMeta      DetectionMethod = "meta"      // HTML <meta> charset declaration
+	MethodLabel     DetectionMethod = "label"     // Charset given by WithCharset
+	MethodHeuristic DetectionMethod = "heuristic" // Byte frequency scoring
+	MethodASCII     DetectionMethod = "ascii"     // No non-ASCII bytes to go on
+)
+
+// Detection is the result of charset detection.
+type Detection struct {
+	Charset    string          // Charset name, as accepted by WithCharset
+	Confidence float64         // 0 to 1
+	Method     DetectionMethod // How the charset was chosen
+}
+
+// DetectCharset guesses the charset of sample, which should be the start of
+// the content. A charset is always returned; when the sample gives nothing to
+// go on it is utf-8 with low confidence.
+func DetectCharset(sample []byte) Detection {
+	if d, ok := detectBOM(sample); ok {
+		return d
+	}
+	if d, ok := detectEscapes(sample); ok {
+		return d
+	}
+
+	highBytes := 0
+	for _, b := range sample {
+		if b >= 0x80 {
+			highBytes++
+		}
+	}
+	if highBytes > 0 && validUTF8Prefix(sample) {
+		return Detection{Charset: "utf-8", Confidence: 0.99, Method: MethodUTF8}
+	}
+	if d, ok := detectMeta(sample); ok {
+		return d
+	}
+	if highBytes == 0 {
+		return Detection{Charset: "utf-8", Confidence: 0.5, Method: MethodASCII}
+	}
+	return detectHeuristic(sample, highBytes)
+}
+
+// detectBOM recognises UTF-8, UTF-16 and UTF-32 byte order marks.
+func detectBOM(sample []byte) (Detection, bool) {
+	var charset string
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		charset = "utf-8"
+	case bytes.HasPrefix(sample, []byte{0x00, 0x00, 0xFE, 0xFF}):
+		charset = "utf-32be"
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE, 0x00, 0x00}):
+		charset = "utf-32le"
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		charset = "utf-16be"
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		charset = "utf-16le"
+	default:
+		return Detection{}, false
+	}
+	return Detection{Charset: charset, Confidence: 1, Method: MethodBOM}, true
+}
+
+// detectEscapes recognises the 7-bit ISO-2022 Japanese and Korean encodings.
+func detectEscapes(sample []byte) (Detection, bool) {
+	for _, b := range sample {
+		if b >= 0x80 {
+			// ISO-2022 is pure 7-bit
+			return Detection{}, false
+		}
+	}
+	switch {
+	case bytes.Contains(sample, []byte("\x1b$B")),
+		bytes.Contains(sample, []byte("\x1b$@")),
+		bytes.Contains(sample, []byte("\x1b(J")):
+		return Detection{Charset: "iso-2022-jp", Confidence: 0.95, Method: MethodEscape}, true
+	case bytes.Contains(sample, []byte("\x1b$)C")):
+		return Detection{Charset: "iso-2022-kr", Confidence: 0.95, Method: MethodEscape}, true
+	}
+	return Detection{}, false
+}
+
+// validUTF8Prefix reports whether sample is valid UTF-8, allowing the
+// sample to end part way through a sequence.
+func validUTF8Prefix(sample []byte) bool {
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		if r == utf8.RuneError && size <= 1 {
+			return !utf8.FullRune(sample[i:])
+		}
+		i += size
+	}
+	return true
+}
+
+// metaCharset matches both <meta charset="x"> and
+// <meta http-equiv="Content-Type" content="text/html; charset=x">.
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]*?charset\s*=\s*["']?\s*([a-z0-9_:.+-]+)`)
+
+// detectMeta looks for an HTML <meta> charset declaration.
+func detectMeta(sample []byte) (Detection, bool) {
+	m := metaCharset.FindSubmatch(sample)
+	if m == nil {
+		return Detection{}, false
+	}
+	charset := strings.ToLower(string(m[1]))
+	if _, err := htmlindex.Get(charset); err != nil {
+		return Detection{}, false
+	}
+	if strings.HasPrefix(charset, "utf-16") {
+		// A document that can declare itself in ASCII is not UTF-16
+		charset = "utf-8"
+	}
+	return Detection{Charset: charset, Confidence: 0.9, Method: MethodMeta}, true
+}
+
+// heuristicCandidates are the legacy charsets considered by byte frequency
+// scoring, each with the function that scores one decoded rune.
+var heuristicCandidates = []struct {
+	charset string
+	score   func(r, prev rune) float64
+}{
+	{"windows-1252", scoreLatin},
+	{"windows-1251", scoreCyrillic},
+	{"koi8-r", scoreCyrillic},
+	{"shift_jis", scoreJapanese},
+	{"euc-jp", scoreJapanese},
+	{"gbk", scoreSimplifiedChinese},
+	{"big5", scoreTraditionalChinese},
+	{"euc-kr", scoreKorean},
+}
+
+// detectHeuristic decodes sample with each candidate charset and picks the
+// one whose output looks most like natural text.
+func detectHeuristic(sample []byte, highBytes int) Detection {
+	best, second := -1.0, -1.0
+	bestCharset := "windows-1252"
+	for _, c := range heuristicCandidates {
+		enc, err := htmlindex.Get(c.charset)
+		if err != nil {
+			continue
+		}
+		decoded, _ := enc.NewDecoder().Bytes(sample)
+		// Do not penalise a sequence cut off by the end of the sample
+		decoded = bytes.TrimSuffix(decoded, []byte("�"))
+
+		total := 0.0
+		prev := ' '
+		for _, r := range string(decoded) {
+			if r >= utf8.RuneSelf {
+				total += c.score(r, prev)
+			}
+			prev = r
+		}
+		score := total / float64(highBytes)
+		if score > best {
+			best, second = score, best
+			bestCharset = c.charset
+		} else if score > second {
+			second = score
+		}
+	}
+
+	if bestCharset == "windows-1252" {
+		bestCharset = latinCharset(sample)
+	}
+
+	confidence := clamp(best) * 0.9
+	if best > 0 {
+		// Close runners-up make the choice less certain
+		confidence *= 0.5 + 0.5*clamp((best-second)/best)
+	}
+	return Detection{Charset: bestCharset, Confidence: confidence, Method: MethodHeuristic}
+}
+
+// latinCharset distinguishes windows-1252 from ISO-8859-1: the range
+// 0x80-0x9F holds printable characters in the former but C1 controls in the
+// latter, so text using it can only be windows-1252.
+func latinCharset(sample []byte) string {
+	for _, b := range sample {
+		if b >= 0x80 && b <= 0x9F {
+			return "windows-1252"
+		}
+	}
+	return "iso-8859-1"
+}
+
+func clamp(f float64) float64 {
+	return max(0, min(1, f))
+}
+
+// Scores are per source byte: single-byte charsets score each rune once,
+// double-byte charsets score each rune for its two bytes.
+
+// scoreInvalid scores runes that no charset should produce from real text.
+func scoreInvalid(r rune) (float64, bool) {
+	switch {
+	case r == utf8.RuneError:
+		return -2, true
+	case unicode.IsControl(r), unicode.Is(unicode.Co, r), !unicode.IsGraphic(r):
+		return -2, true
+	case r >= 0x2500 && r <= 0x259F:
+		// Box drawing and block elements fill the upper half of KOI8-R and
+		// are what mis-decoded text often turns into
+		return -1, true
+	}
+	return 0, false
+}
+
+const (
+	latinCommon    = "éèêàâäöüóáíúñçßãõœëïîôûùåøæ"
+	latinPunctuate = "’‘“”–—…•€«»°·©®™§¿¡"
+	cyrillicCommon = "оеаинтсрвлкмдпу"
+)
+
+// scoreLatin favours sparse accented letters among ASCII words.
+func scoreLatin(r, prev rune) float64 {
+	if s, ok := scoreInvalid(r); ok {
+		return s
+	}
+	switch {
+	case strings.ContainsRune(latinCommon, r):
+		if prev >= utf8.RuneSelf && unicode.IsLetter(prev) {
+			// Runs of accented letters are typical of mis-decoded Cyrillic
+			return -0.5
+		}
+		return 1
+	case strings.ContainsRune(latinPunctuate, r):
+		return 1
+	case unicode.Is(unicode.Latin, r):
+		if prev >= utf8.RuneSelf && unicode.IsLetter(prev) {
+			return -1
+		}
+		return 0.4
+	}
+	return 0
+}
+
+// scoreCyrillic favours runs of lowercase Cyrillic letters.
+func scoreCyrillic(r, prev rune) float64 {
+	if s, ok := scoreInvalid(r); ok {
+		return s
+	}
+	switch {
+	case unicode.Is(unicode.Cyrillic, r):
+		if prev < utf8.RuneSelf && unicode.IsLetter(prev) {
+			// Cyrillic letters do not share words with ASCII letters
+			return -1
+		}
+		if strings.ContainsRune(cyrillicCommon, r) {
+			return 1
+		}
+		if unicode.IsUpper(r) {
+			return 0.3
+		}
+		return 0.6
+	case strings.ContainsRune(latinPunctuate, r):
+		return 1
+	}
+	return 0
+}
+
+// Common characters are taken from frequency lists of each written language.
+const (
+	hanJapanese    = "日一国人年大十二本中長出三時行見月分後前生五間上東四今金九入学高円子外八六下来気小七山話女北午百書先名川千水半男西電校語土木聞食車何南万毎白天母火右読友左休父雨会社自事者同場合新私思手方業地理定実発的対部明用意動法問題作通言度彼連絡確認送信返件様願致申"
+	hanSimplified  = "的一是不了人我在有他这中大来上个国到说们为子和你地出道也时年得就那要下以生会自着去之过家学对可她里后小么心多天而能好都然没日于起还发成事只作当想看文无开手十用主行方又如前所本见经头面公同三已老从动两长知民样现分将外但身些与高意进把法此实回二理美点月明其种声全工己话儿者向情部正名定女问力机给等几很业最间新什打便位因重被走电四第门相次东政海口使教西再平真听世气信北少关并内加化由却代军产入先山五太水万市眼体别处总才场师书比住员九笑性通目华报立马命张活难神数件安表原车白应路期叫死常提感金何更反合放做系计或司利受光王果亲界及今京务制解各任至清物台象记边共风战干接它许八特觉望直服毛林题建南度统色字请交爱让认算论百吃义科怎元社术结六功指思非流每青管夫连远资队跟带花快条院变联言权往展该领传近留红治决周保达办运武半候七必城父强步完革深区即求品士转量空甚众技轻程告江语英基派满式李息写呢识极令黄德收脸钱党倒未持取设始版双历越史商千片容研像找友孩站广改议形委早房音火际则首单据导影失拿网香似斯专石若兵弟谁校读志飞观争究包组造落视济喜离虽坏兴切团养"
+	hanTraditional = "的一是不了人我在有他這中大來上個國到說們為子和你地出道也時年得就那要下以生會自著去之過家學對可她裡後小麼心多天而能好都然沒日於起還發成事只作當想看文無開手十用主行方又如前所本見經頭面公同三已老從動兩長知民樣現分將外但身些與高意進把法此實回二理美點月明其種聲全工己話兒者向情部正名定女問力機給等幾很業最間新什打便位因重被走電四第門相次東政海口使教西再平真聽世氣信北少關並內加化由卻代軍產入先山五太水萬市眼體別處總才場師書比住員九笑性通目華報立馬命張活難神數件安表原車白應路期叫死常提感金何更反合放做系計或司利受光王果親界及今京務制解各任至清物台象記邊共風戰乾接它許八特覺望直服毛林題建南度統色字請交愛讓認算論百吃義科怎元社術結六功指思非流每青管夫連遠資隊跟帶花快條院變聯言權往展該領傳近留紅治決周保達辦運武半候七必城父強步完革深區即求品士轉量空甚眾技輕程告江語英基派滿式李息寫呢識極令黃德收臉錢黨倒未持取設始版雙歷越史商千片容研像找友孩站廣改議形委早房音火際則首單據導影失拿網香似斯專石若兵弟誰校讀志飛觀爭究包組造落視濟喜離雖壞興切團養"
+	hangulCommon   = "이다는의에가을하고지기한로서사리어자도수대시그일정아인나들해으를보국만것여부게요있습니라주내우제상전안원동면경과성적문터장오소회"
+)
+
+// scoreCJK scores a rune decoded by a double-byte charset. kana is the
+// score for Hiragana and Katakana, common holds the characters that score
+// highest for the charset's language.
+func scoreCJK(r rune, kana float64, common string) float64 {
+	if s, ok := scoreInvalid(r); ok {
+		return s
+	}
+	switch {
+	case r >= 0xFF61 && r <= 0xFF9F:
+		// Half-width katakana come from single bytes and are rare in mail
+		return 0.2
+	case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+		return kana
+	case strings.ContainsRune(common, r):
+		return 2
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hangul, r):
+		return 0.8
+	case r >= 0x3000 && r <= 0x303F, r >= 0xFF00 && r <= 0xFFEF:
+		// CJK punctuation and full-width forms
+		return 1.6
+	}
+	return 0
+}
+
+func scoreJapanese(r, _ rune) float64 {
+	return scoreCJK(r, 2, hanJapanese)
+}
+
+func scoreSimplifiedChinese(r, _ rune) float64 {
+	return scoreCJK(r, 0.2, hanSimplified)
+}
+
+func scoreTraditionalChinese(r, _ rune) float64 {
+	return scoreCJK(r, 0.2, hanTraditional)
+}
+
+func scoreKorean(r, _ rune) float64 {
+	return scoreCJK(r, 0.2, hangulCommon)
+}
+
+// resolveCharset decides between a charset label and a detection result.
+// Strong signals always win. A legacy label is otherwise kept unless the
+// heuristics are confident the content is in a different script, since
+// they cannot tell apart charsets for the same script (such as ISO-8859-1
+// and ISO-8859-15).
+func resolveCharset(label string, d Detection) Detection {
+	switch strings.ToLower(label) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		// Missing or default labels say nothing about legacy content
+		return d
+	}
+	switch d.Method {
+	case MethodBOM, MethodEscape, MethodUTF8:
+		return d
+	case MethodHeuristic:
+		labelFamily, detectedFamily := charsetFamily(label), charsetFamily(d.Charset)
+		if d.Confidence >= 0.6 && labelFamily != "" && detectedFamily != "" && labelFamily != detectedFamily {
+			return d
+		}
+	}
+	return Detection{Charset: label, Confidence: 0.5, Method: MethodLabel}
+}
+
+// charsetFamilies groups canonical charset names by the script they encode.
+var charsetFamilies = map[string]string{
+	"windows-1250": "latin", "windows-1252": "latin", "windows-1254": "latin",
+	"windows-1257": "latin", "windows-1258": "latin", "macintosh": "latin",
+	"iso-8859-2": "latin", "iso-8859-3": "latin", "iso-8859-4": "latin",
+	"iso-8859-10": "latin", "iso-8859-13": "latin", "iso-8859-14": "latin",
+	"iso-8859-15": "latin", "iso-8859-16": "latin",
+	"windows-1251": "cyrillic", "koi8-r": "cyrillic", "koi8-u": "cyrillic",
+	"iso-8859-5": "cyrillic", "ibm866": "cyrillic", "x-mac-cyrillic": "cyrillic",
+	"shift_jis": "japanese", "euc-jp": "japanese", "iso-2022-jp": "japanese",
+	"gbk": "chinese", "gb18030": "chinese", "big5": "chinese",
+	"euc-kr": "korean",
+}
+
+// charsetFamily returns the script family of a charset, or "" if unknown.
+func charsetFamily(charset string) string {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return ""
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return ""
+	}
+	return charsetFamilies[name]
+}
+
+// replaySource returns previously sniffed data before continuing with src.
+type replaySource struct {
+	data []byte
+	src  textproc.BytesProcessor
+	eof  bool
+}
+
+func (r *replaySource) Next() ([]byte, error) {
+	if len(r.data) > 0 {
+		data := r.data
+		r.data = nil
+		return data, nil
+	}
+	if r.eof {
+		return nil, io.EOF
+	}
+	return r.src.Next()
+}
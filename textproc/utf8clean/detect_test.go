@@ -0,0 +1,176 @@
+package utf8clean
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// encodeAs encodes s in the named charset.
+func encodeAs(t *testing.T, charset, s string) []byte {
+	t.Helper()
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDetectCharsetBOM(t *testing.T) {
+	tests := []struct {
+		sample []byte
+		want   string
+	}{
+		{[]byte("\xef\xbb\xbfhello"), "utf-8"},
+		{[]byte{0xff, 0xfe, 'h', 0}, "utf-16le"},
+		{[]byte{0xfe, 0xff, 0, 'h'}, "utf-16be"},
+		{[]byte{0xff, 0xfe, 0, 0, 'h', 0, 0, 0}, "utf-32le"},
+	}
+	for _, tc := range tests {
+		d := DetectCharset(tc.sample)
+		if d.Charset != tc.want || d.Method != MethodBOM || d.Confidence != 1 {
+			t.Errorf("%x: expected %s by BOM, got %+v", tc.sample, tc.want, d)
+		}
+	}
+}
+
+func TestDetectCharsetISO2022JP(t *testing.T) {
+	d := DetectCharset([]byte("\x1b$BF|K\\8l\x1b(B"))
+	if d.Charset != "iso-2022-jp" || d.Method != MethodEscape {
+		t.Errorf("expected iso-2022-jp by escape, got %+v", d)
+	}
+}
+
+func TestDetectCharsetUTF8(t *testing.T) {
+	// Cut part way through the final character, as a sample might be
+	sample := []byte("café 日本")
+	d := DetectCharset(sample[:len(sample)-1])
+	if d.Charset != "utf-8" || d.Method != MethodUTF8 {
+		t.Errorf("expected utf-8, got %+v", d)
+	}
+}
+
+func TestDetectCharsetASCII(t *testing.T) {
+	d := DetectCharset([]byte("plain old text"))
+	if d.Charset != "utf-8" || d.Method != MethodASCII {
+		t.Errorf("expected utf-8 by ascii, got %+v", d)
+	}
+}
+
+func TestDetectCharsetMeta(t *testing.T) {
+	tests := []string{
+		`<html><head><meta charset="windows-1251"></head>`,
+		`<meta http-equiv="Content-Type" content="text/html; charset=Windows-1251">`,
+	}
+	for _, sample := range tests {
+		d := DetectCharset(append([]byte(sample), 0xcf, 0xf0))
+		if d.Charset != "windows-1251" || d.Method != MethodMeta {
+			t.Errorf("%q: expected windows-1251 by meta, got %+v", sample, d)
+		}
+	}
+}
+
+func TestDetectCharsetHeuristic(t *testing.T) {
+	tests := []struct {
+		charset string
+		text    string
+	}{
+		{"windows-1251", "Привет, как дела? Это тестовое сообщение на русском языке."},
+		{"koi8-r", "Привет, как дела? Это тестовое сообщение на русском языке."},
+		{"iso-8859-1", "Le café est très bon à Paris, n'est-ce pas? Où est la gare?"},
+		{"windows-1252", "Smart “quotes” and a café — done"},
+		{"shift_jis", "こんにちは、今日はいい天気ですね。明日会いましょう。"},
+		{"euc-jp", "こんにちは、今日はいい天気ですね。明日会いましょう。"},
+		{"gbk", "你好，这是一个测试邮件。我们明天在公司见面。"},
+		{"big5", "你好，這是一個測試郵件。我們明天在公司見面。"},
+		{"euc-kr", "안녕하세요. 이것은 테스트 메일입니다. 내일 회사에서 만나요."},
+	}
+	for _, tc := range tests {
+		d := DetectCharset(encodeAs(t, tc.charset, tc.text))
+		if d.Charset != tc.charset || d.Method != MethodHeuristic {
+			t.Errorf("%s: got %+v", tc.charset, d)
+		}
+		if d.Confidence <= 0 || d.Confidence > 1 {
+			t.Errorf("%s: confidence %f out of range", tc.charset, d.Confidence)
+		}
+	}
+}
+
+func TestResolveCharsetKeepsLegacyLabel(t *testing.T) {
+	d := resolveCharset("iso-8859-15", Detection{Charset: "iso-8859-1", Confidence: 0.9, Method: MethodHeuristic})
+	if d.Charset != "iso-8859-15" || d.Method != MethodLabel {
+		t.Errorf("expected label kept, got %+v", d)
+	}
+}
+
+func TestResolveCharsetOverridesWrongLabel(t *testing.T) {
+	d := resolveCharset("iso-8859-1", Detection{Charset: "utf-8", Confidence: 0.99, Method: MethodUTF8})
+	if d.Charset != "utf-8" {
+		t.Errorf("expected utf-8 to override label, got %+v", d)
+	}
+}
+
+func TestProcessorCharsetDetection(t *testing.T) {
+	input := encodeAs(t, "windows-1251", "Привет, как дела? Это тестовое сообщение.")
+	src := &mockSource{blocks: splitBytes(input)}
+	p, err := NewProcessor(src, WithCharsetDetection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.DetectedCharset(); ok {
+		t.Error("expected no detection before Next")
+	}
+	if got := string(readAllProcessor(t, p)); got != "Привет, как дела? Это тестовое сообщение." {
+		t.Errorf("unexpected output %q", got)
+	}
+	d, ok := p.DetectedCharset()
+	if !ok || d.Charset != "windows-1251" {
+		t.Errorf("expected windows-1251 detected, got %+v", d)
+	}
+}
+
+func TestProcessorCharsetDetectionWrongLabel(t *testing.T) {
+	// UTF-8 content labelled as ISO-8859-1
+	src := &mockSource{blocks: [][]byte{[]byte("naïve café")}}
+	p, err := NewProcessor(src, WithCharset("ISO-8859-1"), WithCharsetDetection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(readAllProcessor(t, p)); got != "naïve café" {
+		t.Errorf("expected 'naïve café', got %q", got)
+	}
+}
+
+func TestProcessorCharsetDetectionUnknownLabel(t *testing.T) {
+	src := &mockSource{blocks: [][]byte{[]byte("hello")}}
+	p, err := NewProcessor(src, WithCharset("x-unknown"), WithCharsetDetection())
+	if err != nil {
+		t.Fatalf("expected unknown label to be ignored, got %v", err)
+	}
+	if got := string(readAllProcessor(t, p)); got != "hello" {
+		t.Errorf("expected 'hello', got %q", got)
+	}
+}
+
+func TestProcessorCharsetDetectionStripsUTF8BOM(t *testing.T) {
+	src := &mockSource{blocks: [][]byte{[]byte("\xef\xbb\xbfhi")}}
+	p, err := NewProcessor(src, WithCharsetDetection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAllProcessor(t, p); !bytes.Equal(got, []byte("hi")) {
+		t.Errorf("expected BOM stripped, got %q", got)
+	}
+}
+
+func TestResolveCharsetOverridesLabelForOtherScript(t *testing.T) {
+	d := resolveCharset("iso-8859-1", Detection{Charset: "windows-1251", Confidence: 0.7, Method: MethodHeuristic})
+	if d.Charset != "windows-1251" {
+		t.Errorf("expected windows-1251 to override label, got %+v", d)
+	}
+}
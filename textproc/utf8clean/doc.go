@@ -9,22 +9,35 @@
 // Commonly supported charsets include:
 //   - ISO-8859-* (Western European, Cyrillic, Arabic, Hebrew, etc.)
 //   - Windows-* codepages (1250-1258)
-//   - UTF-16, UTF-32 (with BOM detection)
+//   - UTF-16, UTF-32 (a leading byte order mark overrides the labelled endianness)
 //   - Asian encodings (Shift_JIS, EUC-JP, GBK, Big5, etc.)
 //
+// The charset decoder keeps its state across Next() calls, so multi-byte
+// sequences and ISO-2022 shift states may straddle source blocks. Only bytes
+// the decoder cannot convert are replaced with U+FFFD.
+//
 // For a complete list of supported charsets, see:
 // https://pkg.go.dev/golang.org/x/text/encoding/htmlindex
 //
 // Supported transfer encodings:
-//   - base64
-//   - quoted-printable (QP)
+//   - base64, decoded by a streaming Base64Decoder that ignores line breaks and
+//     tolerates missing padding; use WithLenientBase64 to skip invalid bytes
+//   - quoted-printable (QP), decoded by a streaming QuotedPrintableDecoder so that
+//     escapes and soft line breaks may straddle source blocks
 //
 // When charset is not specified or empty, data is treated as UTF-8.
 // When charset is specified, data is converted from that charset to UTF-8.
 //
+// WithCharsetDetection sniffs the start of the content instead (byte order
+// marks, ISO-2022 escapes, UTF-8 validity, HTML <meta> declarations and byte
+// frequency scoring for common Latin, Cyrillic and CJK charsets). A charset
+// from WithCharset becomes a hint that is overridden only when the content
+// contradicts it. DetectedCharset reports the choice and its confidence.
+//
 // Errors:
 //   - ErrInvalidCharset: returned when an unsupported charset is specified
 //   - ErrInvalidTransferEncoding: returned when an unsupported transfer encoding is specified
+//   - ErrInvalidBase64: returned by Next when base64 content is malformed (strict mode only)
 //
 // Example usage:
 //
@@ -31,3 +31,22 @@ func (e ErrInvalidTransferEncoding) Error() string {
 func (e ErrInvalidTransferEncoding) Unwrap() error {
 	return nil
 }
+
+// ErrInvalidBase64 indicates base64 content contained a byte that is not
+// valid at its position. Offset is relative to the start of the encoded
+// stream. Byte is zero when the input ended in the middle of a quantum.
+type ErrInvalidBase64 struct {
+	Offset int64
+	Byte   byte
+}
+
+func (e ErrInvalidBase64) Error() string {
+	if e.Byte == 0 {
+		return fmt.Sprintf("invalid base64: truncated input at offset %d", e.Offset)
+	}
+	return fmt.Sprintf("invalid base64: unexpected byte %q at offset %d", e.Byte, e.Offset)
+}
+
+func (e ErrInvalidBase64) Unwrap() error {
+	return nil
+}
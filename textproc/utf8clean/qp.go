@@ -0,0 +1,158 @@
+package utf8clean
+
+import (
+	"io"
+
+	"github.com/jarrod-lowe/jmap-service-libs/textproc"
+)
+
+// QuotedPrintableDecoder decodes a quoted-printable stream (RFC 2045 section 6.7).
+//
+// Escapes and soft line breaks split across source blocks are carried over to
+// the next Next() call. Hex digits are accepted in either case, trailing
+// whitespace before a line break is removed as transport padding, and
+// malformed escapes are passed through literally. Output is raw bytes in the
+// part's original charset; charset conversion happens later.
+type QuotedPrintableDecoder struct {
+	src     textproc.BytesProcessor
+	pending []byte // Undecoded input that may be an incomplete escape
+	eof     bool
+}
+
+var _ textproc.BytesProcessor = (*QuotedPrintableDecoder)(nil)
+
+// NewQuotedPrintableDecoder creates a QuotedPrintableDecoder reading from src.
+func NewQuotedPrintableDecoder(src textproc.BytesProcessor) *QuotedPrintableDecoder {
+	return &QuotedPrintableDecoder{src: src}
+}
+
+// Next returns the next block of decoded bytes.
+// Returns io.EOF when all data has been consumed.
+func (d *QuotedPrintableDecoder) Next() ([]byte, error) {
+	for !d.eof {
+		block, err := d.src.Next()
+		if err == io.EOF {
+			d.eof = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var out []byte
+		out, d.pending = decodeQuotedPrintable(append(d.pending, block...), false)
+		if len(out) > 0 {
+			return out, nil
+		}
+	}
+
+	// Flush whatever is left now that no more input can arrive
+	out, _ := decodeQuotedPrintable(d.pending, true)
+	d.pending = nil
+	if len(out) > 0 {
+		return out, nil
+	}
+	return nil, io.EOF
+}
+
+// decodeQuotedPrintable decodes as much of data as possible. Unless final is
+// set, a trailing sequence whose meaning depends on bytes not yet seen is
+// returned as rest instead of being decoded.
+func decodeQuotedPrintable(data []byte, final bool) (out, rest []byte) {
+	out = make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch c {
+		case '=':
+			if i+2 < len(data) {
+				if hi, ok := unhex(data[i+1]); ok {
+					if lo, ok := unhex(data[i+2]); ok {
+						out = append(out, hi<<4|lo)
+						i += 3
+						continue
+					}
+				}
+			} else if i+1 < len(data) && isHex(data[i+1]) {
+				// "=X" at the end of the block: wait for the second digit
+				if !final {
+					return out, data[i:]
+				}
+				out = append(out, c)
+				i++
+				continue
+			}
+
+			// Soft line break: "=" followed by optional whitespace and a newline
+			j := skipSpace(data, i+1)
+			switch {
+			case j == len(data):
+				if !final {
+					return out, data[i:]
+				}
+				// "=" at the very end of input is a soft break
+				i = j
+			case data[j] == '\n':
+				i = j + 1
+			case data[j] == '\r':
+				if j+1 == len(data) {
+					if !final {
+						return out, data[i:]
+					}
+					i = j + 1
+				} else if data[j+1] == '\n' {
+					i = j + 2
+				} else {
+					out = append(out, c)
+					i++
+				}
+			default:
+				// Not a valid escape, keep it literally
+				out = append(out, c)
+				i++
+			}
+		case ' ', '\t':
+			j := skipSpace(data, i)
+			if j == len(data) && !final {
+				return out, data[i:]
+			}
+			if j < len(data) && (data[j] == '\r' || data[j] == '\n') {
+				// Transport padding before a hard line break
+				i = j
+				continue
+			}
+			out = append(out, data[i:j]...)
+			i = j
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out, nil
+}
+
+// skipSpace returns the index of the first byte at or after i that is not a
+// space or tab.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func isHex(c byte) bool {
+	_, ok := unhex(c)
+	return ok
+}
+
+// unhex converts a hex digit in either case to its value.
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
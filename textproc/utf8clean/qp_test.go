@@ -0,0 +1,126 @@
+package utf8clean
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// readAllQP decodes the given blocks and returns the concatenated output.
+func readAllQP(t *testing.T, blocks ...string) string {
+	t.Helper()
+	src := &mockSource{}
+	for _, b := range blocks {
+		src.blocks = append(src.blocks, []byte(b))
+	}
+	d := NewQuotedPrintableDecoder(src)
+	var out bytes.Buffer
+	for {
+		block, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out.Write(block)
+	}
+	return out.String()
+}
+
+func TestQuotedPrintableEscapes(t *testing.T) {
+	got := readAllQP(t, "caf=C3=A9 and caf=c3=a9")
+	if got != "café and café" {
+		t.Errorf("expected 'café and café', got %q", got)
+	}
+}
+
+func TestQuotedPrintableEscapeSplitAcrossBlocks(t *testing.T) {
+	// Split after "=" and after "=C"
+	got := readAllQP(t, "caf=", "C3=A", "9!")
+	if got != "café!" {
+		t.Errorf("expected 'café!', got %q", got)
+	}
+}
+
+func TestQuotedPrintableSoftLineBreak(t *testing.T) {
+	got := readAllQP(t, "soft=\r\nbreak and=\nbare LF")
+	if got != "softbreak andbare LF" {
+		t.Errorf("expected soft breaks removed, got %q", got)
+	}
+}
+
+func TestQuotedPrintableSoftLineBreakSplitAcrossBlocks(t *testing.T) {
+	got := readAllQP(t, "soft=", "\r", "\nbreak")
+	if got != "softbreak" {
+		t.Errorf("expected 'softbreak', got %q", got)
+	}
+}
+
+func TestQuotedPrintableSoftLineBreakWithTrailingWhitespace(t *testing.T) {
+	got := readAllQP(t, "soft= \t\r\nbreak")
+	if got != "softbreak" {
+		t.Errorf("expected 'softbreak', got %q", got)
+	}
+}
+
+func TestQuotedPrintableHardLineBreakKeptAndPaddingRemoved(t *testing.T) {
+	got := readAllQP(t, "line one  ", " \r\nline two")
+	if got != "line one\r\nline two" {
+		t.Errorf("expected padding removed, got %q", got)
+	}
+}
+
+func TestQuotedPrintableMalformedEscapeLiteral(t *testing.T) {
+	got := readAllQP(t, "a=ZZb = c=")
+	if got != "a=ZZb = c" {
+		t.Errorf("expected malformed escapes kept, got %q", got)
+	}
+}
+
+func TestQuotedPrintableTruncatedEscapeAtEOF(t *testing.T) {
+	got := readAllQP(t, "abc=4")
+	if got != "abc=4" {
+		t.Errorf("expected truncated escape kept, got %q", got)
+	}
+}
+
+func TestQuotedPrintableEmitsRawBytes(t *testing.T) {
+	// Decoded bytes must not be widened to runes
+	got := readAllQP(t, "=E9=FF")
+	if got != "\xe9\xff" {
+		t.Errorf("expected raw bytes e9 ff, got %x", got)
+	}
+}
+
+func TestQuotedPrintableSourceError(t *testing.T) {
+	srcErr := errors.New("boom")
+	d := NewQuotedPrintableDecoder(&errorSource{err: srcErr})
+	if _, err := d.Next(); !errors.Is(err, srcErr) {
+		t.Errorf("expected source error, got %v", err)
+	}
+}
+
+func TestProcessorQuotedPrintableWithCharset(t *testing.T) {
+	// ISO-8859-1 "é" split across blocks as "=E" "9"
+	src := &mockSource{blocks: [][]byte{[]byte("caf=E"), []byte("9")}}
+	p, err := NewProcessor(src, WithCharset("ISO-8859-1"), WithTransferEncoding("quoted-printable"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []byte
+	for {
+		block, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, block...)
+	}
+	if string(out) != "café" {
+		t.Errorf("expected 'café', got %q", out)
+	}
+}
@@ -1,15 +1,16 @@
 package utf8clean
 
 import (
-	"encoding/base64"
 	"fmt"
 	"io"
-	"mime"
 	"strings"
 
 	"github.com/jarrod-lowe/jmap-service-libs/textproc"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+	"golang.org/x/text/transform"
 )
 
 // Processor reads bytes from a source and validates they are UTF-8.
@@ -18,8 +19,18 @@ type Processor struct {
 	blockSize        int
 	charset          string
 	transferEncoding string
-	buf              []byte            // Buffer for incomplete UTF-8 sequences
-	charsetEncoding  encoding.Encoding // Charset decoder for conversion
+	lenientBase64    bool
+	buf              []byte                // Buffer for incomplete UTF-8 sequences
+	charsetEncoding  encoding.Encoding     // Charset for conversion
+	decoder          transform.Transformer // Persistent charset decoder, nil for UTF-8
+	raw              []byte                // Input not yet consumed by decoder
+	base64           *Base64Decoder        // Set when transfer encoding is base64
+	detect           bool                  // Sniff the charset on the first Next()
+	detection        *Detection            // Result of detection, once run
+	offsets          *textproc.OffsetMap   // Output to input offsets, when known
+	offsetsChecked   bool                  // offsets has been set up if possible
+	bufIn            int64                 // Input offset of buf[0]
+	outPos           int64                 // Output offset of the next valid byte
 }
 
 // Option configures a Processor.
@@ -54,6 +65,15 @@ func getEncoding(charset string) (encoding.Encoding, error) {
 		return nil, nil
 	}
 
+	// UTF-32 is not in the WHATWG index. The unlabelled form defaults to
+	// big-endian (RFC 2781 style), and any BOM overrides the label.
+	switch strings.ToLower(charset) {
+	case "utf-32", "utf32", "utf-32be":
+		return utf32.UTF32(utf32.BigEndian, utf32.UseBOM), nil
+	case "utf-32le":
+		return utf32.UTF32(utf32.LittleEndian, utf32.UseBOM), nil
+	}
+
 	// Use htmlindex.Get() for standard charset mapping
 	enc, err := htmlindex.Get(charset)
 	if err != nil {
@@ -62,6 +82,29 @@ func getEncoding(charset string) (encoding.Encoding, error) {
 	return enc, nil
 }
 
+// newCharsetDecoder returns a decoder for enc that keeps its state between
+// calls. UTF-16 input may start with a byte order mark which overrides the
+// labelled endianness; the BOM itself is removed.
+func newCharsetDecoder(charset string, enc encoding.Encoding) transform.Transformer {
+	if enc == nil {
+		return nil
+	}
+	if strings.HasPrefix(strings.ToLower(charset), "utf-16") {
+		return unicode.BOMOverride(enc.NewDecoder())
+	}
+	return enc.NewDecoder()
+}
+
+// WithCharsetDetection sniffs the first DetectionSampleSize bytes of decoded
+// content to choose the charset. A charset set by WithCharset is treated as a
+// hint: it is kept unless the content contradicts it, and an unknown charset
+// name is ignored rather than rejected. See DetectCharset.
+func WithCharsetDetection() Option {
+	return func(p *Processor) {
+		p.detect = true
+	}
+}
+
 // WithTransferEncoding sets the content-transfer-encoding for decoding.
 func WithTransferEncoding(encoding string) Option {
 	return func(p *Processor) {
@@ -69,6 +112,14 @@ func WithTransferEncoding(encoding string) Option {
 	}
 }
 
+// WithLenientBase64 skips bytes that are not valid base64 instead of
+// returning ErrInvalidBase64. The number skipped is reported by Base64Skipped.
+func WithLenientBase64() Option {
+	return func(p *Processor) {
+		p.lenientBase64 = true
+	}
+}
+
 // NewProcessor creates a new Processor with the given BytesProcessor source.
 // This enables pull-based lazy evaluation where the processor calls Next() on its source.
 // Returns error for invalid charset or transfer encoding.
@@ -84,15 +135,27 @@ func NewProcessor(src textproc.BytesProcessor, opts ...Option) (*Processor, erro
 	var err error
 	p.charsetEncoding, err = getEncoding(p.charset)
 	if err != nil {
-		return nil, ErrInvalidCharset{Charset: p.charset}
+		if !p.detect {
+			return nil, ErrInvalidCharset{Charset: p.charset}
+		}
+		// A wrong label is one of the things detection is for
+		p.charset = ""
 	}
+	p.decoder = newCharsetDecoder(p.charset, p.charsetEncoding)
 
 	// Validate transfer encoding
 	if p.transferEncoding != "" {
 		normalized := strings.ToLower(strings.ReplaceAll(p.transferEncoding, " ", ""))
 		switch normalized {
-		case "base64", "quoted-printable", "qp":
-			// Valid
+		case "base64":
+			// Quanta can straddle source blocks and lines, so decode the
+			// stream before it is split any further
+			p.base64 = NewBase64Decoder(p.src, p.lenientBase64)
+			p.src = p.base64
+		case "quoted-printable", "qp":
+			// QP escapes and soft line breaks can straddle source blocks,
+			// so decode the stream before it is split any further
+			p.src = NewQuotedPrintableDecoder(p.src)
 		default:
 			return nil, ErrInvalidTransferEncoding{Encoding: p.transferEncoding}
 		}
@@ -101,9 +164,85 @@ func NewProcessor(src textproc.BytesProcessor, opts ...Option) (*Processor, erro
 	return p, nil
 }
 
+// Offsets maps offsets in the UTF-8 output back to the input bytes. It is
+// nil before the first Next, and when a transfer encoding or charset
+// conversion is in use, since those do not preserve positions.
+func (p *Processor) Offsets() *textproc.OffsetMap {
+	return p.offsets
+}
+
+// Base64Skipped returns the number of invalid bytes skipped by lenient
+// base64 decoding so far. It is zero when the transfer encoding is not base64.
+func (p *Processor) Base64Skipped() int {
+	if p.base64 == nil {
+		return 0
+	}
+	return p.base64.Skipped()
+}
+
+// DetectedCharset returns the charset chosen by WithCharsetDetection.
+// The second result is false until detection has run on the first Next().
+func (p *Processor) DetectedCharset() (Detection, bool) {
+	if p.detection == nil {
+		return Detection{}, false
+	}
+	return *p.detection, true
+}
+
+// sniff buffers the start of the content, detects its charset and sets up
+// the decoder, then arranges for the buffered content to be replayed.
+func (p *Processor) sniff() error {
+	var sample []byte
+	eof := false
+	for len(sample) < DetectionSampleSize {
+		block, err := p.src.Next()
+		if err == io.EOF {
+			eof = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+		sample = append(sample, block...)
+	}
+
+	d := resolveCharset(p.charset, DetectCharset(sample))
+	p.detection = &d
+	p.src = &replaySource{data: sample, src: p.src, eof: eof}
+
+	if d.Method == MethodBOM && d.Charset == "utf-8" {
+		// Strip the BOM rather than passing U+FEFF through
+		p.charsetEncoding = unicode.UTF8BOM
+		p.decoder = unicode.UTF8BOM.NewDecoder()
+		return nil
+	}
+	enc, err := getEncoding(d.Charset)
+	if err != nil {
+		return ErrInvalidCharset{Charset: d.Charset}
+	}
+	p.charset = d.Charset
+	p.charsetEncoding = enc
+	p.decoder = newCharsetDecoder(d.Charset, enc)
+	return nil
+}
+
 // Next reads the next block of data from the source with validated UTF-8.
 // Returns io.EOF when all data has been consumed.
 func (p *Processor) Next() ([]byte, error) {
+	if p.detect && p.detection == nil {
+		if err := p.sniff(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.offsetsChecked {
+		// Offsets are only tracked when input bytes pass straight through
+		p.offsetsChecked = true
+		if p.decoder == nil && p.transferEncoding == "" {
+			p.offsets = &textproc.OffsetMap{}
+		}
+	}
+
 	// Build output from buffered data and new data
 	var output []byte
 
@@ -127,6 +266,13 @@ func (p *Processor) Next() ([]byte, error) {
 		block, err := p.src.Next()
 		if err != nil {
 			if err == io.EOF {
+				// Flush input held back by the charset decoder
+				if p.decoder != nil && len(p.raw) > 0 {
+					p.buf = append(p.buf, p.convertCharset(nil, true)...)
+					valid, remaining, _ := p.validateAndBuffer(p.buf)
+					output = append(output, valid...)
+					p.buf = remaining
+				}
 				// End of input - flush any remaining buffered data
 				if len(p.buf) > 0 {
 					// Invalid UTF-8 at end - replace with U+FFFD
@@ -141,19 +287,9 @@ func (p *Processor) Next() ([]byte, error) {
 			return nil, err
 		}
 
-		// Apply transfer encoding decoding if set
-		if p.transferEncoding != "" {
-			var err error
-			block, err = p.convertTransferEncoding(block)
-			if err != nil {
-				// Invalid transfer encoding, return error
-				return nil, err
-			}
-		}
-
 		// Apply charset conversion if charset is set
-		if p.charsetEncoding != nil {
-			block = p.convertCharset(block)
+		if p.decoder != nil {
+			block = p.convertCharset(block, false)
 		}
 
 		// Append new data to buffer and process it
@@ -178,6 +314,10 @@ func (p *Processor) Next() ([]byte, error) {
 // validateAndBuffer processes a byte slice and returns valid UTF-8 bytes, remaining bytes,
 // and whether there's an incomplete sequence at the end.
 func (p *Processor) validateAndBuffer(data []byte) (valid, remaining []byte, hasIncomplete bool) {
+	defer func() {
+		p.bufIn += int64(len(data) - len(remaining))
+		p.outPos += int64(len(valid))
+	}()
 	for i := 0; i < len(data); {
 		r, size := decodeUTF8Rune(data[i:])
 
@@ -199,6 +339,9 @@ func (p *Processor) validateAndBuffer(data []byte) (valid, remaining []byte, has
 			// Just an invalid byte - replace with U+FFFD
 			valid = append(valid, '\xef', '\xbf', '\xbd')
 			i++
+			if p.offsets != nil {
+				p.offsets.Add(p.outPos+int64(len(valid)), p.bufIn+int64(i))
+			}
 			continue
 		}
 
@@ -211,81 +354,43 @@ func (p *Processor) validateAndBuffer(data []byte) (valid, remaining []byte, has
 }
 
 // convertCharset converts bytes from the source charset to UTF-8.
-func (p *Processor) convertCharset(data []byte) []byte {
-	if p.charsetEncoding == nil {
-		return data
-	}
-
-	// Create a transformer for the charset
-	decoder := p.charsetEncoding.NewDecoder()
-
-	// Convert the data
-	result, err := decoder.Bytes(data)
-	if err != nil {
-		// On conversion error, replace with U+FFFD
-		return []byte{'\xef', '\xbf', '\xbd'}
-	}
-
-	return result
-}
-
-// convertTransferEncoding decodes Content-Transfer-Encoding.
-func (p *Processor) convertTransferEncoding(data []byte) ([]byte, error) {
-	if p.transferEncoding == "" {
-		return data, nil
-	}
-
-	// Normalize encoding name
-	normalized := strings.ToLower(strings.ReplaceAll(p.transferEncoding, " ", ""))
-
-	switch normalized {
-	case "base64":
-		decoder := base64.StdEncoding
-		result, err := decoder.DecodeString(string(data))
-		if err != nil {
-			// On decode error, return as-is (will fail validation)
-			return data, nil
-		}
-		return []byte(result), nil
-	case "quoted-printable", "qp":
-		// Quoted-printable encoding: =XX where XX is hex value of byte
-		decoder := new(mime.WordDecoder)
-		decoded := string(data)
-		result, err := decoder.DecodeHeader(decoded)
-		if err != nil {
-			// Fallback: try to decode =XX sequences manually
-			decoded = decodeQuotedPrintable(decoded)
-		} else if result == decoded {
-			// No change, try manual decoding
-			decoded = decodeQuotedPrintable(decoded)
-		}
-		return []byte(decoded), nil
-	default:
-		// Unknown encoding, return nil data with error
-		return nil, ErrInvalidTransferEncoding{Encoding: p.transferEncoding}
-	}
-}
-
-// decodeQuotedPrintable decodes quoted-printable =XX sequences manually.
-func decodeQuotedPrintable(s string) string {
-	var result []rune
-	for i := 0; i < len(s); i++ {
-		if s[i] == '=' && i+2 < len(s) {
-			// Try to parse hex value
-			hexStr := s[i+1 : i+3]
-			if len(hexStr) == 2 {
-				var b byte
-				_, err := fmt.Sscanf(hexStr, "%02X", &b)
-				if err == nil {
-					result = append(result, rune(b))
-					i += 2
-					continue
-				}
+// Multi-byte sequences and shift states that straddle blocks are held back
+// until the next call; atEOF flushes them. Only bytes the decoder cannot
+// convert are replaced with U+FFFD.
+func (p *Processor) convertCharset(data []byte, atEOF bool) []byte {
+	p.raw = append(p.raw, data...)
+	out := make([]byte, 0, len(p.raw)*2)
+	dst := make([]byte, 4096)
+	for {
+		nDst, nSrc, err := p.decoder.Transform(dst, p.raw, atEOF)
+		out = append(out, dst[:nDst]...)
+		p.raw = p.raw[nSrc:]
+
+		switch err {
+		case nil:
+			return out
+		case transform.ErrShortDst:
+			if nDst == 0 && nSrc == 0 {
+				dst = make([]byte, 2*len(dst))
+			}
+		case transform.ErrShortSrc:
+			if atEOF {
+				// Truncated sequence at end of input
+				out = append(out, '\xef', '\xbf', '\xbd')
+				p.raw = nil
+			}
+			return out
+		default:
+			// Undecodable byte - replace it and carry on with the rest
+			out = append(out, '\xef', '\xbf', '\xbd')
+			if len(p.raw) > 0 {
+				p.raw = p.raw[1:]
+			}
+			if len(p.raw) == 0 {
+				return out
 			}
 		}
-		result = append(result, rune(s[i]))
 	}
-	return string(result)
 }
 
 // decodeUTF8Rune decodes a UTF-8 rune from a byte slice.
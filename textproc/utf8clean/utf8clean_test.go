@@ -731,3 +731,149 @@ type errorSource struct {
 func (e *errorSource) Next() ([]byte, error) {
 	return nil, e.err
 }
+
+// readAllProcessor drains p and returns everything it produced.
+func readAllProcessor(t *testing.T, p *Processor) []byte {
+	t.Helper()
+	var out []byte
+	for {
+		block, err := p.Next()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out = append(out, block...)
+	}
+}
+
+// splitBytes splits data into single-byte blocks so that every multi-byte
+// sequence straddles a block boundary.
+func splitBytes(data []byte) [][]byte {
+	blocks := make([][]byte, len(data))
+	for i := range data {
+		blocks[i] = data[i : i+1]
+	}
+	return blocks
+}
+
+func TestCharsetSequencesSplitAcrossBlocks(t *testing.T) {
+	tests := []struct {
+		charset string
+		input   []byte
+		want    string
+	}{
+		{"Shift_JIS", []byte{0x93, 0xfa, 0x96, 0x7b, 0x8c, 0xea}, "日本語"},
+		{"GBK", []byte{0xd6, 0xd0, 0xce, 0xc4}, "中文"},
+		{"UTF-16LE", []byte{0xe5, 0x65, 0x2c, 0x67}, "日本"},
+		{"ISO-2022-JP", []byte("\x1b$BF|K\\8l\x1b(B!"), "日本語!"},
+		{"Big5", []byte{0xa4, 0xa4, 0xa4, 0xe5}, "中文"},
+	}
+	for _, tc := range tests {
+		src := &mockSource{blocks: splitBytes(tc.input)}
+		p, err := NewProcessor(src, WithCharset(tc.charset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(readAllProcessor(t, p)); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.charset, tc.want, got)
+		}
+	}
+}
+
+func TestCharsetInvalidBytesReplacedIndividually(t *testing.T) {
+	// A byte invalid in Shift_JIS must not take the rest of the block with it
+	src := &mockSource{blocks: [][]byte{{'a', 0xa0, 'b', 'c', 0x82}, {0xa0}}}
+	p, err := NewProcessor(src, WithCharset("Shift_JIS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(readAllProcessor(t, p))
+	if got != "a�bcあ" {
+		t.Errorf("expected only the invalid byte replaced, got %q", got)
+	}
+}
+
+func TestCharsetTruncatedSequenceAtEOF(t *testing.T) {
+	src := &mockSource{blocks: [][]byte{{'a', 0x82}}}
+	p, err := NewProcessor(src, WithCharset("Shift_JIS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(readAllProcessor(t, p)); got != "a�" {
+		t.Errorf("expected 'a\\uFFFD', got %q", got)
+	}
+}
+
+func TestUTF16BOMOverridesLabel(t *testing.T) {
+	// Big-endian BOM and text, labelled as plain UTF-16 (little-endian by default)
+	input := []byte{0xfe, 0xff, 0x00, 'h', 0x00, 'i'}
+	p, err := NewProcessor(&mockSource{blocks: splitBytes(input)}, WithCharset("UTF-16"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(readAllProcessor(t, p)); got != "hi" {
+		t.Errorf("expected 'hi', got %q", got)
+	}
+}
+
+func TestUTF16LEBOMRemoved(t *testing.T) {
+	input := []byte{0xff, 0xfe, 'h', 0x00, 'i', 0x00}
+	p, err := NewProcessor(&mockSource{blocks: [][]byte{input}}, WithCharset("UTF-16"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(readAllProcessor(t, p)); got != "hi" {
+		t.Errorf("expected 'hi', got %q", got)
+	}
+}
+
+func TestUTF32(t *testing.T) {
+	tests := []struct {
+		charset string
+		input   []byte
+	}{
+		{"UTF-32", []byte{0, 0, 0, 'h', 0, 0, 0, 'i'}},
+		{"UTF-32", []byte{0xff, 0xfe, 0, 0, 'h', 0, 0, 0, 'i', 0, 0, 0}},
+		{"UTF-32LE", []byte{'h', 0, 0, 0, 'i', 0, 0, 0}},
+	}
+	for _, tc := range tests {
+		p, err := NewProcessor(&mockSource{blocks: splitBytes(tc.input)}, WithCharset(tc.charset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(readAllProcessor(t, p)); got != "hi" {
+			t.Errorf("%s %x: expected 'hi', got %q", tc.charset, tc.input, got)
+		}
+	}
+}
+
+func TestProcessorOffsets(t *testing.T) {
+	input := []byte("a\xffb\xfe\xfec")
+	p, err := NewProcessor(&mockSource{blocks: splitBytes(input)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := readAllProcessor(t, p)
+	m := p.Offsets()
+	if m == nil {
+		t.Fatal("expected offsets for UTF-8 input")
+	}
+	for _, c := range []byte("abc") {
+		if got := m.Map(int64(bytes.IndexByte(out, c))); got != int64(bytes.IndexByte(input, c)) {
+			t.Errorf("%q: expected input offset %d, got %d", c, bytes.IndexByte(input, c), got)
+		}
+	}
+}
+
+func TestProcessorOffsetsUnavailableWithCharset(t *testing.T) {
+	p, err := NewProcessor(&mockSource{blocks: [][]byte{[]byte("abc")}}, WithCharset("iso-8859-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAllProcessor(t, p)
+	if p.Offsets() != nil {
+		t.Error("expected no offsets with charset conversion")
+	}
+}